@@ -0,0 +1,33 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// addressString returns the canonical form addresses are stored and queried
+// in: lowercase hex with the 0x prefix, like hashes. common.Address.String
+// returns the EIP-55 checksummed form instead, which MUST NOT be stored, as
+// lookups would then depend on the casing of their input.
+func addressString(address common.Address) string {
+	return strings.ToLower(address.Hex())
+}
+
+// normalizeAddress returns the canonical form of an address given as a
+// string, such as a token address, see addressString. Unlike addressString,
+// it leaves malformed addresses as they are but for their casing.
+func normalizeAddress(address string) string {
+	return strings.ToLower(address)
+}
+
+// normalizeTokenAddresses returns tokens keyed by the canonical form of their
+// address. Of tokens keyed by the same address in different casings, an
+// arbitrary one is kept.
+func normalizeTokenAddresses(tokens map[string]*Token) map[string]*Token {
+	normalized := make(map[string]*Token, len(tokens))
+	for address, token := range tokens {
+		normalized[normalizeAddress(address)] = token
+	}
+	return normalized
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetTableSizes returns the on-disk size in bytes of every table in the
+// schema, including its indexes and TOAST data, keyed by table name.
+func (d *Database) GetTableSizes(ctx context.Context) (map[string]int64, error) {
+	const selectTableSizesStatement = `
+	SELECT
+		relname, pg_total_relation_size(relid)
+	FROM pg_catalog.pg_statio_user_tables;
+	`
+
+	if d.dialect != dialectPostgres {
+		return nil, ErrUnsupportedDialect
+	}
+
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, selectTableSizesStatement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var table string
+		var size int64
+		if err := rows.Scan(&table, &size); err != nil {
+			return nil, err
+		}
+		sizes[table] = size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sizes, nil
+}
+
+// rolledBackTables are the tables DeleteL1BlocksFrom deletes from or updates.
+var rolledBackTables = []string{"l1_blocks", "deposits", "withdrawals", "bridged_balances"}
+
+// VacuumAnalyze runs VACUUM ANALYZE on the given tables, or on the tables
+// rolled back by DeleteL1BlocksFrom if none is given, one table at a time.
+// Callers should invoke it after bulk deletions such as deep rollbacks, as the
+// dead rows they leave behind slow queries down until autovacuum catches up.
+// VACUUM cannot run within a transaction, so it runs directly on the primary
+// and may take a while on large tables.
+func (d *Database) VacuumAnalyze(ctx context.Context, tables ...string) error {
+	const vacuumAnalyzeStatement = `
+	VACUUM ANALYZE %s
+	`
+
+	if d.dialect != dialectPostgres {
+		return ErrUnsupportedDialect
+	}
+	if len(tables) == 0 {
+		tables = rolledBackTables
+	}
+
+	for _, table := range tables {
+		_, err := d.db.ExecContext(ctx, fmt.Sprintf(vacuumAnalyzeStatement, pq.QuoteIdentifier(table)))
+		if err != nil {
+			return contextErr(ctx, err)
+		}
+	}
+	return nil
+}
+
+// IndexingStaleness returns how old the highest indexed L1 and L2 blocks are
+// relative to now, a unix timestamp in seconds. A monitoring loop can alert
+// when either exceeds a threshold. If no block has been indexed on a layer,
+// its age is measured from the unix epoch.
+func (d *Database) IndexingStaleness(ctx context.Context, now uint64) (l1Age, l2Age time.Duration, err error) {
+	const selectHighestTimestampsStatement = `
+	SELECT
+		(SELECT COALESCE(MAX(timestamp), 0) FROM l1_blocks WHERE reorged_at IS NULL),
+		(SELECT COALESCE(MAX(timestamp), 0) FROM l2_blocks);
+	`
+
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	var l1Timestamp, l2Timestamp uint64
+	err = d.db.QueryRowContext(ctx, selectHighestTimestampsStatement).Scan(&l1Timestamp, &l2Timestamp)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return blockAge(now, l1Timestamp), blockAge(now, l2Timestamp), nil
+}
+
+// blockAge returns the time elapsed between the block timestamp and now. It
+// is zero for blocks timestamped in the future.
+func blockAge(now, timestamp uint64) time.Duration {
+	if timestamp >= now {
+		return 0
+	}
+	return time.Duration(now-timestamp) * time.Second
+}
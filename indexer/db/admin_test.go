@@ -0,0 +1,96 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetTableSizes asserts that the size of every table is reported, and
+// that the deposits table takes up space once rows are inserted.
+func TestGetTableSizes(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xff01"), 0),
+			newTestDeposit(common.HexToHash("0xff02"), 1),
+		},
+	})
+	require.Nil(t, err)
+
+	sizes, err := d.GetTableSizes(context.Background())
+	require.Nil(t, err)
+	require.Contains(t, sizes, "withdrawals")
+	require.Greater(t, sizes["deposits"], int64(0))
+}
+
+// TestIndexingStaleness asserts that the age of the highest indexed blocks is
+// computed relative to the given time.
+func TestIndexingStaleness(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1000,
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:       common.HexToHash("0x11"),
+		ParentHash: common.HexToHash("0x10"),
+		Number:     1,
+		Timestamp:  1500,
+	})
+	require.Nil(t, err)
+
+	l1Age, l2Age, err := d.IndexingStaleness(ctx, 2000)
+	require.Nil(t, err)
+	require.Equal(t, 1000*time.Second, l1Age)
+	require.Equal(t, 500*time.Second, l2Age)
+
+	l1Age, l2Age, err = d.IndexingStaleness(ctx, 1200)
+	require.Nil(t, err)
+	require.Equal(t, 200*time.Second, l1Age)
+	require.Equal(t, time.Duration(0), l2Age)
+}
+
+// TestVacuumAnalyze asserts that the tables rolled back by DeleteL1BlocksFrom
+// can be vacuumed after a rollback, and that unknown tables are reported.
+func TestVacuumAnalyze(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xff01"), 0)},
+	})
+	require.Nil(t, err)
+	require.Nil(t, d.DeleteL1BlocksFrom(ctx, 1))
+
+	require.Nil(t, d.VacuumAnalyze(ctx))
+	require.Nil(t, d.VacuumAnalyze(ctx, "deposits"))
+	require.NotNil(t, d.VacuumAnalyze(ctx, "unknown"))
+}
@@ -1,5 +1,16 @@
 package db
 
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrAirdropTotalMismatch signals that an airdrop's total amount does not
+// equal the sum of its category amounts.
+var ErrAirdropTotalMismatch = errors.New("airdrop total does not match category sum")
+
 type Airdrop struct {
 	Address              string `json:"address"`
 	VoterAmount          string `json:"voterAmount"`
@@ -8,6 +19,65 @@ type Airdrop struct {
 	ActiveBridgedAmount  string `json:"activeBridgedAmount"`
 	OpUserAmount         string `json:"opUserAmount"`
 	OpRepeatUserAmount   string `json:"opRepeatUserAmount"`
+	OpOgAmount           string `json:"opOgAmount"`
+	BonusAmount          string `json:"bonusAmount"`
+	TotalAmount          string `json:"totalAmount"`
+}
+
+// AirdropTotals sums the amounts of every airdrop, per category and overall,
+// in the token's base units.
+type AirdropTotals struct {
+	VoterAmount          string `json:"voterAmount"`
+	MultisigSignerAmount string `json:"multisigSignerAmount"`
+	GitcoinAmount        string `json:"gitcoinAmount"`
+	ActiveBridgedAmount  string `json:"activeBridgedAmount"`
+	OpUserAmount         string `json:"opUserAmount"`
+	OpRepeatUserAmount   string `json:"opRepeatUserAmount"`
+	OpOgAmount           string `json:"opOgAmount"`
 	BonusAmount          string `json:"bonusAmount"`
 	TotalAmount          string `json:"totalAmount"`
+
+	// Count is the number of eligible addresses.
+	Count uint64 `json:"count"`
+}
+
+// AirdropChange records the values an airdrop had until it was corrected.
+type AirdropChange struct {
+	Previous   Airdrop   `json:"previous"`
+	ReplacedAt time.Time `json:"replacedAt"`
+}
+
+// Validate checks that the airdrop's TotalAmount equals the sum of all of its
+// category amounts.
+func (a *Airdrop) Validate() error {
+	categories := []string{
+		a.VoterAmount,
+		a.MultisigSignerAmount,
+		a.GitcoinAmount,
+		a.ActiveBridgedAmount,
+		a.OpUserAmount,
+		a.OpRepeatUserAmount,
+		a.OpOgAmount,
+		a.BonusAmount,
+	}
+
+	sum := new(big.Int)
+	for _, category := range categories {
+		amount, ok := new(big.Int).SetString(category, 10)
+		if !ok {
+			return fmt.Errorf("unable to parse airdrop amount %q", category)
+		}
+		sum.Add(sum, amount)
+	}
+
+	total, ok := new(big.Int).SetString(a.TotalAmount, 10)
+	if !ok {
+		return fmt.Errorf("unable to parse airdrop total %q", a.TotalAmount)
+	}
+	if total.Cmp(sum) != 0 {
+		return fmt.Errorf("%w: address %s has total %s but categories sum to %s",
+			ErrAirdropTotalMismatch, a.Address, total, sum)
+	}
+
+	return nil
 }
@@ -0,0 +1,227 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const insertAirdropStatement = `
+INSERT INTO airdrops (address, voter_amount, bonus_amount, total_amount)
+VALUES ($1, $2, $3, $4)
+`
+
+// TestFindInconsistentAirdrops asserts that only airdrops whose total does not
+// match the sum of their categories are reported, and that Validate agrees.
+func TestFindInconsistentAirdrops(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	consistent := common.HexToAddress("0xbb01")
+	inconsistent := common.HexToAddress("0xbb02")
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err := conn.Exec(insertAirdropStatement,
+		strings.ToLower(consistent.String()), "100", "5", "105")
+	require.Nil(t, err)
+	_, err = conn.Exec(insertAirdropStatement,
+		strings.ToLower(inconsistent.String()), "100", "5", "100")
+	require.Nil(t, err)
+
+	addresses, err := d.FindInconsistentAirdrops(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{strings.ToLower(inconsistent.String())}, addresses)
+
+	airdrop, err := d.GetAirdrop(context.Background(), consistent)
+	require.Nil(t, err)
+	require.Nil(t, airdrop.Validate())
+
+	airdrop, err = d.GetAirdrop(context.Background(), inconsistent)
+	require.Nil(t, err)
+	require.True(t, errors.Is(airdrop.Validate(), db.ErrAirdropTotalMismatch))
+}
+
+// TestGetAirdrop asserts that GetAirdrop returns the stored allocation for an
+// eligible address, and nil otherwise.
+func TestGetAirdrop(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	eligible := common.HexToAddress("0xbb01")
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err := conn.Exec(insertAirdropStatement,
+		strings.ToLower(eligible.String()), "100", "5", "105")
+	require.Nil(t, err)
+
+	airdrop, err := d.GetAirdrop(context.Background(), eligible)
+	require.Nil(t, err)
+	require.Equal(t, &db.Airdrop{
+		Address:              strings.ToLower(eligible.String()),
+		VoterAmount:          "100",
+		MultisigSignerAmount: "0",
+		GitcoinAmount:        "0",
+		ActiveBridgedAmount:  "0",
+		OpUserAmount:         "0",
+		OpRepeatUserAmount:   "0",
+		OpOgAmount:           "0",
+		BonusAmount:          "5",
+		TotalAmount:          "105",
+	}, airdrop)
+
+	airdrop, err = d.GetAirdrop(context.Background(), common.HexToAddress("0xbb02"))
+	require.Nil(t, err)
+	require.Nil(t, airdrop)
+}
+
+// TestGetAirdrops asserts that GetAirdrops returns the allocations of the
+// eligible addresses only, whatever the case they are stored in.
+func TestGetAirdrops(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	first := common.HexToAddress("0xbb01")
+	second := common.HexToAddress("0xbb02")
+	ineligible := common.HexToAddress("0xbb03")
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err := conn.Exec(insertAirdropStatement,
+		strings.ToLower(first.String()), "100", "5", "105")
+	require.Nil(t, err)
+	_, err = conn.Exec(insertAirdropStatement,
+		strings.ToLower(second.String()), "200", "0", "200")
+	require.Nil(t, err)
+
+	airdrops, err := d.GetAirdrops(context.Background(), []common.Address{first, second, ineligible})
+	require.Nil(t, err)
+	require.Len(t, airdrops, 2)
+	require.Equal(t, "105", airdrops[first].TotalAmount)
+	require.Equal(t, "200", airdrops[second].TotalAmount)
+	_, ok := airdrops[ineligible]
+	require.False(t, ok)
+
+	airdrops, err = d.GetAirdrops(context.Background(), nil)
+	require.Nil(t, err)
+	require.Empty(t, airdrops)
+}
+
+// TestAirdropAmountChecks asserts that every amount of an airdrop is checked
+// to be a number by its own constraint.
+func TestAirdropAmountChecks(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	address := strings.ToLower(common.HexToAddress("0xbb01").String())
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err := conn.Exec(insertAirdropStatement, address, "100", "5", "105")
+	require.Nil(t, err)
+
+	for _, column := range []string{"op_repeat_user_amount", "total_amount"} {
+		_, err = conn.Exec("UPDATE airdrops SET "+column+" = 'x' WHERE address = $1", address)
+		require.NotNil(t, err, column)
+	}
+}
+
+// TestUpsertAirdrop asserts that correcting an airdrop replaces its values and
+// records the replaced ones in its history, oldest first.
+func TestUpsertAirdrop(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	address := common.HexToAddress("0xbb01")
+	newAirdrop := func(voterAmount, bonusAmount, totalAmount string) *db.Airdrop {
+		return &db.Airdrop{
+			Address:              address.String(),
+			VoterAmount:          voterAmount,
+			MultisigSignerAmount: "0",
+			GitcoinAmount:        "0",
+			ActiveBridgedAmount:  "0",
+			OpUserAmount:         "0",
+			OpRepeatUserAmount:   "0",
+			OpOgAmount:           "0",
+			BonusAmount:          bonusAmount,
+			TotalAmount:          totalAmount,
+		}
+	}
+
+	require.Nil(t, d.UpsertAirdrop(ctx, newAirdrop("100", "0", "100")))
+	history, err := d.GetAirdropHistory(ctx, address)
+	require.Nil(t, err)
+	require.Empty(t, history)
+
+	require.Nil(t, d.UpsertAirdrop(ctx, newAirdrop("100", "5", "105")))
+	require.Nil(t, d.UpsertAirdrop(ctx, newAirdrop("90", "5", "95")))
+
+	err = d.UpsertAirdrop(ctx, newAirdrop("90", "5", "100"))
+	require.True(t, errors.Is(err, db.ErrAirdropTotalMismatch))
+
+	airdrop, err := d.GetAirdrop(ctx, address)
+	require.Nil(t, err)
+	require.Equal(t, "95", airdrop.TotalAmount)
+
+	history, err = d.GetAirdropHistory(ctx, address)
+	require.Nil(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, "100", history[0].Previous.TotalAmount)
+	require.Equal(t, "105", history[1].Previous.TotalAmount)
+	require.Equal(t, strings.ToLower(address.String()), history[1].Previous.Address)
+}
+
+// TestGetAirdropTotals asserts that the amounts of every airdrop are summed
+// per category and overall, and that they are zero without airdrops.
+func TestGetAirdropTotals(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	totals, err := d.GetAirdropTotals(ctx)
+	require.Nil(t, err)
+	require.Equal(t, "0", totals.TotalAmount)
+	require.Equal(t, uint64(0), totals.Count)
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(insertAirdropStatement,
+		strings.ToLower(common.HexToAddress("0xbb01").String()), "100", "5", "105")
+	require.Nil(t, err)
+	_, err = conn.Exec(insertAirdropStatement,
+		strings.ToLower(common.HexToAddress("0xbb02").String()), "18446744073709551616", "0", "18446744073709551616")
+	require.Nil(t, err)
+
+	totals, err = d.GetAirdropTotals(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.AirdropTotals{
+		VoterAmount:          "18446744073709551716",
+		MultisigSignerAmount: "0",
+		GitcoinAmount:        "0",
+		ActiveBridgedAmount:  "0",
+		OpUserAmount:         "0",
+		OpRepeatUserAmount:   "0",
+		OpOgAmount:           "0",
+		BonusAmount:          "5",
+		TotalAmount:          "18446744073709551721",
+		Count:                2,
+	}, totals)
+}
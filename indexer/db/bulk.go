@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// BulkOptions tunes how AddIndexedL1Blocks writes a batch of blocks.
+type BulkOptions struct {
+	// AsynchronousCommit turns off synchronous_commit for the duration of the
+	// batch. The commit then returns before its WAL records are flushed to
+	// disk, which dramatically speeds up backfills. The tradeoff is that a
+	// crash of the database server may lose the last few committed batches.
+	// The database stays consistent, and since the indexer resumes from the
+	// highest stored block the lost blocks are simply indexed again, but it
+	// must not be enabled for writes that cannot be replayed. It is ignored
+	// by SQLite.
+	AsynchronousCommit bool
+
+	// DeferConstraints postpones foreign key checks until the batch commits,
+	// so that rows may be inserted before the rows they reference, e.g. to
+	// pipeline the insertion of events ahead of their block. A batch leaving
+	// a dangling reference fails as a whole on commit.
+	DeferConstraints bool
+}
+
+// AddIndexedL1Blocks inserts a batch of indexed L1 blocks in a single
+// transaction, in the given order. Either all blocks are inserted or none.
+// Writes are fully durable unless relaxed through opts.
+func (d *Database) AddIndexedL1Blocks(ctx context.Context, blocks []*IndexedL1Block, opts BulkOptions) error {
+	const setAsynchronousCommitStatement = `
+	SET LOCAL synchronous_commit = off
+	`
+
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		changes = nil
+
+		if opts.AsynchronousCommit && d.dialect == dialectPostgres {
+			if _, err := tx.ExecContext(ctx, setAsynchronousCommitStatement); err != nil {
+				return err
+			}
+		}
+		if opts.DeferConstraints {
+			if err := deferConstraints(ctx, tx, d.dialect); err != nil {
+				return err
+			}
+		}
+
+		for _, block := range blocks {
+			finalized, err := d.addIndexedL1Block(ctx, tx, block)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, finalized...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// deferConstraints postpones the foreign key checks of tx until it commits.
+func deferConstraints(ctx context.Context, tx *sql.Tx, dialect dialect) error {
+	const deferConstraintsStatement = `
+	SET CONSTRAINTS ALL DEFERRED
+	`
+	const deferForeignKeysStatementSQLite = `
+	PRAGMA defer_foreign_keys = ON
+	`
+
+	statement := deferConstraintsStatement
+	if dialect == dialectSQLite {
+		statement = deferForeignKeysStatementSQLite
+	}
+
+	_, err := tx.ExecContext(ctx, statement)
+	return err
+}
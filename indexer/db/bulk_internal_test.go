@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder collects the statements sent over its connections.
+type recorder struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *recorder) record(query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, strings.TrimSpace(query))
+}
+
+func (r *recorder) reset() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statements := r.statements
+	r.statements = nil
+	return statements
+}
+
+// recordingConnector hands out connections that only expose driver.Conn, so
+// that database/sql prepares every statement it runs and the recorder sees it.
+type recordingConnector struct {
+	driver.Connector
+	recorder *recorder
+}
+
+func (c *recordingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingConn{Conn: conn, recorder: c.recorder}, nil
+}
+
+type recordingConn struct {
+	driver.Conn
+	recorder *recorder
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	c.recorder.record(query)
+	return c.Conn.Prepare(query)
+}
+
+// newRecordingDatabase returns a migrated Database whose statements are
+// recorded.
+func newRecordingDatabase(t *testing.T) (*Database, *recorder) {
+	dbName := uuid.NewString()
+	conn, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+	_, err = conn.Exec(fmt.Sprintf("CREATE DATABASE \"%s\";", dbName))
+	require.Nil(t, err)
+	require.Nil(t, conn.Close())
+
+	dsn := fmt.Sprintf("%s dbname=%s", testDSN, dbName)
+	d, err := NewDatabase(dsn)
+	require.Nil(t, err)
+	require.Nil(t, d.db.Close())
+
+	connector, err := pq.NewConnector(dsn)
+	require.Nil(t, err)
+	r := &recorder{}
+	d.db = sql.OpenDB(&recordingConnector{Connector: connector, recorder: r})
+
+	return d, r
+}
+
+// TestAddIndexedL1BlocksAsynchronousCommit asserts that synchronous_commit is
+// only relaxed by the bulk path, and only when asked to.
+func TestAddIndexedL1BlocksAsynchronousCommit(t *testing.T) {
+	t.Parallel()
+
+	d, r := newRecordingDatabase(t)
+	defer d.Close()
+
+	isSetLocal := func(statement string) bool {
+		return strings.HasPrefix(statement, "SET LOCAL synchronous_commit")
+	}
+
+	err := d.AddIndexedL1Block(context.Background(), &IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+	})
+	require.Nil(t, err)
+	for _, statement := range r.reset() {
+		require.False(t, isSetLocal(statement))
+	}
+
+	err = d.AddIndexedL1Blocks(context.Background(), []*IndexedL1Block{
+		{
+			Hash:       common.HexToHash("0x02"),
+			ParentHash: common.HexToHash("0x01"),
+			Number:     2,
+			Timestamp:  2,
+		},
+	}, BulkOptions{})
+	require.Nil(t, err)
+	for _, statement := range r.reset() {
+		require.False(t, isSetLocal(statement))
+	}
+
+	err = d.AddIndexedL1Blocks(context.Background(), []*IndexedL1Block{
+		{
+			Hash:       common.HexToHash("0x03"),
+			ParentHash: common.HexToHash("0x02"),
+			Number:     3,
+			Timestamp:  3,
+		},
+		{
+			Hash:       common.HexToHash("0x04"),
+			ParentHash: common.HexToHash("0x03"),
+			Number:     4,
+			Timestamp:  4,
+		},
+	}, BulkOptions{AsynchronousCommit: true})
+	require.Nil(t, err)
+	statements := r.reset()
+	require.NotEmpty(t, statements)
+	require.True(t, isSetLocal(statements[0]))
+	for _, statement := range statements[1:] {
+		require.False(t, isSetLocal(statement))
+	}
+
+	highest, err := d.GetHighestL1Block(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, uint64(4), highest.Number)
+}
+
+// TestDeferConstraints asserts that, once constraints are deferred, deposits
+// may be inserted before their block within a transaction, and that a
+// dangling reference still fails the transaction on commit.
+func TestDeferConstraints(t *testing.T) {
+	t.Parallel()
+
+	d, _ := newRecordingDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	block := &IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+	}
+	deposit := Deposit{
+		TxHash:      common.HexToHash("0xff01"),
+		L1Token:     common.HexToAddress(ETHL1Token.Address),
+		L2Token:     ETHL2Address,
+		FromAddress: common.HexToAddress("0xaa01"),
+		ToAddress:   common.HexToAddress("0xaa02"),
+		Amount:      big.NewInt(1),
+		Data:        []byte{},
+	}
+
+	err := txn(ctx, d.db, func(tx *sql.Tx) error {
+		return d.insertDeposits(ctx, tx, block.Hash, []Deposit{deposit})
+	})
+	require.NotNil(t, err)
+
+	err = txn(ctx, d.db, func(tx *sql.Tx) error {
+		if err := deferConstraints(ctx, tx, d.dialect); err != nil {
+			return err
+		}
+		if err := d.insertDeposits(ctx, tx, block.Hash, []Deposit{deposit}); err != nil {
+			return err
+		}
+		_, err := d.addIndexedL1Block(ctx, tx, block)
+		return err
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(ctx, deposit.FromAddress, PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+
+	err = txn(ctx, d.db, func(tx *sql.Tx) error {
+		if err := deferConstraints(ctx, tx, d.dialect); err != nil {
+			return err
+		}
+		deposit.TxHash = common.HexToHash("0xff02")
+		return d.insertDeposits(ctx, tx, common.HexToHash("0x02"), []Deposit{deposit})
+	})
+	require.NotNil(t, err)
+}
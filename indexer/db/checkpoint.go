@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// l1CheckpointSetting names the setting holding the L1 ingestion checkpoint.
+const l1CheckpointSetting = "l1_checkpoint"
+
+// AddIndexedL1BlockWithCheckpoint inserts the indexed block like
+// AddIndexedL1Block and records checkpoint as the L1 ingestion checkpoint in
+// the same transaction. The checkpoint therefore never gets ahead of the
+// committed blocks: either both are written or neither is.
+func (d *Database) AddIndexedL1BlockWithCheckpoint(ctx context.Context, block *IndexedL1Block, checkpoint BlockLocator) error {
+	value, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	var changes []WithdrawalStatusChange
+	err = d.txn(ctx, func(tx *sql.Tx) error {
+		var err error
+		changes, err = d.addIndexedL1Block(ctx, tx, block)
+		if err != nil {
+			return err
+		}
+		return putSetting(ctx, tx, l1CheckpointSetting, string(value))
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// GetL1Checkpoint returns the L1 ingestion checkpoint recorded by
+// AddIndexedL1BlockWithCheckpoint, or nil if none was recorded yet. Rolling
+// back blocks with DeleteL1BlocksFrom or ReplaceIndexedL1Block moves it back
+// below them.
+func (d *Database) GetL1Checkpoint(ctx context.Context) (*BlockLocator, error) {
+	const selectSettingStatement = `
+	SELECT value FROM settings WHERE name = $1;
+	`
+
+	var checkpoint *BlockLocator
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		var value string
+		err := tx.QueryRowContext(ctx, selectSettingStatement, l1CheckpointSetting).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		checkpoint = new(BlockLocator)
+		return json.Unmarshal([]byte(value), checkpoint)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// clampL1Checkpoint moves the L1 ingestion checkpoint back below the given
+// block number within tx, if it is at or beyond it, so that it never points
+// at a rolled back block. The checkpoint is moved to the indexed block
+// preceding number, with a zero hash if that block is not indexed, and is
+// removed if number is 0.
+func clampL1Checkpoint(ctx context.Context, tx *sql.Tx, number uint64) error {
+	const selectSettingStatement = `
+	SELECT value FROM settings WHERE name = $1;
+	`
+
+	const selectBlockHashStatement = `
+	SELECT hash FROM l1_blocks WHERE number = $1 AND reorged_at IS NULL;
+	`
+
+	const deleteSettingStatement = `
+	DELETE FROM settings WHERE name = $1;
+	`
+
+	var value string
+	err := tx.QueryRowContext(ctx, selectSettingStatement, l1CheckpointSetting).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var checkpoint BlockLocator
+	if err := json.Unmarshal([]byte(value), &checkpoint); err != nil {
+		return err
+	}
+	if checkpoint.Number < number {
+		return nil
+	}
+	if number == 0 {
+		_, err := tx.ExecContext(ctx, deleteSettingStatement, l1CheckpointSetting)
+		return err
+	}
+
+	checkpoint = BlockLocator{Number: number - 1}
+	var hash string
+	err = tx.QueryRowContext(ctx, selectBlockHashStatement, checkpoint.Number).Scan(&hash)
+	switch {
+	case err == nil:
+		checkpoint.Hash = common.HexToHash(hash)
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	clamped, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return putSetting(ctx, tx, l1CheckpointSetting, string(clamped))
+}
+
+// putSetting sets the named setting to value within tx.
+func putSetting(ctx context.Context, tx *sql.Tx, name, value string) error {
+	const upsertSettingStatement = `
+	INSERT INTO settings
+		(name, value)
+	VALUES
+		($1, $2)
+	ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value;
+	`
+
+	_, err := tx.ExecContext(ctx, upsertSettingStatement, name, value)
+	return err
+}
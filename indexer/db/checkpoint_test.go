@@ -0,0 +1,106 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAddIndexedL1BlockWithCheckpoint asserts that the checkpoint is committed
+// together with its block, and left untouched when the block is rolled back.
+func TestAddIndexedL1BlockWithCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	checkpoint, err := d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Nil(t, checkpoint)
+
+	block := &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xdd01"), 0)},
+	}
+	err = d.AddIndexedL1BlockWithCheckpoint(ctx, block, db.BlockLocator{Number: 1, Hash: block.Hash})
+	require.Nil(t, err)
+
+	checkpoint, err = d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.BlockLocator{Number: 1, Hash: block.Hash}, checkpoint)
+
+	// Re-adding the block fails, so the newer checkpoint must not be stored.
+	err = d.AddIndexedL1BlockWithCheckpoint(ctx, block, db.BlockLocator{Number: 2, Hash: common.HexToHash("0x02")})
+	require.True(t, errors.Is(err, db.ErrDuplicateBlock))
+
+	checkpoint, err = d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.BlockLocator{Number: 1, Hash: block.Hash}, checkpoint)
+
+	stored, err := d.GetIndexedL1BlockByHash(ctx, block.Hash, true)
+	require.Nil(t, err)
+	require.Len(t, stored.Deposits, 1)
+}
+
+// TestL1CheckpointClampedOnRollback asserts that rolling back or replacing
+// blocks moves the checkpoint back below them, and leaves a checkpoint below
+// them untouched.
+func TestL1CheckpointClampedOnRollback(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	blocks := make([]*db.IndexedL1Block, 4)
+	for i := range blocks {
+		number := uint64(i + 1)
+		blocks[i] = &db.IndexedL1Block{
+			Hash:       common.BigToHash(new(big.Int).SetUint64(number)),
+			ParentHash: common.BigToHash(new(big.Int).SetUint64(number - 1)),
+			Number:     number,
+			Timestamp:  number,
+		}
+		err := d.AddIndexedL1BlockWithCheckpoint(ctx, blocks[i], db.BlockLocator{Number: number, Hash: blocks[i].Hash})
+		require.Nil(t, err)
+	}
+
+	err := d.DeleteL1BlocksFrom(ctx, 4)
+	require.Nil(t, err)
+	checkpoint, err := d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.BlockLocator{Number: 3, Hash: blocks[2].Hash}, checkpoint)
+
+	replacement := &db.IndexedL1Block{
+		Hash:       common.HexToHash("0xbb02"),
+		ParentHash: blocks[0].Hash,
+		Number:     2,
+		Timestamp:  2,
+	}
+	err = d.ReplaceIndexedL1Block(ctx, replacement)
+	require.Nil(t, err)
+	checkpoint, err = d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.BlockLocator{Number: 1, Hash: blocks[0].Hash}, checkpoint)
+
+	err = d.DeleteL1BlocksFrom(ctx, 3)
+	require.Nil(t, err)
+	checkpoint, err = d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.BlockLocator{Number: 1, Hash: blocks[0].Hash}, checkpoint)
+
+	err = d.DeleteL1BlocksFrom(ctx, 0)
+	require.Nil(t, err)
+	checkpoint, err = d.GetL1Checkpoint(ctx)
+	require.Nil(t, err)
+	require.Nil(t, checkpoint)
+}
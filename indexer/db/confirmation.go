@@ -0,0 +1,43 @@
+package db
+
+// ConfirmationStatus classifies how safe a deposit is from being reorged out,
+// based on the number of L1 blocks indexed on top of it.
+type ConfirmationStatus string
+
+const (
+	ConfirmationStatusUnconfirmed ConfirmationStatus = "UNCONFIRMED"
+	ConfirmationStatusConfirmed   ConfirmationStatus = "CONFIRMED"
+	ConfirmationStatusFinalized   ConfirmationStatus = "FINALIZED"
+)
+
+// DefaultConfirmationThresholds are used when the Database is not configured
+// with ConfirmationThresholds.
+var DefaultConfirmationThresholds = ConfirmationThresholds{
+	Confirmed: 12,
+	Finalized: 64,
+}
+
+// ConfirmationThresholds holds the confirmation depths at which a deposit is
+// considered confirmed and finalized. A deposit included in the current head
+// has a depth of one.
+type ConfirmationThresholds struct {
+	Confirmed uint64
+	Finalized uint64
+}
+
+// Status returns the ConfirmationStatus of a block relative to the given head.
+func (c ConfirmationThresholds) Status(blockNumber, head uint64) ConfirmationStatus {
+	if blockNumber > head {
+		return ConfirmationStatusUnconfirmed
+	}
+
+	depth := head - blockNumber + 1
+	switch {
+	case depth >= c.Finalized:
+		return ConfirmationStatusFinalized
+	case depth >= c.Confirmed:
+		return ConfirmationStatusConfirmed
+	default:
+		return ConfirmationStatusUnconfirmed
+	}
+}
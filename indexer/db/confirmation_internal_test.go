@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfirmationThresholdsStatus asserts that confirmation depths are mapped
+// to the expected status given fixed thresholds.
+func TestConfirmationThresholdsStatus(t *testing.T) {
+	thresholds := ConfirmationThresholds{
+		Confirmed: 3,
+		Finalized: 10,
+	}
+	const head = 100
+
+	tests := []struct {
+		name        string
+		blockNumber uint64
+		expStatus   ConfirmationStatus
+	}{
+		{"ahead of head", 101, ConfirmationStatusUnconfirmed},
+		{"head", 100, ConfirmationStatusUnconfirmed},
+		{"below confirmed", 99, ConfirmationStatusUnconfirmed},
+		{"at confirmed", 98, ConfirmationStatusConfirmed},
+		{"below finalized", 92, ConfirmationStatusConfirmed},
+		{"at finalized", 91, ConfirmationStatusFinalized},
+		{"genesis", 0, ConfirmationStatusFinalized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := thresholds.Status(test.blockNumber, head)
+			require.Equal(t, test.expStatus, status)
+		})
+	}
+}
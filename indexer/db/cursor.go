@@ -0,0 +1,80 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor signals that a pagination cursor could not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor identifies a row in a listing ordered by block number, log index and
+// guid. The guid breaks ties between rows sharing a block number and log
+// index, which happens when reorged rows are included.
+type Cursor struct {
+	BlockNumber uint64
+	LogIndex    uint64
+	GUID        string
+}
+
+// Encode returns the opaque string representation of the cursor.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d:%s", c.BlockNumber, c.LogIndex, c.GUID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor returned by Encode.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	parts := strings.SplitN(string(raw), ":", 3)
+	if len(parts) != 3 {
+		return Cursor{}, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	blockNumber, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	logIndex, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if _, err := uuid.Parse(parts[2]); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	return Cursor{
+		BlockNumber: blockNumber,
+		LogIndex:    logIndex,
+		GUID:        parts[2],
+	}, nil
+}
+
+// condition extends conditions with the keyset predicate selecting the rows
+// after the cursor, or from the cursor on if inclusive, along with args
+// extended by the values it binds. Rows after the cursor are the older ones
+// if descending.
+func (c Cursor) condition(tables activityTables, inclusive, descending bool, conditions string, args []interface{}) (string, []interface{}) {
+	operator := ">"
+	if descending {
+		operator = "<"
+	}
+	if inclusive {
+		operator += "="
+	}
+
+	args = append(args, c.BlockNumber, c.LogIndex, c.GUID)
+	n := len(args)
+	return fmt.Sprintf("%s AND (%s.number, %s.log_index, %s.guid) %s ($%d, $%d, $%d)",
+		conditions, tables.blocks, tables.activity, tables.activity,
+		operator, n-2, n-1, n), args
+}
@@ -1,70 +1,512 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 
-	// NOTE: Only postgresql backend is supported at the moment.
-	_ "github.com/lib/pq"
+	// NOTE: Only the postgresql driver is bundled. See dialect for SQLite.
+	"github.com/lib/pq"
 )
 
 // Database contains the database instance and the connection string.
 type Database struct {
-	db     *sql.DB
-	config string
+	// nextReplica is accessed atomically, so it is kept first for 64-bit
+	// alignment on 32-bit platforms.
+	nextReplica uint64
+
+	db            *sql.DB
+	ownsDB        bool
+	replicas      []*sql.DB
+	config        string
+	dialect       dialect
+	logger        log.Logger
+	maxPageOffset uint64
+	maxPageLimit  uint64
+	confirmations ConfirmationThresholds
+
+	onWithdrawalStatusChange func(WithdrawalStatusChange)
+	prices                   PriceProvider
+
+	finalizationPeriodSeconds uint64
+
+	finalizedWithdrawals *withdrawalStatusCache
+
+	txnMaxAttempts  int
+	txnRetryBackoff time.Duration
+	queryTimeout    time.Duration
+
+	statements *statementCache
+
+	l1Tokens *tokenCache
+	l2Tokens *tokenCache
+
+	slowQueryThreshold time.Duration
+	logSlowQueryArgs   bool
+
+	explain bool
+
+	softDeleteReorged bool
+
+	newGUID func() string
+}
+
+// DatabaseConfig holds the options used to open a Database.
+type DatabaseConfig struct {
+	// DSN is the postgres connection string. A DSN prefixed with the scheme
+	// of a SQLite driver opens a SQLite database instead, see dialect.
+	DSN string
+
+	// Logger receives operational logs such as applied migrations. The
+	// package logger is used when unset.
+	Logger log.Logger
+
+	// DisableMigrations skips applying pending migrations on startup. The
+	// database schema must then already be at SchemaVersion.
+	DisableMigrations bool
+
+	// SkipSchemaVersionCheck opens the database whatever its schema version,
+	// so that operators can inspect and upgrade it step by step with
+	// CurrentSchemaVersion and MigrateTo. Queries may fail until the schema
+	// is at SchemaVersion.
+	SkipSchemaVersionCheck bool
+
+	// MaxPageOffset is the deepest row paginated getters will serve, i.e.
+	// the largest offset plus limit. DefaultMaxPageOffset is used when unset.
+	MaxPageOffset uint64
+
+	// MaxPageLimit is the largest page paginated getters will serve.
+	// DefaultMaxPageLimit is used when unset.
+	MaxPageLimit uint64
+
+	// ReplicaDSNs are the connection strings of read replicas of the
+	// primary database. Listings, lookups and aggregates are spread over
+	// them in turn, while writes and the reads the indexer resumes from go
+	// to the primary.
+	ReplicaDSNs []string
+
+	// ConfirmationThresholds classify deposits by their confirmation depth.
+	// DefaultConfirmationThresholds are used when unset.
+	ConfirmationThresholds *ConfirmationThresholds
+
+	// MaxOpenConns caps the connections opened to each database.
+	// DefaultMaxOpenConns is used when unset.
+	MaxOpenConns int
+
+	// MaxIdleConns caps the idle connections kept open to each database.
+	// DefaultMaxIdleConns is used when unset.
+	MaxIdleConns int
+
+	// ConnMaxLifetime is how long a connection may be reused before it is
+	// closed. DefaultConnMaxLifetime is used when unset.
+	ConnMaxLifetime time.Duration
+
+	// OnWithdrawalStatusChange is called for every withdrawal whose status
+	// changed, once the transaction changing it has committed. It runs on the
+	// committing goroutine, so slow work such as posting a webhook should be
+	// handed off.
+	OnWithdrawalStatusChange func(WithdrawalStatusChange)
+
+	// PriceProvider attaches USD values to listed deposits. Values are left
+	// unset when it is nil.
+	PriceProvider PriceProvider
+
+	// FinalizationPeriodSeconds is how long after its L2 block a withdrawal
+	// becomes finalizable on L1. DefaultFinalizationPeriodSeconds is used
+	// when unset.
+	FinalizationPeriodSeconds uint64
+
+	// WithdrawalStatusCacheSize is the number of finalized withdrawal
+	// statuses GetWithdrawalStatus keeps in memory. The cache is purged by
+	// rollbacks through this Database only, so processes serving reads off
+	// a database written by another process should keep it small.
+	// DefaultWithdrawalStatusCacheSize is used when unset.
+	WithdrawalStatusCacheSize int
+
+	// TokenCacheSize is the number of tokens of each layer
+	// GetL1TokenByAddress and GetL2TokenByAddress keep in memory, evicting
+	// the least recently used ones. Tokens written through this Database are
+	// invalidated right away. The cache is disabled when unset.
+	TokenCacheSize int
+
+	// TokenCacheTTL is how long a cached token is served before it is looked
+	// up again, which bounds how stale tokens written by another process may
+	// get. DefaultTokenCacheTTL is used when unset.
+	TokenCacheTTL time.Duration
+
+	// TxnMaxAttempts is how many times a transaction is attempted when it
+	// fails with a serialization failure or a deadlock. Other errors are
+	// returned immediately. DefaultTxnMaxAttempts is used when unset, and 1
+	// disables retries.
+	TxnMaxAttempts int
+
+	// TxnRetryBackoff is how long to wait before retrying a transaction for
+	// the first time. The wait doubles for every subsequent retry.
+	// DefaultTxnRetryBackoff is used when unset.
+	TxnRetryBackoff time.Duration
+
+	// QueryTimeout caps how long a transaction or query may run, retries
+	// included, whatever the context it is given: a context without a
+	// deadline is bounded by QueryTimeout, and a context whose deadline is
+	// further away is cut short to it, while an earlier deadline is kept.
+	// Running out of time fails with context.DeadlineExceeded. On Postgres,
+	// the statements of a transaction are also bounded server side through
+	// statement_timeout, so that a statement running past the deadline is
+	// cancelled even if the context it runs with has none. Migrations,
+	// VacuumAnalyze, HealthCheck and the streaming exports are only bounded
+	// by their context, as they either run for long or are probes with
+	// their own deadline. Nothing is bounded when unset.
+	QueryTimeout time.Duration
+
+	// StatementTimeout sets statement_timeout on every connection opened to
+	// a Postgres database, primary and replicas alike, so that Postgres
+	// cancels any statement running for longer, including those run with a
+	// context without deadline. Unlike QueryTimeout, it bounds each statement
+	// rather than whole transactions and also applies to migrations and
+	// exports. Statements are not bounded when unset.
+	StatementTimeout time.Duration
+
+	// SoftDeleteReorged tombstones the L1 blocks and deposits rolled back by
+	// DeleteL1BlocksFrom and ReplaceIndexedL1Block rather than deleting them,
+	// so that reorgs leave an audit trail. Tombstoned rows are hidden from
+	// every getter but keep taking space until they are deleted with
+	// PurgeReorged.
+	SoftDeleteReorged bool
+
+	// RandomGUIDs gives new deposits and withdrawals random (version 4)
+	// guids rather than the time-ordered (version 7) guids of NewGUID, for
+	// deployments that must not expose when a row was indexed through its
+	// guid.
+	RandomGUIDs bool
+
+	// DisableStatementCache runs every query ad hoc rather than caching
+	// prepared statements for the hottest ones. Prepared statements are
+	// bound to their connection, so the cache must be disabled behind a
+	// connection pooler in transaction mode.
+	DisableStatementCache bool
+
+	// SlowQueryThreshold is how long a transaction or query may take before
+	// the Database method running it is logged at WARN along with its
+	// duration. Slow queries are not logged when unset.
+	SlowQueryThreshold time.Duration
+
+	// LogSlowQueryArgs also logs the arguments of slow queries where they
+	// are known. They may hold addresses, so this is meant for debugging.
+	LogSlowQueryArgs bool
+
+	// EnableExplain allows Explain to run the listing queries under EXPLAIN
+	// ANALYZE. It is meant for investigating slow queries, so Explain fails
+	// with ErrExplainDisabled unless it is set.
+	EnableExplain bool
+}
+
+const (
+	// DefaultMaxOpenConns is the number of connections opened to each
+	// database when the Database is not configured with MaxOpenConns.
+	DefaultMaxOpenConns = 20
+
+	// DefaultMaxIdleConns is the number of idle connections kept open to
+	// each database when the Database is not configured with MaxIdleConns.
+	DefaultMaxIdleConns = 5
+
+	// DefaultConnMaxLifetime is how long connections are reused when the
+	// Database is not configured with ConnMaxLifetime.
+	DefaultConnMaxLifetime = 30 * time.Minute
+
+	// DefaultTxnMaxAttempts is how many times a transaction is attempted
+	// when the Database is not configured with TxnMaxAttempts.
+	DefaultTxnMaxAttempts = 3
+
+	// DefaultTxnRetryBackoff is how long to wait before the first retry of a
+	// transaction when the Database is not configured with TxnRetryBackoff.
+	DefaultTxnRetryBackoff = 50 * time.Millisecond
+)
+
+// configurePool applies the connection pool settings of cfg to db, falling
+// back to the defaults for unset settings. SQLite connections are never
+// recycled and default to a single open connection, as closing the last
+// connection to an in-memory database discards it.
+func configurePool(db *sql.DB, dialect dialect, cfg DatabaseConfig) {
+	maxOpenConns := cfg.MaxOpenConns
+	if maxOpenConns == 0 {
+		maxOpenConns = DefaultMaxOpenConns
+		if dialect == dialectSQLite {
+			maxOpenConns = 1
+		}
+	}
+	maxIdleConns := cfg.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = DefaultMaxIdleConns
+	}
+	connMaxLifetime := cfg.ConnMaxLifetime
+	if connMaxLifetime == 0 {
+		connMaxLifetime = DefaultConnMaxLifetime
+	}
+
+	if dialect == dialectSQLite {
+		connMaxLifetime = 0
+	}
+
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
 }
 
-// NewDatabase returns the database for the given connection string.
+// NewDatabase returns the database for the given connection string, using the
+// default settings of DatabaseConfig.
 func NewDatabase(config string) (*Database, error) {
-	db, err := sql.Open("postgres", config)
+	return NewDatabaseWithConfig(DatabaseConfig{DSN: config})
+}
+
+// NewDatabaseWithConfig returns the database for the given config and applies
+// any pending migrations. It fails with ErrSchemaVersionMismatch if the schema
+// does not match SchemaVersion afterwards.
+func NewDatabaseWithConfig(cfg DatabaseConfig) (*Database, error) {
+	db, dialect, err := openDB(withStatementTimeout(cfg.DSN, cfg.StatementTimeout))
+	if err != nil {
+		return nil, err
+	}
+	configurePool(db, dialect, cfg)
+
+	d, err := newDatabase(db, dialect, cfg)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
+	d.ownsDB = true
+	return d, nil
+}
+
+// NewDatabaseWithDB returns the database backed by the given Postgres pool,
+// e.g. one shared with other subsystems or set up by a test, using the default
+// settings of DatabaseConfig. The pool is used as is: its connection settings
+// are left untouched and Close leaves it open. It is pinged and pending
+// migrations are applied as by NewDatabaseWithConfig. Config returns an empty
+// string.
+func NewDatabaseWithDB(db *sql.DB) (*Database, error) {
+	return newDatabase(db, dialectPostgres, DatabaseConfig{})
+}
+
+// newDatabase returns the database backed by db once it is reachable and its
+// schema is up to date, applying the settings of cfg other than its DSN and
+// pool settings.
+func newDatabase(db *sql.DB, dialect dialect, cfg DatabaseConfig) (*Database, error) {
+	ctx := context.Background()
 
-	err = db.Ping()
+	err := db.PingContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, migration := range schema {
-		_, err = db.Exec(migration)
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.New("module", "db")
+	}
+
+	maxPageOffset := cfg.MaxPageOffset
+	if maxPageOffset == 0 {
+		maxPageOffset = DefaultMaxPageOffset
+	}
+
+	maxPageLimit := cfg.MaxPageLimit
+	if maxPageLimit == 0 {
+		maxPageLimit = DefaultMaxPageLimit
+	}
+
+	finalizationPeriodSeconds := cfg.FinalizationPeriodSeconds
+	if finalizationPeriodSeconds == 0 {
+		finalizationPeriodSeconds = DefaultFinalizationPeriodSeconds
+	}
+
+	withdrawalStatusCacheSize := cfg.WithdrawalStatusCacheSize
+	if withdrawalStatusCacheSize == 0 {
+		withdrawalStatusCacheSize = DefaultWithdrawalStatusCacheSize
+	}
+
+	tokenCacheTTL := cfg.TokenCacheTTL
+	if tokenCacheTTL == 0 {
+		tokenCacheTTL = DefaultTokenCacheTTL
+	}
+
+	txnMaxAttempts := cfg.TxnMaxAttempts
+	if txnMaxAttempts == 0 {
+		txnMaxAttempts = DefaultTxnMaxAttempts
+	}
+
+	txnRetryBackoff := cfg.TxnRetryBackoff
+	if txnRetryBackoff == 0 {
+		txnRetryBackoff = DefaultTxnRetryBackoff
+	}
+
+	confirmations := DefaultConfirmationThresholds
+	if cfg.ConfirmationThresholds != nil {
+		confirmations = *cfg.ConfirmationThresholds
+	}
+
+	// The replicas opened so far are closed should any later step fail. db
+	// belongs to the caller, which closes it if it owns it.
+	var replicas []*sql.DB
+	succeeded := false
+	defer func() {
+		if succeeded {
+			return
+		}
+		for _, replica := range replicas {
+			replica.Close()
+		}
+	}()
+
+	for _, dsn := range cfg.ReplicaDSNs {
+		replica, replicaDialect, err := openDB(withStatementTimeout(dsn, cfg.StatementTimeout))
 		if err != nil {
 			return nil, err
 		}
+		replicas = append(replicas, replica)
+		configurePool(replica, replicaDialect, cfg)
+		if err := replica.PingContext(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	return &Database{
-		db:     db,
-		config: config,
-	}, nil
+	d := &Database{
+		db:            db,
+		replicas:      replicas,
+		config:        cfg.DSN,
+		dialect:       dialect,
+		logger:        logger,
+		maxPageOffset: maxPageOffset,
+		maxPageLimit:  maxPageLimit,
+		confirmations: confirmations,
+
+		onWithdrawalStatusChange: cfg.OnWithdrawalStatusChange,
+		prices:                   cfg.PriceProvider,
+
+		finalizationPeriodSeconds: finalizationPeriodSeconds,
+
+		finalizedWithdrawals: newWithdrawalStatusCache(withdrawalStatusCacheSize),
+
+		txnMaxAttempts:  txnMaxAttempts,
+		txnRetryBackoff: txnRetryBackoff,
+		queryTimeout:    cfg.QueryTimeout,
+
+		// Statements are prepared on the pool rather than in the
+		// transaction, which needs a second connection that SQLite pools
+		// usually do not have.
+		statements: newStatementCache(cfg.DisableStatementCache || dialect == dialectSQLite),
+
+		l1Tokens: newTokenCache(cfg.TokenCacheSize, tokenCacheTTL),
+		l2Tokens: newTokenCache(cfg.TokenCacheSize, tokenCacheTTL),
+
+		slowQueryThreshold: cfg.SlowQueryThreshold,
+		logSlowQueryArgs:   cfg.LogSlowQueryArgs,
+
+		explain: cfg.EnableExplain,
+
+		softDeleteReorged: cfg.SoftDeleteReorged,
+
+		newGUID: NewGUID,
+	}
+	if cfg.RandomGUIDs {
+		d.newGUID = newRandomGUID
+	}
+
+	if !cfg.DisableMigrations {
+		if err := d.Migrate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if !cfg.SkipSchemaVersionCheck {
+		if err := d.checkSchemaVersion(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	succeeded = true
+	return d, nil
 }
 
-// Close closes the database.
+// Close closes the cached statements, the database and its replicas. A pool
+// given to NewDatabaseWithDB is left open for its owner to close. Every pool
+// is closed even if closing another one fails, and the first error is
+// returned.
 // NOTE: "It is rarely necessary to close a DB."
 // See: https://pkg.go.dev/database/sql#Open
 func (d *Database) Close() error {
-	return d.db.Close()
+	firstErr := d.statements.close()
+	for _, replica := range d.replicas {
+		if err := replica.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if d.ownsDB {
+		if err := d.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
-// Config returns the db connection string.
+// Config returns the db connection string, which is empty for a Database
+// created with NewDatabaseWithDB. It includes the password if the connection
+// string does, so use RedactedConfig to log it.
 func (d *Database) Config() string {
 	return d.config
 }
 
+// RedactedConfig returns the db connection string like Config, with its
+// password replaced by ***, so that it can be logged.
+func (d *Database) RedactedConfig() string {
+	return redactDSN(d.config)
+}
+
+// HealthCheck reports whether the database is reachable and able to run
+// queries. It is cheap enough to back a readiness probe polled every few
+// seconds.
+func (d *Database) HealthCheck(ctx context.Context) error {
+	if err := d.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", contextErr(ctx, err))
+	}
+
+	var one int
+	if err := d.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("query failed: %w", contextErr(ctx, err))
+	}
+
+	return nil
+}
+
+// Stats returns the statistics of the primary connection pool, e.g. to
+// surface the number of open connections alongside HealthCheck.
+func (d *Database) Stats() sql.DBStats {
+	return d.db.Stats()
+}
+
 // GetL1TokenByAddress returns the ERC20 Token corresponding to the given
-// address on L1.
-func (d *Database) GetL1TokenByAddress(address string) (*Token, error) {
+// address on L1, or ErrTokenNotFound if it is not indexed.
+func (d *Database) GetL1TokenByAddress(ctx context.Context, address string) (*Token, error) {
 	const selectL1TokenStatement = `
 	SELECT name, symbol, decimals FROM l1_tokens WHERE address = $1;
 	`
 
+	address = normalizeAddress(address)
+	if token, ok := d.l1Tokens.get(address); ok {
+		return token, nil
+	}
+	generation := d.l1Tokens.currentGeneration()
+
 	var token *Token
-	err := txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectL1TokenStatement, address)
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		row := d.queryRowPrepared(ctx, d.db, tx, selectL1TokenStatement, address)
 		if row.Err() != nil {
 			return row.Err()
 		}
@@ -74,7 +516,7 @@ func (d *Database) GetL1TokenByAddress(address string) (*Token, error) {
 		var decimals uint8
 		err := row.Scan(&name, &symbol, &decimals)
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil
+			return ErrTokenNotFound
 		}
 		if err != nil {
 			return err
@@ -91,19 +533,26 @@ func (d *Database) GetL1TokenByAddress(address string) (*Token, error) {
 		return nil, err
 	}
 
+	d.l1Tokens.add(address, token, generation)
 	return token, nil
 }
 
 // GetL2TokenByAddress returns the ERC20 Token corresponding to the given
-// address on L2.
-func (d *Database) GetL2TokenByAddress(address string) (*Token, error) {
+// address on L2, or ErrTokenNotFound if it is not indexed.
+func (d *Database) GetL2TokenByAddress(ctx context.Context, address string) (*Token, error) {
 	const selectL2TokenStatement = `
 	SELECT name, symbol, decimals FROM l2_tokens WHERE address = $1;
 	`
 
+	address = normalizeAddress(address)
+	if token, ok := d.l2Tokens.get(address); ok {
+		return token, nil
+	}
+	generation := d.l2Tokens.currentGeneration()
+
 	var token *Token
-	err := txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectL2TokenStatement, address)
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		row := d.queryRowPrepared(ctx, d.db, tx, selectL2TokenStatement, address)
 		if row.Err() != nil {
 			return row.Err()
 		}
@@ -113,7 +562,7 @@ func (d *Database) GetL2TokenByAddress(address string) (*Token, error) {
 		var decimals uint8
 		err := row.Scan(&name, &symbol, &decimals)
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil
+			return ErrTokenNotFound
 		}
 		if err != nil {
 			return err
@@ -131,13 +580,157 @@ func (d *Database) GetL2TokenByAddress(address string) (*Token, error) {
 		return nil, err
 	}
 
+	d.l2Tokens.add(address, token, generation)
 	return token, nil
 }
 
+// GetL1TokensByAddresses returns the L1 tokens indexed at the given addresses
+// in a single query, keyed by lowercase address. Addresses that are not
+// indexed are absent from the map.
+func (d *Database) GetL1TokensByAddresses(ctx context.Context, addresses []string) (map[string]*Token, error) {
+	return d.getTokensByAddresses(ctx, "l1_tokens", addresses)
+}
+
+// GetL2TokensByAddresses is the L2 equivalent of GetL1TokensByAddresses.
+func (d *Database) GetL2TokensByAddresses(ctx context.Context, addresses []string) (map[string]*Token, error) {
+	return d.getTokensByAddresses(ctx, "l2_tokens", addresses)
+}
+
+func (d *Database) getTokensByAddresses(ctx context.Context, table string, addresses []string) (map[string]*Token, error) {
+	const selectTokensStatement = `
+	SELECT address, name, symbol, decimals FROM %s WHERE address = ANY($1);
+	`
+
+	if d.dialect != dialectPostgres {
+		return nil, ErrUnsupportedDialect
+	}
+
+	normalized := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		normalized = append(normalized, normalizeAddress(address))
+	}
+
+	var tokens map[string]*Token
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		tokens = make(map[string]*Token)
+
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(selectTokensStatement, table), pq.Array(normalized))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			token := new(Token)
+			if err := rows.Scan(&token.Address, &token.Name, &token.Symbol, &token.Decimals); err != nil {
+				return err
+			}
+			tokens[token.Address] = token
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// GetAllL1Tokens returns the indexed L1 tokens ordered by symbol, paginated by
+// the given params. Only tokens whose symbol starts with symbolPrefix,
+// ignoring case, are returned, e.g. to suggest tokens as the user types; all
+// tokens match an empty prefix. Offset, SkipTotal and HasMore behave as for
+// GetDeposits.
+func (d *Database) GetAllL1Tokens(ctx context.Context, symbolPrefix string, page PaginationParam) (*PaginatedTokens, error) {
+	return d.getAllTokens(ctx, "l1_tokens", symbolPrefix, page)
+}
+
+// GetAllL2Tokens is the L2 equivalent of GetAllL1Tokens.
+func (d *Database) GetAllL2Tokens(ctx context.Context, symbolPrefix string, page PaginationParam) (*PaginatedTokens, error) {
+	return d.getAllTokens(ctx, "l2_tokens", symbolPrefix, page)
+}
+
+func (d *Database) getAllTokens(ctx context.Context, table, symbolPrefix string, page PaginationParam) (*PaginatedTokens, error) {
+	const selectTokensStatement = `
+	SELECT address, name, symbol, decimals FROM %s
+	WHERE LOWER(symbol) LIKE $3 ESCAPE '\'
+	ORDER BY symbol, address
+	LIMIT $1 OFFSET $2;
+	`
+	const selectTokenCountStatement = `
+	SELECT count(*) FROM %s WHERE LOWER(symbol) LIKE $1 ESCAPE '\';
+	`
+
+	if err := d.validatePage(&page); err != nil {
+		return nil, err
+	}
+	pattern := likePrefix(strings.ToLower(symbolPrefix))
+
+	var tokens []Token
+	info := newPageInfo(page)
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		tokens = nil
+		info.Total = 0
+
+		rows, err := tx.QueryContext(
+			ctx, fmt.Sprintf(selectTokensStatement, table),
+			pageLimit(page), page.Offset, pattern,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var token Token
+			if err := rows.Scan(&token.Address, &token.Name, &token.Symbol, &token.Decimals); err != nil {
+				return err
+			}
+			tokens = append(tokens, token)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if page.SkipTotal {
+			return nil
+		}
+		row := tx.QueryRowContext(ctx, fmt.Sprintf(selectTokenCountStatement, table), pattern)
+		return row.Scan(&info.Total)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if page.SkipTotal {
+		info.HasMore = uint64(len(tokens)) > page.Limit
+		if info.HasMore {
+			tokens = tokens[:page.Limit]
+		}
+	}
+	info.ByteSize, err = pageByteSize(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedTokens{
+		info,
+		tokens,
+	}, nil
+}
+
+// likePrefix returns the LIKE pattern matching the strings starting with
+// prefix, escaping its wildcards with a backslash.
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	return escaped + "%"
+}
+
 // AddL1Token inserts the Token details for the given address into the known L1
 // tokens database.
 // NOTE: a Token MUST have a unique address
-func (d *Database) AddL1Token(address string, token *Token) error {
+func (d *Database) AddL1Token(ctx context.Context, address string, token *Token) error {
 	const insertTokenStatement = `
 	INSERT INTO l1_tokens
 		(address, name, symbol, decimals)
@@ -145,8 +738,12 @@ func (d *Database) AddL1Token(address string, token *Token) error {
 		($1, $2, $3, $4)
 	`
 
-	return txn(d.db, func(tx *sql.Tx) error {
-		_, err := tx.Exec(
+	address = normalizeAddress(address)
+	defer d.l1Tokens.remove(address)
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
 			insertTokenStatement,
 			address,
 			token.Name,
@@ -160,7 +757,7 @@ func (d *Database) AddL1Token(address string, token *Token) error {
 // AddL2Token inserts the Token details for the given address into the known L2
 // tokens database.
 // NOTE: a Token MUST have a unique address
-func (d *Database) AddL2Token(address string, token *Token) error {
+func (d *Database) AddL2Token(ctx context.Context, address string, token *Token) error {
 	const insertTokenStatement = `
 	INSERT INTO l2_tokens
 		(address, name, symbol, decimals)
@@ -168,8 +765,12 @@ func (d *Database) AddL2Token(address string, token *Token) error {
 		($1, $2, $3, $4)
 	`
 
-	return txn(d.db, func(tx *sql.Tx) error {
-		_, err := tx.Exec(
+	address = normalizeAddress(address)
+	defer d.l2Tokens.remove(address)
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
 			insertTokenStatement,
 			address,
 			token.Name,
@@ -180,136 +781,427 @@ func (d *Database) AddL2Token(address string, token *Token) error {
 	})
 }
 
-// AddIndexedL1Block inserts the indexed block i.e. the L1 block containing all
-// scanned Deposits into the known deposits database.
-// NOTE: the block hash MUST be unique
-func (d *Database) AddIndexedL1Block(block *IndexedL1Block) error {
-	const insertBlockStatement = `
-	INSERT INTO l1_blocks
-		(hash, parent_hash, number, timestamp)
+// UpsertL1Token inserts the Token details for the given address into the
+// known L1 tokens database, or overwrites the name, symbol and decimals of a
+// token already known at that address, e.g. once they have been corrected
+// after a proxy upgrade. Use AddL1Token to treat a known address as an error.
+func (d *Database) UpsertL1Token(ctx context.Context, address string, token *Token) error {
+	return d.upsertToken(ctx, "l1_tokens", address, token)
+}
+
+// UpsertL2Token is the L2 equivalent of UpsertL1Token.
+func (d *Database) UpsertL2Token(ctx context.Context, address string, token *Token) error {
+	return d.upsertToken(ctx, "l2_tokens", address, token)
+}
+
+func (d *Database) upsertToken(ctx context.Context, table, address string, token *Token) error {
+	const upsertTokenStatement = `
+	INSERT INTO %s
+		(address, name, symbol, decimals)
 	VALUES
 		($1, $2, $3, $4)
+	ON CONFLICT (address)
+		DO UPDATE SET name = $2, symbol = $3, decimals = $4
 	`
 
-	const insertDepositStatement = `
-	INSERT INTO deposits
-		(guid, from_address, to_address, l1_token, l2_token, amount, tx_hash, log_index, l1_block_hash, data)
-	VALUES
-		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	address = normalizeAddress(address)
+	defer d.tokenCache(table).remove(address)
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			fmt.Sprintf(upsertTokenStatement, table),
+			address,
+			token.Name,
+			token.Symbol,
+			token.Decimals,
+		)
+		return err
+	})
+}
+
+// SetL1TokenVerified marks the L1 token at the given address as verified or
+// unverified. Deposits of unverified tokens are hidden from listings by
+// default since they are likely to impersonate well-known tokens.
+func (d *Database) SetL1TokenVerified(ctx context.Context, address string, verified bool) error {
+	const updateTokenVerifiedStatement = `
+	UPDATE l1_tokens SET verified = $2 WHERE address = $1
 	`
 
-	const insertWithdrawalStatement = `
-	INSERT INTO withdrawals
-		(guid, from_address, to_address, l1_token, l2_token, amount, tx_hash, log_index, l1_block_hash, data)
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, updateTokenVerifiedStatement, normalizeAddress(address), verified)
+		return err
+	})
+}
+
+// AddL1Tokens inserts the given L1 tokens keyed by address in a single
+// transaction and returns how many were newly inserted. Tokens that are
+// already known are left untouched rather than failing the batch.
+func (d *Database) AddL1Tokens(ctx context.Context, tokens map[string]*Token) (int64, error) {
+	return d.addTokens(ctx, "l1_tokens", tokens)
+}
+
+// AddL2Tokens is the L2 equivalent of AddL1Tokens.
+func (d *Database) AddL2Tokens(ctx context.Context, tokens map[string]*Token) (int64, error) {
+	return d.addTokens(ctx, "l2_tokens", tokens)
+}
+
+// maxTokensPerInsert bounds the number of rows of a single token insert so
+// that its parameters stay well below the 65535 Postgres allows.
+const maxTokensPerInsert = 1000
+
+func (d *Database) addTokens(ctx context.Context, table string, tokens map[string]*Token) (int64, error) {
+	const insertTokensStatement = `
+	INSERT INTO %s
+		(address, name, symbol, decimals)
 	VALUES
-		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	ON CONFLICT (tx_hash)
-		DO UPDATE SET l1_block_hash = $9;
 	`
+	const columns = 4
 
-	return txn(d.db, func(tx *sql.Tx) error {
-		_, err := tx.Exec(
-			insertBlockStatement,
-			block.Hash.String(),
-			block.ParentHash.String(),
-			block.Number,
-			block.Timestamp,
-		)
-		if err != nil {
-			return err
-		}
+	tokens = normalizeTokenAddresses(tokens)
+	addresses := make([]string, 0, len(tokens))
+	for address := range tokens {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var inserted int64
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		inserted = 0
+		for start := 0; start < len(addresses); start += maxTokensPerInsert {
+			end := start + maxTokensPerInsert
+			if end > len(addresses) {
+				end = len(addresses)
+			}
 
-		if len(block.Deposits) == 0 {
-			return nil
-		}
+			var statement strings.Builder
+			fmt.Fprintf(&statement, insertTokensStatement, table)
+			args := make([]interface{}, 0, (end-start)*columns)
+			for i, address := range addresses[start:end] {
+				if i > 0 {
+					statement.WriteString(",")
+				}
+				fmt.Fprintf(&statement, "\n\t\t($%d, $%d, $%d, $%d)",
+					i*columns+1, i*columns+2, i*columns+3, i*columns+4)
+
+				token := tokens[address]
+				args = append(args, address, token.Name, token.Symbol, token.Decimals)
+			}
+			statement.WriteString("\n\tON CONFLICT (address) DO NOTHING;")
 
-		for _, deposit := range block.Deposits {
-			_, err = tx.Exec(
-				insertDepositStatement,
-				NewGUID(),
-				deposit.FromAddress.String(),
-				deposit.ToAddress.String(),
-				deposit.L1Token.String(),
-				deposit.L2Token.String(),
-				deposit.Amount.String(),
-				deposit.TxHash.String(),
-				deposit.LogIndex,
-				block.Hash.String(),
-				deposit.Data,
-			)
+			result, err := tx.ExecContext(ctx, statement.String(), args...)
 			if err != nil {
 				return err
 			}
-		}
-
-		if len(block.Withdrawals) == 0 {
-			return nil
-		}
-
-		for _, withdrawal := range block.Withdrawals {
-			_, err = tx.Exec(
-				insertWithdrawalStatement,
-				NewGUID(),
-				withdrawal.FromAddress.String(),
-				withdrawal.ToAddress.String(),
-				withdrawal.L1Token.String(),
-				withdrawal.L2Token.String(),
-				withdrawal.Amount.String(),
-				withdrawal.TxHash.String(),
-				withdrawal.LogIndex,
-				block.Hash.String(),
-				withdrawal.Data,
-			)
+			n, err := result.RowsAffected()
 			if err != nil {
 				return err
 			}
+			inserted += n
 		}
-
 		return nil
 	})
+	if err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
 }
 
-// AddIndexedL2Block inserts the indexed block i.e. the L2 block containing all
-// scanned Withdrawals into the known withdrawals database.
-// NOTE: the block hash MUST be unique
-func (d *Database) AddIndexedL2Block(block *IndexedL2Block) error {
-	const insertBlockStatement = `
-	INSERT INTO l2_blocks
-		(hash, parent_hash, number, timestamp)
-	VALUES
-		($1, $2, $3, $4)
-	`
+// RefreshL1TokenMetadata upserts the given L1 tokens keyed by address in a
+// single statement and returns the addresses, in ascending order, of the tokens
+// that were added or whose metadata actually changed. Tokens whose metadata is
+// unchanged are left untouched.
+func (d *Database) RefreshL1TokenMetadata(ctx context.Context, updates map[string]*Token) ([]string, error) {
+	return d.refreshTokenMetadata(ctx, "l1_tokens", updates)
+}
 
-	const insertWithdrawalStatement = `
-	INSERT INTO withdrawals
+// RefreshL2TokenMetadata is the L2 equivalent of RefreshL1TokenMetadata.
+func (d *Database) RefreshL2TokenMetadata(ctx context.Context, updates map[string]*Token) ([]string, error) {
+	return d.refreshTokenMetadata(ctx, "l2_tokens", updates)
+}
+
+func (d *Database) refreshTokenMetadata(ctx context.Context, table string, updates map[string]*Token) ([]string, error) {
+	const upsertTokensStatement = `
+	INSERT INTO %[1]s
+		(address, name, symbol, decimals)
+	SELECT * FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $4::INTEGER[])
+	ON CONFLICT (address) DO UPDATE SET
+		name = EXCLUDED.name, symbol = EXCLUDED.symbol, decimals = EXCLUDED.decimals
+	WHERE (%[1]s.name, %[1]s.symbol, %[1]s.decimals)
+		IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.symbol, EXCLUDED.decimals)
+	RETURNING address;
+	`
+
+	if d.dialect != dialectPostgres {
+		return nil, ErrUnsupportedDialect
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	defer d.tokenCache(table).purge()
+
+	updates = normalizeTokenAddresses(updates)
+	addresses := make([]string, 0, len(updates))
+	for address := range updates {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	names := make([]string, len(addresses))
+	symbols := make([]string, len(addresses))
+	decimals := make([]int64, len(addresses))
+	for i, address := range addresses {
+		names[i] = updates[address].Name
+		symbols[i] = updates[address].Symbol
+		decimals[i] = int64(updates[address].Decimals)
+	}
+
+	var changed []string
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		changed = nil
+
+		rows, err := tx.QueryContext(
+			ctx,
+			fmt.Sprintf(upsertTokensStatement, table),
+			pq.Array(addresses),
+			pq.Array(names),
+			pq.Array(symbols),
+			pq.Array(decimals),
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var address string
+			if err := rows.Scan(&address); err != nil {
+				return err
+			}
+			changed = append(changed, address)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// AddIndexedL1Block inserts the indexed block i.e. the L1 block containing all
+// scanned Deposits into the known deposits database, and links the pending
+// Withdrawals it finalized to it, see MarkWithdrawalFinalized.
+// NOTE: the block hash and number MUST be unique. ErrDuplicateBlock is
+// returned if the block is already indexed, and ErrConflictingBlock if
+// another block is indexed at the same number, see ReplaceIndexedL1Block.
+func (d *Database) AddIndexedL1Block(ctx context.Context, block *IndexedL1Block) error {
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		var err error
+		changes, err = d.addIndexedL1Block(ctx, tx, block)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// AddIndexedL1BlockChecked inserts the indexed block like AddIndexedL1Block,
+// after checking in the same transaction that it extends the indexed chain:
+// if a block is indexed at the previous height, its hash must be the parent
+// hash of block, or ErrChainDiscontinuity is returned and nothing is inserted.
+func (d *Database) AddIndexedL1BlockChecked(ctx context.Context, block *IndexedL1Block) error {
+	const selectParentHashStatement = `
+	SELECT hash FROM l1_blocks WHERE number = $1 AND reorged_at IS NULL;
+	`
+
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		changes = nil
+
+		if block.Number > 0 {
+			var parentHash string
+			err := tx.QueryRowContext(ctx, selectParentHashStatement, block.Number-1).Scan(&parentHash)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+			case err != nil:
+				return err
+			case common.HexToHash(parentHash) != block.ParentHash:
+				return fmt.Errorf("%w: block %d has parent %s but block %d is %s",
+					ErrChainDiscontinuity, block.Number, block.ParentHash, block.Number-1, parentHash)
+			}
+		}
+
+		var err error
+		changes, err = d.addIndexedL1Block(ctx, tx, block)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// addIndexedL1Block inserts the indexed block within tx and returns the status
+// changes of the withdrawals it finalized, to be reported once tx commits.
+func (d *Database) addIndexedL1Block(ctx context.Context, tx *sql.Tx, block *IndexedL1Block) ([]WithdrawalStatusChange, error) {
+	// A block rolled back by a soft delete is restored rather than inserted
+	// again, should it become canonical again. Its tombstoned deposits are
+	// kept as they are, and block.Deposits inserted afresh.
+	const insertBlockStatement = `
+	INSERT INTO l1_blocks
+		(hash, parent_hash, number, timestamp)
+	VALUES
+		($1, $2, $3, $4)
+	ON CONFLICT (hash)
+		DO UPDATE SET reorged_at = NULL WHERE l1_blocks.reorged_at IS NOT NULL
+	`
+
+	result, err := tx.ExecContext(
+		ctx,
+		insertBlockStatement,
+		block.Hash.String(),
+		block.ParentHash.String(),
+		block.Number,
+		block.Timestamp,
+	)
+	// Conflicts on the hash are handled above, so a violation can only be
+	// on the number.
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: %s at number %d", ErrConflictingBlock, block.Hash, block.Number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if inserted == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateBlock, block.Hash)
+	}
+
+	for start := 0; start < len(block.Deposits); start += maxDepositsPerInsert {
+		end := start + maxDepositsPerInsert
+		if end > len(block.Deposits) {
+			end = len(block.Deposits)
+		}
+		err = d.insertDeposits(ctx, tx, block.Hash, block.Deposits[start:end])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Sum the deposits of each address and token first so that a block
+	// with many deposits from the same address only updates its balance
+	// once.
+	type balanceKey struct {
+		address common.Address
+		l1Token common.Address
+	}
+	var keys []balanceKey
+	deltas := make(map[balanceKey]*big.Int)
+	for _, deposit := range block.Deposits {
+		key := balanceKey{deposit.FromAddress, deposit.L1Token}
+		if _, ok := deltas[key]; !ok {
+			keys = append(keys, key)
+			deltas[key] = new(big.Int)
+		}
+		deltas[key].Add(deltas[key], deposit.Amount)
+	}
+
+	for _, key := range keys {
+		err = updateBridgedBalance(ctx, tx, key.address, key.l1Token, deltas[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []WithdrawalStatusChange
+	for _, withdrawal := range block.Withdrawals {
+		finalized, err := finalizeWithdrawals(ctx, tx, withdrawal.TxHash, block.Hash)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, finalized...)
+	}
+
+	return changes, nil
+}
+
+// AddIndexedL2Block inserts the indexed block i.e. the L2 block containing all
+// scanned Withdrawals into the known withdrawals database.
+// NOTE: the block hash and number MUST be unique. ErrDuplicateBlock is
+// returned if the block is already indexed, and ErrConflictingBlock if
+// another block is indexed at the same number, see DeleteL2BlocksFrom.
+// Finalizations recorded as pending for the transactions of its withdrawals
+// are applied, and reported once the block has committed, see
+// MarkWithdrawalFinalized.
+func (d *Database) AddIndexedL2Block(ctx context.Context, block *IndexedL2Block) error {
+	const insertBlockStatement = `
+	INSERT INTO l2_blocks
+		(hash, parent_hash, number, timestamp)
+	VALUES
+		($1, $2, $3, $4)
+	ON CONFLICT (hash) DO NOTHING
+	`
+
+	const insertWithdrawalStatement = `
+	INSERT INTO withdrawals
 		(guid, from_address, to_address, l1_token, l2_token, amount, tx_hash, log_index, l2_block_hash, data)
 	VALUES
 		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
-	return txn(d.db, func(tx *sql.Tx) error {
-		_, err := tx.Exec(
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		changes = nil
+
+		result, err := tx.ExecContext(
+			ctx,
 			insertBlockStatement,
 			block.Hash.String(),
 			block.ParentHash.String(),
 			block.Number,
 			block.Timestamp,
 		)
+		// Conflicts on the hash are handled above, so a violation can only
+		// be on the number.
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s at number %d", ErrConflictingBlock, block.Hash, block.Number)
+		}
+		if err != nil {
+			return err
+		}
+		inserted, err := result.RowsAffected()
 		if err != nil {
 			return err
 		}
+		if inserted == 0 {
+			return fmt.Errorf("%w: %s", ErrDuplicateBlock, block.Hash)
+		}
 
 		if len(block.Withdrawals) == 0 {
 			return nil
 		}
 
 		for _, withdrawal := range block.Withdrawals {
-			_, err = tx.Exec(
+			_, err = tx.ExecContext(
+				ctx,
 				insertWithdrawalStatement,
-				NewGUID(),
-				withdrawal.FromAddress.String(),
-				withdrawal.ToAddress.String(),
-				withdrawal.L1Token.String(),
-				withdrawal.L2Token.String(),
+				d.newGUID(),
+				addressString(withdrawal.FromAddress),
+				addressString(withdrawal.ToAddress),
+				addressString(withdrawal.L1Token),
+				addressString(withdrawal.L2Token),
 				withdrawal.Amount.String(),
 				withdrawal.TxHash.String(),
 				withdrawal.LogIndex,
@@ -319,365 +1211,1834 @@ func (d *Database) AddIndexedL2Block(block *IndexedL2Block) error {
 			if err != nil {
 				return err
 			}
+
+			amount := new(big.Int).Neg(withdrawal.Amount)
+			err = updateBridgedBalance(ctx, tx, withdrawal.FromAddress, withdrawal.L1Token, amount)
+			if err != nil {
+				return err
+			}
+		}
+
+		applied := make(map[common.Hash]bool)
+		for _, withdrawal := range block.Withdrawals {
+			if applied[withdrawal.TxHash] {
+				continue
+			}
+			applied[withdrawal.TxHash] = true
+
+			finalized, err := applyPendingFinalization(ctx, tx, withdrawal.TxHash)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, finalized...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// maxDepositsPerInsert caps the rows inserted by a single statement so that
+// its parameters stay well below the 65535 Postgres allows.
+const maxDepositsPerInsert = 1000
+
+// insertDeposits inserts the deposits of the given L1 block with a single
+// multi-row statement.
+func (d *Database) insertDeposits(ctx context.Context, tx *sql.Tx, blockHash common.Hash, deposits []Deposit) error {
+	const insertDepositsStatement = `
+	INSERT INTO deposits
+		(guid, from_address, to_address, l1_token, l2_token, amount, tx_hash, log_index, l1_block_hash, data, source_address)
+	VALUES
+	`
+	const columns = 11
+
+	var statement strings.Builder
+	statement.WriteString(insertDepositsStatement)
+	args := make([]interface{}, 0, len(deposits)*columns)
+	for i, deposit := range deposits {
+		if i > 0 {
+			statement.WriteString(",")
+		}
+		statement.WriteString("\n\t\t(")
+		for j := 1; j <= columns; j++ {
+			if j > 1 {
+				statement.WriteString(", ")
+			}
+			fmt.Fprintf(&statement, "$%d", i*columns+j)
+		}
+		statement.WriteString(")")
+
+		args = append(args,
+			d.newGUID(),
+			addressString(deposit.FromAddress),
+			addressString(deposit.ToAddress),
+			addressString(deposit.L1Token),
+			addressString(deposit.L2Token),
+			deposit.Amount.String(),
+			deposit.TxHash.String(),
+			deposit.LogIndex,
+			blockHash.String(),
+			deposit.Data,
+			addressString(deposit.SourceAddress),
+		)
+	}
+
+	_, err := tx.ExecContext(ctx, statement.String(), args...)
+	return err
+}
+
+// updateBridgedBalance adds delta to the net amount the given address has
+// bridged for the given L1 token.
+func updateBridgedBalance(ctx context.Context, tx *sql.Tx, address, l1Token common.Address, delta *big.Int) error {
+	const upsertBridgedBalanceStatement = `
+	INSERT INTO bridged_balances
+		(address, token, net_amount)
+	VALUES
+		($1, $2, $3)
+	ON CONFLICT (address, token)
+		DO UPDATE SET net_amount = bridged_balances.net_amount + EXCLUDED.net_amount;
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		upsertBridgedBalanceStatement,
+		addressString(address),
+		addressString(l1Token),
+		delta.String(),
+	)
+	return err
+}
+
+// GetBridgedBalance returns the net amount of the given L1 token the address
+// has bridged, i.e. its deposits minus its withdrawals. The balance is
+// maintained as deposits and withdrawals are indexed, so the lookup does not
+// need to sum the address's history.
+func (d *Database) GetBridgedBalance(ctx context.Context, address, l1Token common.Address) (*big.Int, error) {
+	const selectBridgedBalanceStatement = `
+	SELECT net_amount FROM bridged_balances WHERE address = $1 AND token = $2;
+	`
+
+	balance := new(big.Int)
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectBridgedBalanceStatement, addressString(address), addressString(l1Token))
+
+		var netAmount string
+		err := row.Scan(&netAmount)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 
+		if _, ok := balance.SetString(netAmount, 10); !ok {
+			return fmt.Errorf("unable to parse bridged balance %q", netAmount)
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
 }
 
 // GetDepositsByAddress returns the list of Deposits indexed for the given
-// address paginated by the given params.
-func (d *Database) GetDepositsByAddress(address common.Address, page PaginationParam) (*PaginatedDeposits, error) {
+// address paginated by the given params. See GetDeposits.
+func (d *Database) GetDepositsByAddress(ctx context.Context, address common.Address, page PaginationParam) (*PaginatedDeposits, error) {
+	return d.GetDeposits(ctx, ActivityFilter{Address: &address}, page)
+}
+
+// GetDepositsByAddressSince returns the list of Deposits indexed for the given
+// address in L1 blocks numbered above afterBlock, paginated by the given
+// params in ascending block order, so that a poller can fetch only the
+// deposits newer than the last one it has seen and keep the number of its
+// block as a high-water mark. See GetDeposits.
+func (d *Database) GetDepositsByAddressSince(ctx context.Context, address common.Address, afterBlock uint64, page PaginationParam) (*PaginatedDeposits, error) {
+	if afterBlock == math.MaxUint64 {
+		return &PaginatedDeposits{Page: newPageInfo(page)}, nil
+	}
+
+	return d.GetDeposits(ctx, ActivityFilter{
+		Address:   &address,
+		FromBlock: afterBlock + 1,
+		SortBy:    SortByBlockNumber,
+		SortDir:   SortAsc,
+	}, page)
+}
+
+// GetDepositsByToAddress returns the list of Deposits received by the given
+// address paginated by the given params. See GetDeposits.
+func (d *Database) GetDepositsByToAddress(ctx context.Context, address common.Address, page PaginationParam) (*PaginatedDeposits, error) {
+	return d.GetDeposits(ctx, ActivityFilter{ToAddress: &address}, page)
+}
+
+// GetDepositsInvolvingAddress returns the list of Deposits made or received
+// by the given address paginated by the given params. See GetDeposits.
+func (d *Database) GetDepositsInvolvingAddress(ctx context.Context, address common.Address, page PaginationParam) (*PaginatedDeposits, error) {
+	return d.GetDeposits(ctx, ActivityFilter{AnyAddress: &address}, page)
+}
+
+// GetDepositsByToken returns the list of Deposits of the given L1 token
+// paginated by the given params. See GetDeposits.
+func (d *Database) GetDepositsByToken(ctx context.Context, l1Token common.Address, page PaginationParam) (*PaginatedDeposits, error) {
+	return d.GetDeposits(ctx, ActivityFilter{Token: &l1Token}, page)
+}
+
+// GetDepositsByBlockRange returns the list of Deposits made in the L1 blocks
+// numbered from to to, inclusive, paginated by the given params. It fails
+// with ErrInvalidBlockRange if from is greater than to. See GetDeposits.
+func (d *Database) GetDepositsByBlockRange(ctx context.Context, from, to uint64, page PaginationParam) (*PaginatedDeposits, error) {
+	if from > to {
+		return nil, fmt.Errorf("%w: from %d is greater than to %d", ErrInvalidBlockRange, from, to)
+	}
+	// A zero ToBlock does not bound the filter, but the genesis block holds
+	// no deposits anyway.
+	if to == 0 {
+		return &PaginatedDeposits{Page: newPageInfo(page)}, nil
+	}
+
+	return d.GetDeposits(ctx, ActivityFilter{FromBlock: from, ToBlock: to}, page)
+}
+
+// GetLatestDeposits returns the most recent deposits of all addresses, newest
+// first, e.g. for an activity feed. See GetDeposits for the deposits that are
+// excluded and for the bounds of limit.
+func (d *Database) GetLatestDeposits(ctx context.Context, limit uint64) ([]DepositJSON, error) {
+	deposits, err := d.GetDeposits(ctx, ActivityFilter{
+		SortBy:  SortByBlockNumber,
+		SortDir: SortDesc,
+	}, PaginationParam{
+		Limit:     limit,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deposits.Deposits, nil
+}
+
+// GetDeposits returns the list of Deposits matching the given filter paginated
+// by the given params. Deposits invalidated by a reorg are excluded unless
+// filter.IncludeReorged is set, and deposits of tokens that have not been
+// verified are excluded unless filter.IncludeUnverified is set. Each deposit's
+// ConfirmationStatus is derived from the highest indexed L1 block.
+//
+// Deposits are ordered by filter.SortBy in filter.SortDir, by ascending
+// timestamp by default, and then by block number and log index. When
+// page.Cursor is set, the page starts right after the cursor, or at it if
+// page.InclusiveCursor is set, and page.Offset is ignored. Cursors are only
+// supported in block order, by timestamp or block number, in which the
+// NextCursor of the returned page is set whenever the page is full. In
+// descending order the cursor loads the next older page, which suits
+// newest-first infinite scrolling. Cursors are recommended over page.Offset,
+// which gets slow for deep pages.
+//
+// When page.SkipTotal is set, the total is not counted and HasMore reports
+// whether rows follow the page instead. Deposits are counted per token into
+// TokenCounts when page.IncludeTokenCounts is set. USD values are attached if
+// a PriceProvider is configured.
+func (d *Database) GetDeposits(ctx context.Context, filter ActivityFilter, page PaginationParam) (*PaginatedDeposits, error) {
+	const selectHeadStatement = `
+	SELECT COALESCE(MAX(number), 0) FROM l1_blocks WHERE reorged_at IS NULL;
+	`
+	statement, args, err := d.depositsQuery(filter, &page)
+	if err != nil {
+		return nil, err
+	}
+	blockOrder, _ := isBlockOrder(filter.SortBy, filter.SortDir)
+
+	var deposits []DepositJSON
+	db := d.reader()
+	err = d.txnOn(ctx, db, func(tx *sql.Tx) error {
+		deposits = nil
+
+		var head uint64
+		if err := d.queryRowPrepared(ctx, db, tx, selectHeadStatement).Scan(&head); err != nil {
+			return err
+		}
+
+		rows, err := d.queryPrepared(ctx, db, tx, statement, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			deposit, err := scanDepositRow(rows)
+			if err != nil {
+				return err
+			}
+			deposit.ConfirmationStatus = d.confirmations.Status(deposit.BlockNumber, head)
+			deposits = append(deposits, deposit)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := newPageInfo(page)
+	if page.SkipTotal {
+		info.HasMore = uint64(len(deposits)) > page.Limit
+		if info.HasMore {
+			deposits = deposits[:page.Limit]
+		}
+	} else {
+		info.Total, err = d.countDeposits(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if page.IncludeTokenCounts {
+		info.TokenCounts, err = d.countDepositsByToken(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d.enrichDeposits(ctx, deposits)
+
+	if blockOrder && len(deposits) > 0 && uint64(len(deposits)) == page.Limit {
+		last := deposits[len(deposits)-1]
+		info.NextCursor = Cursor{
+			BlockNumber: last.BlockNumber,
+			LogIndex:    last.LogIndex,
+			GUID:        last.GUID,
+		}.Encode()
+	}
+	info.ByteSize, err = pageByteSize(deposits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedDeposits{
+		info,
+		deposits,
+	}, nil
+}
+
+// depositsQuery returns the statement listing the deposits matching filter
+// within page, along with its arguments. The bounds of page are validated and
+// adjusted first, see PaginationParam.Validate.
+func (d *Database) depositsQuery(filter ActivityFilter, page *PaginationParam) (string, []interface{}, error) {
 	const selectDepositsStatement = `
 	SELECT
 		deposits.guid, deposits.from_address, deposits.to_address,
-		deposits.amount, deposits.tx_hash, deposits.data,
+		deposits.amount, deposits.tx_hash,
+		CASE WHEN $5 THEN NULL ELSE deposits.data END, octet_length(deposits.data),
 		deposits.l1_token, deposits.l2_token,
-		l1_tokens.name, l1_tokens.symbol, l1_tokens.decimals,
-		l1_blocks.number, l1_blocks.timestamp
+		COALESCE(l1_tokens.name, ''), COALESCE(l1_tokens.symbol, ''), COALESCE(l1_tokens.decimals, 0),
+		deposits.log_index, l1_blocks.number, l1_blocks.timestamp,
+		deposits.source_address
 	FROM deposits
 		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
-		INNER JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
-	WHERE deposits.from_address = $1 ORDER BY l1_blocks.timestamp LIMIT $2 OFFSET $3;
+		LEFT JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
+	WHERE %s AND ($3 OR deposits.reorged_at IS NULL)
+		AND ($4 OR COALESCE(l1_tokens.verified, false))
+		AND ($6 = '' OR deposits.source_address = $6)
+	ORDER BY %s
+	LIMIT $1 OFFSET $2;
+	`
+	if err := d.validatePage(page); err != nil {
+		return "", nil, err
+	}
+
+	order, err := orderBy(depositTables, filter.SortBy, filter.SortDir)
+	if err != nil {
+		return "", nil, err
+	}
+	blockOrder, descending := isBlockOrder(filter.SortBy, filter.SortDir)
+	if page.Cursor != "" && !blockOrder {
+		return "", nil, fmt.Errorf("%w: cursors require block order", ErrInvalidSort)
+	}
+
+	offset := page.Offset
+	if page.Cursor != "" {
+		offset = 0
+	}
+
+	conditions, args := filter.conditions(depositTables, []interface{}{
+		pageLimit(*page),
+		offset,
+		filter.IncludeReorged,
+		filter.IncludeUnverified,
+		page.OmitData,
+		filter.sourceAddress(),
+	})
+	if page.Cursor != "" {
+		cursor, err := ParseCursor(page.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions, args = cursor.condition(depositTables, page.InclusiveCursor, descending, conditions, args)
+	}
+
+	return fmt.Sprintf(selectDepositsStatement, conditions, order), args, nil
+}
+
+// rowScanner is implemented by both *sql.Rows and *sql.Row.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDepositRow scans a deposit selected with the columns of depositsQuery,
+// in the same order.
+func scanDepositRow(row rowScanner) (DepositJSON, error) {
+	var deposit DepositJSON
+	var l1Token Token
+	var sourceAddress sql.NullString
+	if err := row.Scan(
+		&deposit.GUID, &deposit.FromAddress, &deposit.ToAddress,
+		&deposit.Amount, &deposit.TxHash,
+		&deposit.Data, &deposit.DataLength,
+		&l1Token.Address, &deposit.L2Token,
+		&l1Token.Name, &l1Token.Symbol, &l1Token.Decimals,
+		&deposit.LogIndex, &deposit.BlockNumber, &deposit.BlockTimestamp,
+		&sourceAddress,
+	); err != nil {
+		return DepositJSON{}, err
+	}
+	deposit.L1Token = &l1Token
+	deposit.SourceAddress = sourceAddress.String
+	return deposit, nil
+}
+
+// GetDepositByTxHash returns the deposit emitted at logIndex by the given L1
+// transaction, or ErrDepositNotFound if it is not indexed. Deposits
+// invalidated by a reorg are ignored.
+func (d *Database) GetDepositByTxHash(ctx context.Context, hash common.Hash, logIndex uint64) (*DepositJSON, error) {
+	return d.getDeposit(ctx, "deposits.tx_hash = $1 AND deposits.log_index = $2", hash.String(), logIndex)
+}
+
+// GetDepositByGUID returns the deposit with the given guid, or
+// ErrDepositNotFound if it is not indexed. The guid is validated first, so
+// that malformed input fails with ErrInvalidGUID rather than a database error.
+// Deposits invalidated by a reorg are ignored.
+func (d *Database) GetDepositByGUID(ctx context.Context, guid string) (*DepositJSON, error) {
+	guid, err := ParseGUID(guid)
+	if err != nil {
+		return nil, err
+	}
+	return d.getDeposit(ctx, "deposits.guid = $1", guid)
+}
+
+// getDeposit returns the single deposit matching condition, along with its
+// confirmation status. It backs GetDepositByTxHash and GetDepositByGUID.
+func (d *Database) getDeposit(ctx context.Context, condition string, args ...interface{}) (*DepositJSON, error) {
+	const selectDepositStatement = `
+	SELECT
+		deposits.guid, deposits.from_address, deposits.to_address,
+		deposits.amount, deposits.tx_hash,
+		deposits.data, octet_length(deposits.data),
+		deposits.l1_token, deposits.l2_token,
+		COALESCE(l1_tokens.name, ''), COALESCE(l1_tokens.symbol, ''), COALESCE(l1_tokens.decimals, 0),
+		deposits.log_index, l1_blocks.number, l1_blocks.timestamp,
+		deposits.source_address
+	FROM deposits
+		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+		LEFT JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
+	WHERE %s
+		AND deposits.reorged_at IS NULL;
+	`
+	const selectHeadStatement = `
+	SELECT COALESCE(MAX(number), 0) FROM l1_blocks WHERE reorged_at IS NULL;
+	`
+
+	statement := fmt.Sprintf(selectDepositStatement, condition)
+	deposit := new(DepositJSON)
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		var head uint64
+		if err := tx.QueryRowContext(ctx, selectHeadStatement).Scan(&head); err != nil {
+			return err
+		}
+
+		var err error
+		*deposit, err = scanDepositRow(tx.QueryRowContext(ctx, statement, args...))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDepositNotFound
+		}
+		if err != nil {
+			return err
+		}
+		deposit.ConfirmationStatus = d.confirmations.Status(deposit.BlockNumber, head)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deposit, nil
+}
+
+// CountDeposits returns the number of deposits matching the given filter
+// without fetching them, i.e. the Total GetDeposits reports for the same
+// filter. Like the listings, deposits invalidated by a reorg and deposits of
+// unverified tokens are not counted unless filter.IncludeReorged and
+// filter.IncludeUnverified are set.
+func (d *Database) CountDeposits(ctx context.Context, filter ActivityFilter) (uint64, error) {
+	return d.countDeposits(ctx, filter)
+}
+
+// countDeposits returns the number of deposits matching the given filter. It
+// backs both CountDeposits and the totals of GetDeposits.
+func (d *Database) countDeposits(ctx context.Context, filter ActivityFilter) (uint64, error) {
+	const selectDepositCountStatement = `
+	SELECT
+		count(*)
+	FROM deposits
+		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+		LEFT JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
+	WHERE %s AND ($1 OR deposits.reorged_at IS NULL)
+		AND ($2 OR COALESCE(l1_tokens.verified, false))
+		AND ($3 = '' OR deposits.source_address = $3);
+	`
+
+	conditions, args := filter.conditions(depositTables, []interface{}{
+		filter.IncludeReorged,
+		filter.IncludeUnverified,
+		filter.sourceAddress(),
+	})
+
+	var count uint64
+	db := d.reader()
+	err := d.txnOn(ctx, db, func(tx *sql.Tx) error {
+		row := d.queryRowPrepared(
+			ctx, db, tx,
+			fmt.Sprintf(selectDepositCountStatement, conditions),
+			args...,
+		)
+		return row.Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// countDepositsByToken returns the number of deposits matching the given
+// filter, keyed by L1 token address.
+func (d *Database) countDepositsByToken(ctx context.Context, filter ActivityFilter) (map[string]uint64, error) {
+	const selectDepositCountsStatement = `
+	SELECT
+		deposits.l1_token, count(*)
+	FROM deposits
+		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+		LEFT JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
+	WHERE %s AND ($1 OR deposits.reorged_at IS NULL)
+		AND ($2 OR COALESCE(l1_tokens.verified, false))
+		AND ($3 = '' OR deposits.source_address = $3)
+	GROUP BY deposits.l1_token;
+	`
+
+	conditions, args := filter.conditions(depositTables, []interface{}{
+		filter.IncludeReorged,
+		filter.IncludeUnverified,
+		filter.sourceAddress(),
+	})
+
+	counts := make(map[string]uint64)
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(
+			ctx,
+			fmt.Sprintf(selectDepositCountsStatement, conditions),
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var token string
+			var count uint64
+			if err := rows.Scan(&token, &count); err != nil {
+				return err
+			}
+			counts[token] = count
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetDepositData returns the data of the deposit with the given guid, or
+// ErrDepositNotFound if no such deposit is indexed. Listings omit the data
+// when PaginationParam.OmitData is set, so it must be fetched through this method
+// instead. The guid is validated first, see GetDepositByGUID. Deposits
+// invalidated by a reorg are ignored.
+func (d *Database) GetDepositData(ctx context.Context, guid string) ([]byte, error) {
+	const selectDepositDataStatement = `
+	SELECT data FROM deposits WHERE guid = $1 AND reorged_at IS NULL;
+	`
+
+	guid, err := ParseGUID(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectDepositDataStatement, guid)
+		err := row.Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDepositNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// scanWithdrawalRow scans a withdrawal selected with the columns of
+// withdrawalsQuery, in the same order.
+func scanWithdrawalRow(row rowScanner) (WithdrawalJSON, error) {
+	var withdrawal WithdrawalJSON
+	var l2Token Token
+	var relatedDepositGUID sql.NullString
+	if err := row.Scan(
+		&withdrawal.GUID, &withdrawal.FromAddress, &withdrawal.ToAddress,
+		&withdrawal.Amount, &withdrawal.TxHash,
+		&withdrawal.Data, &withdrawal.DataLength,
+		&withdrawal.L1Token, &l2Token.Address,
+		&l2Token.Name, &l2Token.Symbol, &l2Token.Decimals,
+		&withdrawal.LogIndex, &withdrawal.L2BlockNumber, &withdrawal.L2BlockTimestamp,
+		&relatedDepositGUID,
+	); err != nil {
+		return WithdrawalJSON{}, err
+	}
+	withdrawal.L2Token = &l2Token
+	withdrawal.RelatedDepositGUID = relatedDepositGUID.String
+	return withdrawal, nil
+}
+
+// GetWithdrawalStatus returns the finalization status corresponding to the
+// given withdrawal transaction hash. A pending withdrawal is returned without
+// L1 block. It returns ErrWithdrawalNotFound if no withdrawal matches the
+// hash. Finalized statuses are cached, so repeatedly polling a completed
+// withdrawal does not hit the database.
+func (d *Database) GetWithdrawalStatus(ctx context.Context, hash common.Hash) (*WithdrawalJSON, error) {
+	if withdrawal, ok := d.finalizedWithdrawals.get(hash); ok {
+		return withdrawal, nil
+	}
+	generation := d.finalizedWithdrawals.currentGeneration()
+
+	withdrawal, err := d.getWithdrawal(ctx, "withdrawals.tx_hash = $1", hash.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if withdrawal.L1BlockNumber != nil {
+		d.finalizedWithdrawals.add(hash, withdrawal, generation)
+	}
+
+	return withdrawal, nil
+}
+
+// GetWithdrawalByGUID returns the withdrawal with the given guid, along with
+// the L1 block finalizing it if any, or ErrWithdrawalNotFound if it is not
+// indexed. The guid is validated first, so that malformed input fails with
+// ErrInvalidGUID rather than a database error.
+func (d *Database) GetWithdrawalByGUID(ctx context.Context, guid string) (*WithdrawalJSON, error) {
+	guid, err := ParseGUID(guid)
+	if err != nil {
+		return nil, err
+	}
+	return d.getWithdrawal(ctx, "withdrawals.guid = $1", guid)
+}
+
+// getWithdrawal returns the single withdrawal matching condition. It backs
+// GetWithdrawalStatus and GetWithdrawalByGUID.
+func (d *Database) getWithdrawal(ctx context.Context, condition string, args ...interface{}) (*WithdrawalJSON, error) {
+	const selectWithdrawalStatement = `
+	SELECT
+	    withdrawals.guid, withdrawals.from_address, withdrawals.to_address,
+		withdrawals.amount, withdrawals.tx_hash,
+		withdrawals.data, octet_length(withdrawals.data),
+		withdrawals.l1_token, withdrawals.l2_token,
+		COALESCE(l2_tokens.name, ''), COALESCE(l2_tokens.symbol, ''), COALESCE(l2_tokens.decimals, 0),
+		withdrawals.log_index,
+		l1_blocks.number, l1_blocks.timestamp,
+		l2_blocks.number, l2_blocks.timestamp
+	FROM withdrawals
+		LEFT JOIN l1_blocks ON withdrawals.l1_block_hash=l1_blocks.hash
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+		LEFT JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
+	WHERE %s;
+	`
+
+	statement := fmt.Sprintf(selectWithdrawalStatement, condition)
+	withdrawal := new(WithdrawalJSON)
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		*withdrawal = WithdrawalJSON{}
+
+		row := tx.QueryRowContext(ctx, statement, args...)
+		if row.Err() != nil {
+			return row.Err()
+		}
+
+		var l2Token Token
+		var l1BlockNumber sql.NullInt64
+		var l1BlockTimestamp sql.NullString
+		err := row.Scan(
+			&withdrawal.GUID, &withdrawal.FromAddress, &withdrawal.ToAddress,
+			&withdrawal.Amount, &withdrawal.TxHash,
+			&withdrawal.Data, &withdrawal.DataLength,
+			&withdrawal.L1Token, &l2Token.Address,
+			&l2Token.Name, &l2Token.Symbol, &l2Token.Decimals,
+			&withdrawal.LogIndex,
+			&l1BlockNumber, &l1BlockTimestamp,
+			&withdrawal.L2BlockNumber, &withdrawal.L2BlockTimestamp,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWithdrawalNotFound
+		}
+		if err != nil {
+			return err
+		}
+		withdrawal.L2Token = &l2Token
+		if l1BlockNumber.Valid {
+			number := uint64(l1BlockNumber.Int64)
+			withdrawal.L1BlockNumber = &number
+			withdrawal.L1BlockTimestamp = &l1BlockTimestamp.String
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return withdrawal, nil
+}
+
+// GetWithdrawalProofData returns the fields needed to prove the withdrawal
+// made by the given L2 transaction, or ErrWithdrawalNotFound if it is not
+// indexed. Only the withdrawal and its L2 block are read, through a cached
+// statement, so the prover may call it repeatedly.
+func (d *Database) GetWithdrawalProofData(ctx context.Context, hash common.Hash) (*WithdrawalProofData, error) {
+	const selectWithdrawalProofDataStatement = `
+	SELECT withdrawals.guid, l2_blocks.number, withdrawals.data
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+	WHERE withdrawals.tx_hash = $1
+	ORDER BY withdrawals.log_index
+	LIMIT 1;
+	`
+
+	proof := &WithdrawalProofData{TxHash: hash}
+	db := d.reader()
+	err := d.txnOn(ctx, db, func(tx *sql.Tx) error {
+		row := d.queryRowPrepared(ctx, db, tx, selectWithdrawalProofDataStatement, hash.String())
+		err := row.Scan(&proof.GUID, &proof.L2BlockNumber, &proof.Data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWithdrawalNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// GetWithdrawalsByAddress returns the list of Withdrawals indexed for the given
+// address paginated by the given params. See GetWithdrawals.
+func (d *Database) GetWithdrawalsByAddress(ctx context.Context, address common.Address, page PaginationParam) (*PaginatedWithdrawals, error) {
+	return d.GetWithdrawals(ctx, ActivityFilter{Address: &address}, page)
+}
+
+// GetWithdrawalsByToAddress returns the list of Withdrawals received by the
+// given address paginated by the given params. See GetWithdrawals.
+func (d *Database) GetWithdrawalsByToAddress(ctx context.Context, address common.Address, page PaginationParam) (*PaginatedWithdrawals, error) {
+	return d.GetWithdrawals(ctx, ActivityFilter{ToAddress: &address}, page)
+}
+
+// GetWithdrawalsInvolvingAddress returns the list of Withdrawals made or
+// received by the given address paginated by the given params. See
+// GetWithdrawals.
+func (d *Database) GetWithdrawalsInvolvingAddress(ctx context.Context, address common.Address, page PaginationParam) (*PaginatedWithdrawals, error) {
+	return d.GetWithdrawals(ctx, ActivityFilter{AnyAddress: &address}, page)
+}
+
+// GetWithdrawalsByBlockRange returns the list of Withdrawals made in the L2
+// blocks numbered from to to, inclusive, paginated by the given params. It
+// fails with ErrInvalidBlockRange if from is greater than to. See
+// GetWithdrawals.
+func (d *Database) GetWithdrawalsByBlockRange(ctx context.Context, from, to uint64, page PaginationParam) (*PaginatedWithdrawals, error) {
+	if from > to {
+		return nil, fmt.Errorf("%w: from %d is greater than to %d", ErrInvalidBlockRange, from, to)
+	}
+	// A zero ToBlock does not bound the filter, but the genesis block holds
+	// no withdrawals anyway.
+	if to == 0 {
+		return &PaginatedWithdrawals{Page: newPageInfo(page)}, nil
+	}
+
+	return d.GetWithdrawals(ctx, ActivityFilter{FromBlock: from, ToBlock: to}, page)
+}
+
+// GetLatestWithdrawals returns the most recent withdrawals of all addresses,
+// newest first. See GetLatestDeposits.
+func (d *Database) GetLatestWithdrawals(ctx context.Context, limit uint64) ([]WithdrawalJSON, error) {
+	withdrawals, err := d.GetWithdrawals(ctx, ActivityFilter{
+		SortBy:  SortByBlockNumber,
+		SortDir: SortDesc,
+	}, PaginationParam{
+		Limit:     limit,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return withdrawals.Withdrawals, nil
+}
+
+// GetWithdrawals returns the list of Withdrawals matching the given filter
+// paginated by the given params. Only withdrawals with filter.WithdrawalStatus
+// are returned when it is set. When page.SkipTotal is set, the total is not
+// counted and HasMore reports whether rows follow the page instead.
+//
+// Withdrawals are ordered and paged by cursor as described for GetDeposits,
+// except that they cannot be sorted by source address. Paging by cursor is
+// recommended over page.Offset, which gets slow for deep pages and skips or
+// repeats rows when withdrawals are indexed between two requests.
+//
+// When page.IncludeRelatedDeposits is set, each withdrawal that completes a
+// round-trip is linked to the deposit that most plausibly funded it. This is
+// a heuristic: the related deposit is the latest deposit of the same L1 token
+// that was sent to the withdrawing address at or before the withdrawal's
+// timestamp. It does not prove that the same funds were bridged back.
+func (d *Database) GetWithdrawals(ctx context.Context, filter ActivityFilter, page PaginationParam) (*PaginatedWithdrawals, error) {
+	statement, args, err := d.withdrawalsQuery(filter, &page)
+	if err != nil {
+		return nil, err
+	}
+	blockOrder, _ := isBlockOrder(filter.SortBy, filter.SortDir)
+
+	var withdrawals []WithdrawalJSON
+	err = d.readTxn(ctx, func(tx *sql.Tx) error {
+		withdrawals = nil
+
+		rows, err := tx.QueryContext(ctx, statement, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			withdrawal, err := scanWithdrawalRow(rows)
+			if err != nil {
+				return err
+			}
+			withdrawals = append(withdrawals, withdrawal)
+		}
+
+		return rows.Err()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := newPageInfo(page)
+	if page.SkipTotal {
+		info.HasMore = uint64(len(withdrawals)) > page.Limit
+		if info.HasMore {
+			withdrawals = withdrawals[:page.Limit]
+		}
+	} else {
+		info.Total, err = d.countWithdrawals(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if blockOrder && len(withdrawals) > 0 && uint64(len(withdrawals)) == page.Limit {
+		last := withdrawals[len(withdrawals)-1]
+		info.NextCursor = Cursor{
+			BlockNumber: last.L2BlockNumber,
+			LogIndex:    last.LogIndex,
+			GUID:        last.GUID,
+		}.Encode()
+	}
+	info.ByteSize, err = pageByteSize(withdrawals)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedWithdrawals{
+		info,
+		withdrawals,
+	}, nil
+}
+
+// withdrawalsQuery is the withdrawal equivalent of depositsQuery.
+func (d *Database) withdrawalsQuery(filter ActivityFilter, page *PaginationParam) (string, []interface{}, error) {
+	const selectWithdrawalsStatement = `
+	SELECT
+	    withdrawals.guid, withdrawals.from_address, withdrawals.to_address,
+		withdrawals.amount, withdrawals.tx_hash,
+		CASE WHEN $4 THEN NULL ELSE withdrawals.data END, octet_length(withdrawals.data),
+		withdrawals.l1_token, withdrawals.l2_token,
+		COALESCE(l2_tokens.name, ''), COALESCE(l2_tokens.symbol, ''), COALESCE(l2_tokens.decimals, 0),
+		withdrawals.log_index, l2_blocks.number, l2_blocks.timestamp,
+		(
+			SELECT deposits.guid FROM deposits
+				INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+			WHERE $3 AND deposits.to_address = withdrawals.from_address
+				AND deposits.l1_token = withdrawals.l1_token
+				AND deposits.reorged_at IS NULL
+				AND l1_blocks.timestamp <= l2_blocks.timestamp
+			ORDER BY l1_blocks.timestamp DESC, l1_blocks.number DESC
+			LIMIT 1
+		)
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+		LEFT JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
+	WHERE %s
+		AND (withdrawals.l1_block_hash IS NULL AND $5 OR withdrawals.l1_block_hash IS NOT NULL AND $6)
+	ORDER BY %s
+	LIMIT $1 OFFSET $2;
+	`
+	if err := d.validatePage(page); err != nil {
+		return "", nil, err
+	}
+
+	pending, finalized, err := filter.WithdrawalStatus.matches()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if filter.SortBy == SortBySourceAddress {
+		return "", nil, fmt.Errorf("%w: withdrawals have no source address", ErrInvalidSort)
+	}
+	order, err := orderBy(withdrawalTables, filter.SortBy, filter.SortDir)
+	if err != nil {
+		return "", nil, err
+	}
+	blockOrder, descending := isBlockOrder(filter.SortBy, filter.SortDir)
+	if page.Cursor != "" && !blockOrder {
+		return "", nil, fmt.Errorf("%w: cursors require block order", ErrInvalidSort)
+	}
+
+	offset := page.Offset
+	if page.Cursor != "" {
+		offset = 0
+	}
+
+	conditions, args := filter.conditions(withdrawalTables, []interface{}{
+		pageLimit(*page),
+		offset,
+		page.IncludeRelatedDeposits,
+		page.OmitData,
+		pending,
+		finalized,
+	})
+	if page.Cursor != "" {
+		cursor, err := ParseCursor(page.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions, args = cursor.condition(withdrawalTables, page.InclusiveCursor, descending, conditions, args)
+	}
+
+	return fmt.Sprintf(selectWithdrawalsStatement, conditions, order), args, nil
+}
+
+// CountWithdrawals returns the number of withdrawals matching the given
+// filter without fetching them, i.e. the Total GetWithdrawals reports for the
+// same filter. Like the listings, only withdrawals with
+// filter.WithdrawalStatus are counted when it is set, and an empty filter
+// counts every withdrawal.
+func (d *Database) CountWithdrawals(ctx context.Context, filter ActivityFilter) (uint64, error) {
+	return d.countWithdrawals(ctx, filter)
+}
+
+// countWithdrawals returns the number of withdrawals matching the given
+// filter. It backs both CountWithdrawals and the totals of GetWithdrawals.
+func (d *Database) countWithdrawals(ctx context.Context, filter ActivityFilter) (uint64, error) {
+	const selectWithdrawalCountStatement = `
+	SELECT
+		count(*)
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+		LEFT JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
+	WHERE %s
+		AND (withdrawals.l1_block_hash IS NULL AND $1 OR withdrawals.l1_block_hash IS NOT NULL AND $2);
+	`
+
+	pending, finalized, err := filter.WithdrawalStatus.matches()
+	if err != nil {
+		return 0, err
+	}
+	conditions, args := filter.conditions(withdrawalTables, []interface{}{
+		pending,
+		finalized,
+	})
+
+	var count uint64
+	err = d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(
+			ctx,
+			fmt.Sprintf(selectWithdrawalCountStatement, conditions),
+			args...,
+		)
+		return row.Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetWithdrawalData returns the data of the withdrawal with the given guid,
+// or ErrWithdrawalNotFound if no such withdrawal is indexed. The guid is
+// validated first, see GetWithdrawalByGUID.
+func (d *Database) GetWithdrawalData(ctx context.Context, guid string) ([]byte, error) {
+	const selectWithdrawalDataStatement = `
+	SELECT data FROM withdrawals WHERE guid = $1;
+	`
+
+	guid, err := ParseGUID(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectWithdrawalDataStatement, guid)
+		err := row.Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWithdrawalNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// GetAddressActivityRange returns the earliest and latest block timestamps of
+// the deposits and withdrawals made by the given address. Both are zero if the
+// address has no activity.
+func (d *Database) GetAddressActivityRange(ctx context.Context, address common.Address) (first, last uint64, err error) {
+	const selectActivityRangeStatement = `
+	SELECT
+		COALESCE(MIN(activity.timestamp), 0), COALESCE(MAX(activity.timestamp), 0)
+	FROM (
+		SELECT l1_blocks.timestamp FROM deposits
+			INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+		WHERE deposits.from_address = $1 AND deposits.reorged_at IS NULL
+		UNION ALL
+		SELECT l2_blocks.timestamp FROM withdrawals
+			INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+		WHERE withdrawals.from_address = $1
+	) AS activity;
+	`
+
+	err = d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectActivityRangeStatement, addressString(address))
+		return row.Scan(&first, &last)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return first, last, nil
+}
+
+// GetUniqueDepositorCount returns the number of distinct addresses that made a
+// deposit in an L1 block with a timestamp in [start, end). Deposits invalidated
+// by a reorg are not counted.
+func (d *Database) GetUniqueDepositorCount(ctx context.Context, start, end uint64) (uint64, error) {
+	const selectUniqueDepositorCountStatement = `
+	SELECT
+		COUNT(DISTINCT deposits.from_address)
+	FROM deposits
+		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+	WHERE l1_blocks.timestamp >= $1 AND l1_blocks.timestamp < $2
+		AND deposits.reorged_at IS NULL;
+	`
+
+	var count uint64
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectUniqueDepositorCountStatement, start, end)
+		return row.Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetUniqueWithdrawerCount returns the number of distinct addresses that made a
+// withdrawal in an L2 block with a timestamp in [start, end).
+func (d *Database) GetUniqueWithdrawerCount(ctx context.Context, start, end uint64) (uint64, error) {
+	const selectUniqueWithdrawerCountStatement = `
+	SELECT
+		COUNT(DISTINCT withdrawals.from_address)
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+	WHERE l2_blocks.timestamp >= $1 AND l2_blocks.timestamp < $2;
+	`
+
+	var count uint64
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectUniqueWithdrawerCountStatement, start, end)
+		return row.Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetHighestL1Block returns the highest known L1 block.
+func (d *Database) GetHighestL1Block(ctx context.Context) (*BlockLocator, error) {
+	const selectHighestBlockStatement = `
+	SELECT number, hash FROM l1_blocks WHERE reorged_at IS NULL ORDER BY number DESC LIMIT 1
+	`
+
+	var highestBlock *BlockLocator
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectHighestBlockStatement)
+		if row.Err() != nil {
+			return row.Err()
+		}
+
+		var number uint64
+		var hash string
+		err := row.Scan(&number, &hash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				highestBlock = nil
+				return nil
+			}
+			return err
+		}
+
+		highestBlock = &BlockLocator{
+			Number: number,
+			Hash:   common.HexToHash(hash),
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return highestBlock, nil
+}
+
+// GetHighestL2Block returns the highest known L2 block.
+func (d *Database) GetHighestL2Block(ctx context.Context) (*BlockLocator, error) {
+	const selectHighestBlockStatement = `
+	SELECT number, hash FROM l2_blocks ORDER BY number DESC LIMIT 1
+	`
+
+	var highestBlock *BlockLocator
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, selectHighestBlockStatement)
+		if row.Err() != nil {
+			return row.Err()
+		}
+
+		var number uint64
+		var hash string
+		err := row.Scan(&number, &hash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				highestBlock = nil
+				return nil
+			}
+			return err
+		}
+
+		highestBlock = &BlockLocator{
+			Number: number,
+			Hash:   common.HexToHash(hash),
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return highestBlock, nil
+}
+
+// GetHighestFinalizedL2Block returns the highest L2 block holding a withdrawal
+// that has been finalized on L1, or nil if no withdrawal is finalized yet.
+// Withdrawals are finalized out of order, so lower blocks may still hold
+// pending withdrawals.
+func (d *Database) GetHighestFinalizedL2Block(ctx context.Context) (*BlockLocator, error) {
+	const selectHighestFinalizedBlockStatement = `
+	SELECT l2_blocks.number, l2_blocks.hash
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+	WHERE withdrawals.l1_block_hash IS NOT NULL
+	ORDER BY l2_blocks.number DESC LIMIT 1
+	`
+
+	var highestBlock *BlockLocator
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		highestBlock = nil
+
+		var number uint64
+		var hash string
+		err := tx.QueryRowContext(ctx, selectHighestFinalizedBlockStatement).Scan(&number, &hash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		highestBlock = &BlockLocator{
+			Number: number,
+			Hash:   common.HexToHash(hash),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return highestBlock, nil
+}
+
+// GetIndexedL1BlockByHash returns the L1 block by it's hash. If withEvents is
+// set, the deposits it contains and the withdrawals it finalized are loaded
+// too, each in log order, with deposits invalidated by a reorg left out;
+// callers that only need the header should leave it unset to skip those
+// queries. It returns ErrBlockNotFound if the block is not indexed.
+func (d *Database) GetIndexedL1BlockByHash(ctx context.Context, hash common.Hash, withEvents bool) (*IndexedL1Block, error) {
+	const selectBlockByHashStatement = `
+	SELECT
+		hash, parent_hash, number, timestamp
+	FROM l1_blocks
+	WHERE hash = $1 AND reorged_at IS NULL
+	`
+
+	return d.getIndexedL1Block(ctx, selectBlockByHashStatement, hash.String(), withEvents)
+}
+
+// GetIndexedL1BlockByNumber returns the L1 block with the given number. The
+// deposits it contains and the withdrawals it finalized are only loaded if
+// withEvents is set, as described for GetIndexedL1BlockByHash. It returns
+// ErrBlockNotFound if the block is not indexed.
+func (d *Database) GetIndexedL1BlockByNumber(ctx context.Context, number uint64, withEvents bool) (*IndexedL1Block, error) {
+	const selectBlockByNumberStatement = `
+	SELECT
+		hash, parent_hash, number, timestamp
+	FROM l1_blocks
+	WHERE number = $1 AND reorged_at IS NULL
+	`
+
+	return d.getIndexedL1Block(ctx, selectBlockByNumberStatement, number, withEvents)
+}
+
+// getIndexedL1Block returns the L1 block selected by the given statement,
+// along with its events if withEvents is set.
+func (d *Database) getIndexedL1Block(ctx context.Context, statement string, arg interface{}, withEvents bool) (*IndexedL1Block, error) {
+	const selectDepositsByBlockHashStatement = `
+	SELECT
+		guid, from_address, to_address, l1_token, l2_token,
+		amount, tx_hash, data, log_index, source_address
+	FROM deposits
+	WHERE l1_block_hash = $1 AND reorged_at IS NULL
+	ORDER BY log_index;
+	`
+
+	const selectWithdrawalsByL1BlockHashStatement = `
+	SELECT
+		guid, from_address, to_address, l1_token, l2_token,
+		amount, tx_hash, data, log_index
+	FROM withdrawals
+	WHERE l1_block_hash = $1
+	ORDER BY log_index;
+	`
+
+	var block *IndexedL1Block
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		var hash string
+		var parentHash string
+		var number uint64
+		var timestamp uint64
+		err := tx.QueryRowContext(ctx, statement, arg).Scan(&hash, &parentHash, &number, &timestamp)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrBlockNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		block = &IndexedL1Block{
+			Hash:       common.HexToHash(hash),
+			ParentHash: common.HexToHash(parentHash),
+			Number:     number,
+			Timestamp:  timestamp,
+		}
+		if !withEvents {
+			return nil
+		}
+
+		block.Deposits, err = queryBlockDeposits(ctx, tx, selectDepositsByBlockHashStatement, hash)
+		if err != nil {
+			return err
+		}
+		block.Withdrawals, err = queryBlockWithdrawals(ctx, tx, selectWithdrawalsByL1BlockHashStatement, hash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// GetIndexedL2BlockByHash returns the L2 block by its hash. If withEvents is
+// set, the withdrawals it contains and the deposits it finalized are loaded
+// too, each in log order, with deposits invalidated by a reorg left out. It
+// returns ErrBlockNotFound if the block is not indexed.
+func (d *Database) GetIndexedL2BlockByHash(ctx context.Context, hash common.Hash, withEvents bool) (*IndexedL2Block, error) {
+	const selectBlockByHashStatement = `
+	SELECT
+		hash, parent_hash, number, timestamp
+	FROM l2_blocks
+	WHERE hash = $1
+	`
+
+	return d.getIndexedL2Block(ctx, selectBlockByHashStatement, hash.String(), withEvents)
+}
+
+// GetIndexedL2BlockByNumber returns the L2 block with the given number. If
+// withEvents is set, the withdrawals it contains and the deposits it
+// finalized are loaded too, leaving out deposits invalidated by a reorg. It
+// returns ErrBlockNotFound if the block is not indexed.
+func (d *Database) GetIndexedL2BlockByNumber(ctx context.Context, number uint64, withEvents bool) (*IndexedL2Block, error) {
+	const selectBlockByNumberStatement = `
+	SELECT
+		hash, parent_hash, number, timestamp
+	FROM l2_blocks
+	WHERE number = $1
+	`
+
+	return d.getIndexedL2Block(ctx, selectBlockByNumberStatement, number, withEvents)
+}
+
+// getIndexedL2Block returns the L2 block selected by the given statement,
+// along with its events if withEvents is set.
+func (d *Database) getIndexedL2Block(ctx context.Context, statement string, arg interface{}, withEvents bool) (*IndexedL2Block, error) {
+	const selectDepositsByL2BlockHashStatement = `
+	SELECT
+		guid, from_address, to_address, l1_token, l2_token,
+		amount, tx_hash, data, log_index, source_address
+	FROM deposits
+	WHERE l2_block_hash = $1 AND reorged_at IS NULL
+	ORDER BY log_index;
+	`
+
+	const selectWithdrawalsByBlockHashStatement = `
+	SELECT
+		guid, from_address, to_address, l1_token, l2_token,
+		amount, tx_hash, data, log_index
+	FROM withdrawals
+	WHERE l2_block_hash = $1
+	ORDER BY log_index;
 	`
-	var deposits []DepositJSON
 
-	err := txn(d.db, func(tx *sql.Tx) error {
-		rows, err := tx.Query(selectDepositsStatement, address.String(), page.Limit, page.Offset)
+	var block *IndexedL2Block
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		var hash string
+		var parentHash string
+		var number uint64
+		var timestamp uint64
+		err := tx.QueryRowContext(ctx, statement, arg).Scan(&hash, &parentHash, &number, &timestamp)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrBlockNotFound
+		}
 		if err != nil {
 			return err
 		}
-		defer rows.Close()
 
-		for rows.Next() {
-			var deposit DepositJSON
-			var l1Token Token
-			if err := rows.Scan(
-				&deposit.GUID, &deposit.FromAddress, &deposit.ToAddress,
-				&deposit.Amount, &deposit.TxHash, &deposit.Data,
-				&l1Token.Address, &deposit.L2Token,
-				&l1Token.Name, &l1Token.Symbol, &l1Token.Decimals,
-				&deposit.BlockNumber, &deposit.BlockTimestamp,
-			); err != nil {
-				return err
-			}
-			deposit.L1Token = &l1Token
-			deposits = append(deposits, deposit)
+		block = &IndexedL2Block{
+			Hash:       common.HexToHash(hash),
+			ParentHash: common.HexToHash(parentHash),
+			Number:     number,
+			Timestamp:  timestamp,
+		}
+		if !withEvents {
+			return nil
 		}
 
-		return rows.Err()
+		block.Deposits, err = queryBlockDeposits(ctx, tx, selectDepositsByL2BlockHashStatement, hash)
+		if err != nil {
+			return err
+		}
+		block.Withdrawals, err = queryBlockWithdrawals(ctx, tx, selectWithdrawalsByBlockHashStatement, hash)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	const selectDepositCountStatement = `
-	SELECT
-		count(*)
-	FROM deposits
-		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
-		INNER JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
-	WHERE deposits.from_address = $1;
-	`
+	return block, nil
+}
 
-	var count uint64
-	err = txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectDepositCountStatement, address.String())
+// queryBlockDeposits returns the deposits selected by the given statement for
+// the block with the given hash.
+func queryBlockDeposits(ctx context.Context, tx *sql.Tx, statement, hash string) ([]Deposit, error) {
+	rows, err := tx.QueryContext(ctx, statement, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deposits []Deposit
+	for rows.Next() {
+		var deposit Deposit
+		var fromAddress, toAddress, l1Token, l2Token, amount, txHash string
+		var sourceAddress sql.NullString
+		if err := rows.Scan(
+			&deposit.GUID, &fromAddress, &toAddress, &l1Token, &l2Token,
+			&amount, &txHash, &deposit.Data, &deposit.LogIndex, &sourceAddress,
+		); err != nil {
+			return nil, err
+		}
+
+		deposit.FromAddress = common.HexToAddress(fromAddress)
+		deposit.ToAddress = common.HexToAddress(toAddress)
+		deposit.L1Token = common.HexToAddress(l1Token)
+		deposit.L2Token = common.HexToAddress(l2Token)
+		deposit.TxHash = common.HexToHash(txHash)
+		deposit.SourceAddress = common.HexToAddress(sourceAddress.String)
+		deposit.Amount, err = parseAmount(amount)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		deposits = append(deposits, deposit)
+	}
 
-		return row.Scan(&count)
-	})
+	return deposits, rows.Err()
+}
+
+// queryBlockWithdrawals returns the withdrawals selected by the given
+// statement for the block with the given hash.
+func queryBlockWithdrawals(ctx context.Context, tx *sql.Tx, statement, hash string) ([]Withdrawal, error) {
+	rows, err := tx.QueryContext(ctx, statement, hash)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var withdrawals []Withdrawal
+	for rows.Next() {
+		var withdrawal Withdrawal
+		var fromAddress, toAddress, l1Token, l2Token, amount, txHash string
+		if err := rows.Scan(
+			&withdrawal.GUID, &fromAddress, &toAddress, &l1Token, &l2Token,
+			&amount, &txHash, &withdrawal.Data, &withdrawal.LogIndex,
+		); err != nil {
+			return nil, err
+		}
 
-	page.Total = count
+		withdrawal.FromAddress = common.HexToAddress(fromAddress)
+		withdrawal.ToAddress = common.HexToAddress(toAddress)
+		withdrawal.L1Token = common.HexToAddress(l1Token)
+		withdrawal.L2Token = common.HexToAddress(l2Token)
+		withdrawal.TxHash = common.HexToHash(txHash)
+		withdrawal.Amount, err = parseAmount(amount)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, withdrawal)
+	}
 
-	return &PaginatedDeposits{
-		&page,
-		deposits,
-	}, nil
+	return withdrawals, rows.Err()
 }
 
-// GetWithdrawalStatus returns the finalization status corresponding to the
-// given withdrawal transaction hash.
-func (d *Database) GetWithdrawalStatus(hash common.Hash) (*WithdrawalJSON, error) {
-	const selectWithdrawalStatement = `
-	SELECT
-	    withdrawals.guid, withdrawals.from_address, withdrawals.to_address,
-		withdrawals.amount, withdrawals.tx_hash, withdrawals.data,
-		withdrawals.l1_token, withdrawals.l2_token,
-		l2_tokens.name, l2_tokens.symbol, l2_tokens.decimals,
-		l1_blocks.number, l1_blocks.timestamp,
-		l2_blocks.number, l2_blocks.timestamp
-	FROM withdrawals
-		INNER JOIN l1_blocks ON withdrawals.l1_block_hash=l1_blocks.hash
-		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
-		INNER JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
-	WHERE withdrawals.tx_hash = $1;
-	`
+// parseAmount parses an amount stored as a base 10 string.
+func parseAmount(amount string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	return value, nil
+}
+
+const getAirdropQuery = `
+SELECT
+	address, voter_amount, multisig_signer_amount, gitcoin_amount,
+	active_bridged_amount, op_user_amount, op_repeat_user_amount,
+	op_og_amount, bonus_amount, total_amount
+FROM airdrops
+WHERE address = $1
+`
 
-	var withdrawal *WithdrawalJSON
-	err := txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectWithdrawalStatement, hash.String())
+// GetAirdrop returns the airdrop allocated to the given address, or nil if the
+// address is not eligible.
+func (d *Database) GetAirdrop(ctx context.Context, address common.Address) (*Airdrop, error) {
+	var airdrop *Airdrop
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, getAirdropQuery, addressString(address))
 		if row.Err() != nil {
-			return row.Err()
+			return fmt.Errorf("error getting airdrop: %w", row.Err())
 		}
 
-		var l2Token Token
-		if err := row.Scan(
-			&withdrawal.GUID, &withdrawal.FromAddress, &withdrawal.ToAddress,
-			&withdrawal.Amount, &withdrawal.TxHash, &withdrawal.Data,
-			&withdrawal.L1Token, &l2Token.Address,
-			&l2Token.Name, &l2Token.Symbol, &l2Token.Decimals,
-			&withdrawal.L1BlockNumber, &withdrawal.L1BlockTimestamp,
-			&withdrawal.L2BlockNumber, &withdrawal.L2BlockTimestamp,
-		); err != nil {
-			return err
+		a := new(Airdrop)
+		err := row.Scan(
+			&a.Address,
+			&a.VoterAmount,
+			&a.MultisigSignerAmount,
+			&a.GitcoinAmount,
+			&a.ActiveBridgedAmount,
+			&a.OpUserAmount,
+			&a.OpRepeatUserAmount,
+			&a.OpOgAmount,
+			&a.BonusAmount,
+			&a.TotalAmount,
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error scanning airdrop: %w", err)
 		}
-		withdrawal.L2Token = &l2Token
 
+		airdrop = a
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return withdrawal, nil
+	return airdrop, nil
 }
 
-// GetWithdrawalsByAddress returns the list of Withdrawals indexed for the given
-// address paginated by the given params.
-func (d *Database) GetWithdrawalsByAddress(address common.Address, page PaginationParam) (*PaginatedWithdrawals, error) {
-	const selectWithdrawalsStatement = `
+// maxAirdropsPerQuery caps the addresses looked up by a single statement so
+// that its parameters stay well below the 65535 Postgres allows.
+const maxAirdropsPerQuery = 1000
+
+// GetAirdrops returns the airdrops allocated to the given addresses, keyed by
+// address, in a single query per thousand addresses. Addresses that are not
+// eligible are absent from the map.
+func (d *Database) GetAirdrops(ctx context.Context, addresses []common.Address) (map[common.Address]*Airdrop, error) {
+	const selectAirdropsStatement = `
 	SELECT
-	    withdrawals.guid, withdrawals.from_address, withdrawals.to_address,
-		withdrawals.amount, withdrawals.tx_hash, withdrawals.data,
-		withdrawals.l1_token, withdrawals.l2_token,
-		l2_tokens.name, l2_tokens.symbol, l2_tokens.decimals,
-		l2_blocks.number, l2_blocks.timestamp
-	FROM withdrawals
-		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
-		INNER JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
-	WHERE withdrawals.from_address = $1 ORDER BY l2_blocks.timestamp LIMIT $2 OFFSET $3;
+		address, voter_amount, multisig_signer_amount, gitcoin_amount,
+		active_bridged_amount, op_user_amount, op_repeat_user_amount,
+		op_og_amount, bonus_amount, total_amount
+	FROM airdrops
+	WHERE address IN (%s)
 	`
-	var withdrawals []WithdrawalJSON
 
-	err := txn(d.db, func(tx *sql.Tx) error {
-		rows, err := tx.Query(selectWithdrawalsStatement, address.String(), page.Limit, page.Offset)
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
+	var airdrops map[common.Address]*Airdrop
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		airdrops = make(map[common.Address]*Airdrop)
 
-		for rows.Next() {
-			var withdrawal WithdrawalJSON
-			var l2Token Token
-			if err := rows.Scan(
-				&withdrawal.GUID, &withdrawal.FromAddress, &withdrawal.ToAddress,
-				&withdrawal.Amount, &withdrawal.TxHash, &withdrawal.Data,
-				&withdrawal.L1Token, &l2Token.Address,
-				&l2Token.Name, &l2Token.Symbol, &l2Token.Decimals,
-				&withdrawal.L2BlockNumber, &withdrawal.L2BlockTimestamp,
-			); err != nil {
+		for start := 0; start < len(addresses); start += maxAirdropsPerQuery {
+			end := start + maxAirdropsPerQuery
+			if end > len(addresses) {
+				end = len(addresses)
+			}
+			if err := getAirdrops(ctx, tx, selectAirdropsStatement, addresses[start:end], airdrops); err != nil {
 				return err
 			}
-			withdrawal.L2Token = &l2Token
-			withdrawals = append(withdrawals, withdrawal)
 		}
-
-		return rows.Err()
+		return nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
-	const selectWithdrawalCountStatement = `
-	SELECT
-		count(*)
-	FROM withdrawals
-		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
-		INNER JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
-	WHERE withdrawals.from_address = $1;
-	`
+	return airdrops, nil
+}
 
-	var count uint64
-	err = txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectWithdrawalCountStatement, address.String())
+// getAirdrops adds to airdrops the airdrops allocated to the given addresses,
+// looked up within tx with statement, whose IN list is filled in with a
+// placeholder per address.
+func getAirdrops(ctx context.Context, tx *sql.Tx, statement string, addresses []common.Address, airdrops map[common.Address]*Airdrop) error {
+	placeholders := make([]string, 0, len(addresses))
+	args := make([]interface{}, 0, len(addresses))
+	for i, address := range addresses {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, addressString(address))
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(statement, strings.Join(placeholders, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("error getting airdrops: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := new(Airdrop)
+		err := rows.Scan(
+			&a.Address,
+			&a.VoterAmount,
+			&a.MultisigSignerAmount,
+			&a.GitcoinAmount,
+			&a.ActiveBridgedAmount,
+			&a.OpUserAmount,
+			&a.OpRepeatUserAmount,
+			&a.OpOgAmount,
+			&a.BonusAmount,
+			&a.TotalAmount,
+		)
 		if err != nil {
+			return fmt.Errorf("error scanning airdrop: %w", err)
+		}
+		airdrops[common.HexToAddress(a.Address)] = a
+	}
+
+	return rows.Err()
+}
+
+// UpsertAirdrop stores the airdrop allocated to its address, replacing any
+// previous allocation. The replaced values are recorded in the history of the
+// address within the same transaction, see GetAirdropHistory. The airdrop is
+// rejected if it fails Validate.
+func (d *Database) UpsertAirdrop(ctx context.Context, airdrop *Airdrop) error {
+	return d.AddAirdrops(ctx, []*Airdrop{airdrop})
+}
+
+// AddAirdrops stores the given airdrops in a single transaction as described
+// for UpsertAirdrop. Either all airdrops are stored or none.
+func (d *Database) AddAirdrops(ctx context.Context, airdrops []*Airdrop) error {
+	for _, airdrop := range airdrops {
+		if err := airdrop.Validate(); err != nil {
 			return err
 		}
+	}
 
-		return row.Scan(&count)
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		for _, airdrop := range airdrops {
+			if err := upsertAirdrop(ctx, tx, airdrop); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
-	if err != nil {
-		return nil, err
-	}
+}
 
-	page.Total = count
+func upsertAirdrop(ctx context.Context, tx *sql.Tx, airdrop *Airdrop) error {
+	const recordAirdropStatement = `
+	INSERT INTO airdrop_history
+		(address, voter_amount, multisig_signer_amount, gitcoin_amount,
+		active_bridged_amount, op_user_amount, op_repeat_user_amount,
+		op_og_amount, bonus_amount, total_amount)
+	SELECT
+		address, voter_amount, multisig_signer_amount, gitcoin_amount,
+		active_bridged_amount, op_user_amount, op_repeat_user_amount,
+		op_og_amount, bonus_amount, total_amount
+	FROM airdrops
+	WHERE address = $1;
+	`
 
-	return &PaginatedWithdrawals{
-		&page,
-		withdrawals,
-	}, nil
+	const upsertAirdropStatement = `
+	INSERT INTO airdrops
+		(address, voter_amount, multisig_signer_amount, gitcoin_amount,
+		active_bridged_amount, op_user_amount, op_repeat_user_amount,
+		op_og_amount, bonus_amount, total_amount)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (address) DO UPDATE SET
+		voter_amount = $2, multisig_signer_amount = $3, gitcoin_amount = $4,
+		active_bridged_amount = $5, op_user_amount = $6, op_repeat_user_amount = $7,
+		op_og_amount = $8, bonus_amount = $9, total_amount = $10;
+	`
+
+	address := addressString(common.HexToAddress(airdrop.Address))
+	if _, err := tx.ExecContext(ctx, recordAirdropStatement, address); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		upsertAirdropStatement,
+		address,
+		airdrop.VoterAmount,
+		airdrop.MultisigSignerAmount,
+		airdrop.GitcoinAmount,
+		airdrop.ActiveBridgedAmount,
+		airdrop.OpUserAmount,
+		airdrop.OpRepeatUserAmount,
+		airdrop.OpOgAmount,
+		airdrop.BonusAmount,
+		airdrop.TotalAmount,
+	)
+	return err
 }
 
-// GetHighestL1Block returns the highest known L1 block.
-func (d *Database) GetHighestL1Block() (*BlockLocator, error) {
-	const selectHighestBlockStatement = `
-	SELECT number, hash FROM l1_blocks ORDER BY number DESC LIMIT 1
+// GetAirdropHistory returns the values the airdrop of the given address had
+// before each of its corrections, oldest first. The current values are
+// returned by GetAirdrop.
+func (d *Database) GetAirdropHistory(ctx context.Context, address common.Address) ([]AirdropChange, error) {
+	const selectAirdropHistoryStatement = `
+	SELECT
+		address, voter_amount, multisig_signer_amount, gitcoin_amount,
+		active_bridged_amount, op_user_amount, op_repeat_user_amount,
+		op_og_amount, bonus_amount, total_amount, replaced_at
+	FROM airdrop_history
+	WHERE address = $1
+	ORDER BY id;
 	`
 
-	var highestBlock *BlockLocator
-	err := txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectHighestBlockStatement)
-		if row.Err() != nil {
-			return row.Err()
-		}
+	var history []AirdropChange
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		history = nil
 
-		var number uint64
-		var hash string
-		err := row.Scan(&number, &hash)
+		rows, err := tx.QueryContext(ctx, selectAirdropHistoryStatement, addressString(address))
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				highestBlock = nil
-				return nil
-			}
 			return err
 		}
+		defer rows.Close()
 
-		highestBlock = &BlockLocator{
-			Number: number,
-			Hash:   common.HexToHash(hash),
+		for rows.Next() {
+			var change AirdropChange
+			a := &change.Previous
+			if err := rows.Scan(
+				&a.Address,
+				&a.VoterAmount,
+				&a.MultisigSignerAmount,
+				&a.GitcoinAmount,
+				&a.ActiveBridgedAmount,
+				&a.OpUserAmount,
+				&a.OpRepeatUserAmount,
+				&a.OpOgAmount,
+				&a.BonusAmount,
+				&a.TotalAmount,
+				&change.ReplacedAt,
+			); err != nil {
+				return err
+			}
+			history = append(history, change)
 		}
 
-		return nil
+		return rows.Err()
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return highestBlock, nil
+	return history, nil
 }
 
-// GetHighestL2Block returns the highest known L2 block.
-func (d *Database) GetHighestL2Block() (*BlockLocator, error) {
-	const selectHighestBlockStatement = `
-	SELECT number, hash FROM l2_blocks ORDER BY number DESC LIMIT 1
+// GetAirdropTotals returns the amounts of every airdrop summed per category
+// and overall, e.g. to check the allocation against the supply set aside for
+// the program. Amounts are stored as text, so they are summed as NUMERIC to
+// avoid overflowing. All amounts are zero if no airdrop is stored.
+func (d *Database) GetAirdropTotals(ctx context.Context) (*AirdropTotals, error) {
+	const selectAirdropTotalsStatement = `
+	SELECT
+		COALESCE(SUM(CAST(voter_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(multisig_signer_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(gitcoin_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(active_bridged_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(op_user_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(op_repeat_user_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(op_og_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(bonus_amount AS NUMERIC)), 0),
+		COALESCE(SUM(CAST(total_amount AS NUMERIC)), 0),
+		count(*)
+	FROM airdrops;
 	`
 
-	var highestBlock *BlockLocator
-	err := txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectHighestBlockStatement)
-		if row.Err() != nil {
-			return row.Err()
-		}
-
-		var number uint64
-		var hash string
-		err := row.Scan(&number, &hash)
+	var totals *AirdropTotals
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		t := new(AirdropTotals)
+		err := tx.QueryRowContext(ctx, selectAirdropTotalsStatement).Scan(
+			&t.VoterAmount,
+			&t.MultisigSignerAmount,
+			&t.GitcoinAmount,
+			&t.ActiveBridgedAmount,
+			&t.OpUserAmount,
+			&t.OpRepeatUserAmount,
+			&t.OpOgAmount,
+			&t.BonusAmount,
+			&t.TotalAmount,
+			&t.Count,
+		)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				highestBlock = nil
-				return nil
-			}
-			return err
-		}
-
-		highestBlock = &BlockLocator{
-			Number: number,
-			Hash:   common.HexToHash(hash),
+			return fmt.Errorf("error getting airdrop totals: %w", err)
 		}
 
+		totals = t
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return highestBlock, nil
+	return totals, nil
 }
 
-// GetIndexedL1BlockByHash returns the L1 block by it's hash.
-func (d *Database) GetIndexedL1BlockByHash(hash common.Hash) (*IndexedL1Block, error) {
-	const selectBlockByHashStatement = `
-	SELECT
-		hash, parent_hash, number, timestamp
-	FROM l1_blocks
-	WHERE hash = $1
+// FindInconsistentAirdrops returns the addresses of all airdrops whose total
+// amount does not equal the sum of their category amounts.
+func (d *Database) FindInconsistentAirdrops(ctx context.Context) ([]string, error) {
+	const selectInconsistentAirdropsStatement = `
+	SELECT address FROM airdrops
+	WHERE CAST(total_amount AS NUMERIC) <> (
+		CAST(voter_amount AS NUMERIC) + CAST(multisig_signer_amount AS NUMERIC) +
+		CAST(gitcoin_amount AS NUMERIC) + CAST(active_bridged_amount AS NUMERIC) +
+		CAST(op_user_amount AS NUMERIC) + CAST(op_repeat_user_amount AS NUMERIC) +
+		CAST(op_og_amount AS NUMERIC) + CAST(bonus_amount AS NUMERIC)
+	)
+	ORDER BY address;
 	`
 
-	var block *IndexedL1Block
-	err := txn(d.db, func(tx *sql.Tx) error {
-		row := tx.QueryRow(selectBlockByHashStatement, hash.String())
-		if row.Err() != nil {
-			return row.Err()
-		}
+	var addresses []string
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		addresses = nil
 
-		var hash string
-		var parentHash string
-		var number uint64
-		var timestamp uint64
-		err := row.Scan(&hash, &parentHash, &number, &timestamp)
+		rows, err := tx.QueryContext(ctx, selectInconsistentAirdropsStatement)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return nil
-			}
 			return err
 		}
+		defer rows.Close()
 
-		block = &IndexedL1Block{
-			Hash:       common.HexToHash(hash),
-			ParentHash: common.HexToHash(parentHash),
-			Number:     number,
-			Timestamp:  timestamp,
-			Deposits:   nil,
+		for rows.Next() {
+			var address string
+			if err := rows.Scan(&address); err != nil {
+				return err
+			}
+			addresses = append(addresses, address)
 		}
 
-		return nil
+		return rows.Err()
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return block, nil
-}
-
-const getAirdropQuery = `
-SELECT
-	address, voter_amount, multisig_signer_amount, gitcoin_amount,
-	active_bridged_amount, op_user_amount, op_repeat_user_amount,
-    bonus_amount, total_amount
-FROM airdrops
-WHERE address = $1
-`
-
-func (d *Database) GetAirdrop(address common.Address) (*Airdrop, error) {
-	row := d.db.QueryRow(getAirdropQuery, strings.ToLower(address.String()))
-	if row.Err() != nil {
-		return nil, fmt.Errorf("error getting airdrop: %v", row.Err())
-	}
-
-	airdrop := new(Airdrop)
-	err := row.Scan(
-		&airdrop.Address,
-		&airdrop.VoterAmount,
-		&airdrop.MultisigSignerAmount,
-		&airdrop.GitcoinAmount,
-		&airdrop.ActiveBridgedAmount,
-		&airdrop.OpUserAmount,
-		&airdrop.OpRepeatUserAmount,
-		&airdrop.BonusAmount,
-		&airdrop.TotalAmount,
-	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("error scanning airdrop: %v", err)
-	}
-	return airdrop, nil
+	return addresses, nil
 }
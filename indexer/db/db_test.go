@@ -0,0 +1,2204 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testDSN = "host=0.0.0.0 port=5432 user=postgres password=password sslmode=disable"
+
+var (
+	testFromAddress = common.HexToAddress("0xaa01")
+	testToAddress   = common.HexToAddress("0xaa02")
+)
+
+// newTestDSN creates an empty database and returns its connection string.
+func newTestDSN(t testing.TB) string {
+	dbName := uuid.NewString()
+
+	conn, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+
+	_, err = conn.Exec(fmt.Sprintf("CREATE DATABASE \"%s\";", dbName))
+	require.Nil(t, err)
+
+	err = conn.Close()
+	require.Nil(t, err)
+
+	return fmt.Sprintf("%s dbname=%s", testDSN, dbName)
+}
+
+func newDatabase(t testing.TB) *db.Database {
+	d, err := db.NewDatabase(newTestDSN(t))
+	require.Nil(t, err)
+
+	return d
+}
+
+// openConn opens a raw connection to the database backing d so tests can
+// manipulate rows that the Database API does not expose.
+func openConn(t *testing.T, d *db.Database) *sql.DB {
+	conn, err := sql.Open("postgres", d.Config())
+	require.Nil(t, err)
+	return conn
+}
+
+func newTestDeposit(txHash common.Hash, logIndex uint) db.Deposit {
+	return db.Deposit{
+		TxHash:      txHash,
+		L1Token:     common.HexToAddress(db.ETHL1Token.Address),
+		L2Token:     db.ETHL2Address,
+		FromAddress: testFromAddress,
+		ToAddress:   testToAddress,
+		Amount:      big.NewInt(1),
+		Data:        []byte{},
+		LogIndex:    logIndex,
+	}
+}
+
+func newTestWithdrawal(txHash common.Hash, logIndex uint) db.Withdrawal {
+	return db.Withdrawal{
+		TxHash:      txHash,
+		L1Token:     common.HexToAddress(db.ETHL1Token.Address),
+		L2Token:     db.ETHL2Address,
+		FromAddress: testFromAddress,
+		ToAddress:   testToAddress,
+		Amount:      big.NewInt(1),
+		Data:        []byte{},
+		LogIndex:    logIndex,
+	}
+}
+
+// TestNewDatabaseWithDB asserts that a Database can be created around an
+// existing pool, which is migrated and left open on Close.
+func TestNewDatabaseWithDB(t *testing.T) {
+	t.Parallel()
+
+	conn, err := sql.Open("postgres", newTestDSN(t))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	d, err := db.NewDatabaseWithDB(conn)
+	require.Nil(t, err)
+	require.Empty(t, d.Config())
+
+	ctx := context.Background()
+	version, err := d.CurrentSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, db.SchemaVersion, version)
+
+	require.Nil(t, d.Close())
+	require.Nil(t, conn.PingContext(ctx))
+}
+
+// TestMixedCaseAddresses asserts that tokens and deposits are found whatever
+// the casing of the addresses they are looked up with.
+func TestMixedCaseAddresses(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	token := "0xAbCdEf0000000000000000000000000000000001"
+	require.Nil(t, d.AddL1Token(ctx, token, &db.Token{Name: "Token", Symbol: "TKN", Decimals: 18}))
+	require.Nil(t, d.SetL1TokenVerified(ctx, strings.ToUpper(token), true))
+
+	for _, address := range []string{token, strings.ToLower(token), "0x" + strings.ToUpper(token[2:])} {
+		found, err := d.GetL1TokenByAddress(ctx, address)
+		require.Nil(t, err, address)
+		require.Equal(t, "TKN", found.Symbol)
+	}
+
+	bridge := common.HexToAddress("0xDd01")
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.L1Token = common.HexToAddress(token)
+	deposit.SourceAddress = bridge
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDeposits(ctx, db.ActivityFilter{
+		Address:       &testFromAddress,
+		SourceAddress: &bridge,
+	}, db.PaginationParam{})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, strings.ToLower(token), deposits.Deposits[0].L1Token.Address)
+	require.Equal(t, "TKN", deposits.Deposits[0].L1Token.Symbol)
+}
+
+// TestGetDepositsByAddressIncludeReorged asserts that deposits invalidated by
+// a reorg are only returned when IncludeReorged is set.
+func TestGetDepositsByAddressIncludeReorged(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	canonical := newTestDeposit(common.HexToHash("0xff01"), 0)
+	reorged := newTestDeposit(common.HexToHash("0xff02"), 1)
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{canonical, reorged},
+	})
+	require.Nil(t, err)
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(
+		"UPDATE deposits SET reorged_at = NOW() WHERE tx_hash = $1",
+		reorged.TxHash.String(),
+	)
+	require.Nil(t, err)
+
+	page := db.PaginationParam{Limit: 10}
+	filter := db.ActivityFilter{Address: &testFromAddress}
+	deposits, err := d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, canonical.TxHash.String(), deposits.Deposits[0].TxHash)
+
+	filter.IncludeReorged = true
+	deposits, err = d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 2)
+}
+
+// TestGetDepositsByAddressMaxPageOffset asserts that requesting a page ending
+// beyond the configured result window fails with ErrPageOffsetTooLarge.
+func TestGetDepositsByAddressMaxPageOffset(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:           newTestDSN(t),
+		MaxPageOffset: 100,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	_, err = d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{
+		Limit:  10,
+		Offset: 90,
+	})
+	require.Nil(t, err)
+
+	_, err = d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{
+		Limit:  10,
+		Offset: 91,
+	})
+	require.True(t, errors.Is(err, db.ErrPageOffsetTooLarge))
+
+	_, err = d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{
+		Limit:  11,
+		Offset: 90,
+	})
+	require.True(t, errors.Is(err, db.ErrPageOffsetTooLarge))
+}
+
+// TestPaginationParamValidate asserts that a page without a limit gets the
+// default one, and that a page above the maximum limit is rejected.
+func TestPaginationParamValidate(t *testing.T) {
+	t.Parallel()
+
+	page := db.PaginationParam{}
+	require.Nil(t, page.Validate(db.DefaultMaxPageLimit))
+	require.Equal(t, uint64(db.DefaultPageLimit), page.Limit)
+
+	page = db.PaginationParam{Limit: db.DefaultMaxPageLimit}
+	require.Nil(t, page.Validate(db.DefaultMaxPageLimit))
+	require.Equal(t, uint64(db.DefaultMaxPageLimit), page.Limit)
+
+	page = db.PaginationParam{Limit: db.DefaultMaxPageLimit + 1}
+	err := page.Validate(db.DefaultMaxPageLimit)
+	require.True(t, errors.Is(err, db.ErrPageLimitTooLarge))
+}
+
+// TestGetDepositsByAddressMaxPageLimit asserts that requesting a page larger
+// than the configured maximum fails with ErrPageLimitTooLarge.
+func TestGetDepositsByAddressMaxPageLimit(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:          newTestDSN(t),
+		MaxPageLimit: 20,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	_, err = d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{
+		Limit: 20,
+	})
+	require.Nil(t, err)
+
+	_, err = d.GetWithdrawalsByAddress(context.Background(), testFromAddress, db.PaginationParam{
+		Limit: 21,
+	})
+	require.True(t, errors.Is(err, db.ErrPageLimitTooLarge))
+}
+
+// TestGetAddressActivityRange asserts that the activity range spans both the
+// deposits and the withdrawals of an address.
+func TestGetAddressActivityRange(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	first, last, err := d.GetAddressActivityRange(context.Background(), testFromAddress)
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), first)
+	require.Equal(t, uint64(0), last)
+
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  100,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xff01"), 0)},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x01"),
+		Number:     2,
+		Timestamp:  300,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xff02"), 0)},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL2Block(context.Background(), &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   50,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee01"), 0)},
+	})
+	require.Nil(t, err)
+
+	first, last, err = d.GetAddressActivityRange(context.Background(), testFromAddress)
+	require.Nil(t, err)
+	require.Equal(t, uint64(50), first)
+	require.Equal(t, uint64(300), last)
+}
+
+// TestGetDepositsByAddressVerifiedTokens asserts that deposits of unverified
+// tokens are hidden unless they are explicitly requested, and that
+// CountDeposits agrees with the total of the listing either way.
+func TestGetDepositsByAddressVerifiedTokens(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	token := common.HexToAddress("0xcc01")
+	err := d.AddL1Token(context.Background(), token.String(), &db.Token{
+		Address:  token.String(),
+		Name:     "Scam",
+		Symbol:   "SCAM",
+		Decimals: 18,
+	})
+	require.Nil(t, err)
+
+	eth := newTestDeposit(common.HexToHash("0xff01"), 0)
+	scam := newTestDeposit(common.HexToHash("0xff02"), 1)
+	scam.L1Token = token
+
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{eth, scam},
+	})
+	require.Nil(t, err)
+
+	filter := db.ActivityFilter{Address: &testFromAddress}
+	page := db.PaginationParam{Limit: 10}
+	deposits, err := d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, eth.TxHash.String(), deposits.Deposits[0].TxHash)
+
+	count, err := d.CountDeposits(context.Background(), filter)
+	require.Nil(t, err)
+	require.Equal(t, deposits.Page.Total, count)
+
+	filter.IncludeUnverified = true
+	deposits, err = d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 2)
+
+	count, err = d.CountDeposits(context.Background(), filter)
+	require.Nil(t, err)
+	require.Equal(t, deposits.Page.Total, count)
+
+	err = d.SetL1TokenVerified(context.Background(), token.String(), true)
+	require.Nil(t, err)
+
+	filter.IncludeUnverified = false
+	deposits, err = d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 2)
+}
+
+// TestGetDepositsByAddressByteSize asserts that the page reports the size of
+// its serialized items.
+func TestGetDepositsByAddressByteSize(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.Data = []byte("some calldata")
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+
+	encoded, err := json.Marshal(deposits.Deposits)
+	require.Nil(t, err)
+	require.NotZero(t, deposits.Page.ByteSize)
+	require.Equal(t, uint64(len(encoded)), deposits.Page.ByteSize)
+}
+
+// TestGetBridgedBalance asserts that the bridged balance of an address is
+// updated as its deposits and withdrawals are indexed.
+func TestGetBridgedBalance(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	token := common.HexToAddress(db.ETHL1Token.Address)
+
+	balance, err := d.GetBridgedBalance(context.Background(), testFromAddress, token)
+	require.Nil(t, err)
+	require.Equal(t, 0, balance.Cmp(big.NewInt(0)))
+
+	deposit1 := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit1.Amount = big.NewInt(10)
+	deposit2 := newTestDeposit(common.HexToHash("0xff02"), 1)
+	deposit2.Amount = big.NewInt(5)
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit1, deposit2},
+	})
+	require.Nil(t, err)
+
+	balance, err = d.GetBridgedBalance(context.Background(), testFromAddress, token)
+	require.Nil(t, err)
+	require.Equal(t, 0, balance.Cmp(big.NewInt(15)))
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.Amount = big.NewInt(3)
+	err = d.AddIndexedL2Block(context.Background(), &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   2,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	balance, err = d.GetBridgedBalance(context.Background(), testFromAddress, token)
+	require.Nil(t, err)
+	require.Equal(t, 0, balance.Cmp(big.NewInt(12)))
+}
+
+// TestGetWithdrawalsByAddressRelatedDeposits asserts that a withdrawal is
+// linked to the earlier deposit it round-trips when requested.
+func TestGetWithdrawalsByAddressRelatedDeposits(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  100,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xff01"), 0)},
+	})
+	require.Nil(t, err)
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.FromAddress = testToAddress
+	withdrawal.ToAddress = testFromAddress
+	err = d.AddIndexedL2Block(context.Background(), &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   200,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+
+	page := db.PaginationParam{Limit: 10}
+	withdrawals, err := d.GetWithdrawalsByAddress(context.Background(), testToAddress, page)
+	require.Nil(t, err)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	require.Empty(t, withdrawals.Withdrawals[0].RelatedDepositGUID)
+
+	page.IncludeRelatedDeposits = true
+	withdrawals, err = d.GetWithdrawalsByAddress(context.Background(), testToAddress, page)
+	require.Nil(t, err)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	require.Equal(t, deposits.Deposits[0].GUID, withdrawals.Withdrawals[0].RelatedDepositGUID)
+}
+
+// TestGetDepositsByAddressOmitData asserts that the light listing omits the
+// deposit data while reporting its length, and that the data can be fetched
+// separately.
+func TestGetDepositsByAddressOmitData(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.Data = []byte("some calldata")
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{
+		Limit:    10,
+		OmitData: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+	require.Nil(t, deposits.Deposits[0].Data)
+	require.Equal(t, uint64(len(deposit.Data)), deposits.Deposits[0].DataLength)
+
+	data, err := d.GetDepositData(context.Background(), deposits.Deposits[0].GUID)
+	require.Nil(t, err)
+	require.Equal(t, deposit.Data, data)
+}
+
+// TestGetActivityData asserts that the data of deposits and withdrawals is
+// fetched by guid, that unknown and reorged guids return the not found errors
+// rather than empty data, and that malformed guids return ErrInvalidGUID.
+func TestGetActivityData(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:               newTestDSN(t),
+		SoftDeleteReorged: true,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.Data = []byte{}
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.Data = []byte{0x01, 0x02}
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	found, err := d.GetDepositByTxHash(ctx, deposit.TxHash, 0)
+	require.Nil(t, err)
+	data, err := d.GetDepositData(ctx, found.GUID)
+	require.Nil(t, err)
+	require.Empty(t, data)
+
+	proof, err := d.GetWithdrawalProofData(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	data, err = d.GetWithdrawalData(ctx, strings.ToUpper(proof.GUID))
+	require.Nil(t, err)
+	require.Equal(t, withdrawal.Data, data)
+
+	_, err = d.GetDepositData(ctx, db.NewGUID())
+	require.True(t, errors.Is(err, db.ErrDepositNotFound))
+	_, err = d.GetWithdrawalData(ctx, db.NewGUID())
+	require.True(t, errors.Is(err, db.ErrWithdrawalNotFound))
+
+	_, err = d.GetDepositData(ctx, "not-a-guid")
+	require.True(t, errors.Is(err, db.ErrInvalidGUID))
+	_, err = d.GetWithdrawalData(ctx, "not-a-guid")
+	require.True(t, errors.Is(err, db.ErrInvalidGUID))
+
+	require.Nil(t, d.DeleteL1BlocksFrom(ctx, 1))
+	_, err = d.GetDepositData(ctx, found.GUID)
+	require.True(t, errors.Is(err, db.ErrDepositNotFound))
+}
+
+// TestGetUniqueDepositorCount asserts that repeated deposits from the same
+// address are counted once, and only within the requested time window.
+func TestGetUniqueDepositorCount(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	other := newTestDeposit(common.HexToHash("0xff03"), 2)
+	other.FromAddress = common.HexToAddress("0xaa03")
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  100,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xff01"), 0),
+			newTestDeposit(common.HexToHash("0xff02"), 1),
+			other,
+		},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x01"),
+		Number:     2,
+		Timestamp:  200,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xff04"), 0)},
+	})
+	require.Nil(t, err)
+
+	count, err := d.GetUniqueDepositorCount(context.Background(), 0, 300)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), count)
+
+	count, err = d.GetUniqueDepositorCount(context.Background(), 200, 300)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), count)
+
+	count, err = d.GetUniqueDepositorCount(context.Background(), 0, 100)
+	require.Nil(t, err)
+	require.Equal(t, uint64(0), count)
+}
+
+// TestGetUniqueWithdrawerCount asserts that repeated withdrawals from the same
+// address are counted once.
+func TestGetUniqueWithdrawerCount(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	err := d.AddIndexedL2Block(context.Background(), &db.IndexedL2Block{
+		Hash:       common.HexToHash("0x11"),
+		ParentHash: common.HexToHash("0x10"),
+		Number:     1,
+		Timestamp:  100,
+		Withdrawals: []db.Withdrawal{
+			newTestWithdrawal(common.HexToHash("0xee01"), 0),
+			newTestWithdrawal(common.HexToHash("0xee02"), 1),
+		},
+	})
+	require.Nil(t, err)
+
+	count, err := d.GetUniqueWithdrawerCount(context.Background(), 0, 300)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), count)
+}
+
+// TestGetDepositsByAddressSourceAddress asserts that deposits can be filtered
+// by the bridge contract they were made through.
+func TestGetDepositsByAddressSourceAddress(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	standardBridge := common.HexToAddress("0xdd01")
+	customBridge := common.HexToAddress("0xdd02")
+
+	standard := newTestDeposit(common.HexToHash("0xff01"), 0)
+	standard.SourceAddress = standardBridge
+	custom := newTestDeposit(common.HexToHash("0xff02"), 1)
+	custom.SourceAddress = customBridge
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{standard, custom},
+	})
+	require.Nil(t, err)
+
+	page := db.PaginationParam{Limit: 10}
+	filter := db.ActivityFilter{Address: &testFromAddress}
+	deposits, err := d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), deposits.Page.Total)
+
+	filter.SourceAddress = &standardBridge
+	deposits, err = d.GetDeposits(context.Background(), filter, page)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, standard.TxHash.String(), deposits.Deposits[0].TxHash)
+	require.Equal(t, strings.ToLower(standardBridge.String()), deposits.Deposits[0].SourceAddress)
+}
+
+// TestGetIndexedL1BlockByHash asserts that an L1 block is returned with both
+// the deposits it contains and the withdrawals it finalized, or with neither
+// if events are not requested.
+func TestGetIndexedL1BlockByHash(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	err := d.AddIndexedL2Block(context.Background(), &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	l1BlockHash := common.HexToHash("0x01")
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       l1BlockHash,
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  2,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(
+		"UPDATE withdrawals SET l1_block_hash = $1 WHERE tx_hash = $2",
+		l1BlockHash.String(), withdrawal.TxHash.String(),
+	)
+	require.Nil(t, err)
+
+	block, err := d.GetIndexedL1BlockByHash(context.Background(), l1BlockHash, true)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), block.Number)
+	require.Len(t, block.Deposits, 1)
+	require.Equal(t, deposit.TxHash, block.Deposits[0].TxHash)
+	require.Equal(t, 0, deposit.Amount.Cmp(block.Deposits[0].Amount))
+	require.Len(t, block.Withdrawals, 1)
+	require.Equal(t, withdrawal.TxHash, block.Withdrawals[0].TxHash)
+	require.Equal(t, withdrawal.FromAddress, block.Withdrawals[0].FromAddress)
+
+	block, err = d.GetIndexedL1BlockByHash(context.Background(), l1BlockHash, false)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), block.Number)
+	require.Empty(t, block.Deposits)
+	require.Empty(t, block.Withdrawals)
+
+	_, err = d.GetIndexedL1BlockByHash(context.Background(), common.HexToHash("0x02"), false)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+}
+
+// TestGetIndexedL1BlockByHashDeposits asserts that every deposit of a block
+// is loaded along with it, in log order, leaving out reorged deposits.
+func TestGetIndexedL1BlockByHashDeposits(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	deposits := []db.Deposit{
+		newTestDeposit(common.HexToHash("0xff02"), 2),
+		newTestDeposit(common.HexToHash("0xff00"), 0),
+		newTestDeposit(common.HexToHash("0xff01"), 1),
+		newTestDeposit(common.HexToHash("0xff03"), 3),
+	}
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   deposits,
+	})
+	require.Nil(t, err)
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(
+		"UPDATE deposits SET reorged_at = NOW() WHERE tx_hash = $1",
+		common.HexToHash("0xff03").String(),
+	)
+	require.Nil(t, err)
+
+	block, err := d.GetIndexedL1BlockByHash(ctx, common.HexToHash("0x01"), true)
+	require.Nil(t, err)
+	require.Len(t, block.Deposits, 3)
+	for i, deposit := range block.Deposits {
+		require.Equal(t, uint(i), deposit.LogIndex)
+		require.Equal(t, common.BigToHash(big.NewInt(int64(0xff00+i))), deposit.TxHash)
+		require.Equal(t, testFromAddress, deposit.FromAddress)
+	}
+}
+
+// TestCanceledContext asserts that queries run with a canceled context fail
+// with context.Canceled.
+func TestCanceledContext(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.True(t, errors.Is(err, context.Canceled))
+
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+
+	highest, err := d.GetHighestL1Block(context.Background())
+	require.Nil(t, err)
+	require.Nil(t, highest)
+}
+
+// TestGUIDRoundTrip asserts that guids are stored as UUIDs and read back as
+// valid UUID strings that can be used to look rows up again.
+func TestGUIDRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.Data = []byte("some calldata")
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	var dataType string
+	err = conn.QueryRow(
+		"SELECT data_type FROM information_schema.columns WHERE table_name = 'deposits' AND column_name = 'guid'",
+	).Scan(&dataType)
+	require.Nil(t, err)
+	require.Equal(t, "uuid", dataType)
+
+	deposits, err := d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+
+	guid, err := uuid.Parse(deposits.Deposits[0].GUID)
+	require.Nil(t, err)
+	require.Equal(t, guid.String(), deposits.Deposits[0].GUID)
+
+	data, err := d.GetDepositData(context.Background(), guid.String())
+	require.Nil(t, err)
+	require.Equal(t, deposit.Data, data)
+}
+
+// TestAddIndexedL1BlockManyDeposits asserts that blocks with more deposits
+// than fit in a single insert statement are stored completely.
+func TestAddIndexedL1BlockManyDeposits(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	deposits := make([]db.Deposit, 2500)
+	for i := range deposits {
+		deposits[i] = newTestDeposit(common.BigToHash(big.NewInt(int64(i))), uint(i))
+	}
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   deposits,
+	})
+	require.Nil(t, err)
+
+	page, err := d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{Limit: 1})
+	require.Nil(t, err)
+	require.Equal(t, uint64(len(deposits)), page.Page.Total)
+
+	balance, err := d.GetBridgedBalance(context.Background(), testFromAddress, common.HexToAddress(db.ETHL1Token.Address))
+	require.Nil(t, err)
+	require.Equal(t, 0, balance.Cmp(big.NewInt(int64(len(deposits)))))
+}
+
+// BenchmarkAddIndexedL1Block measures inserting a block with 500 deposits.
+func BenchmarkAddIndexedL1Block(b *testing.B) {
+	d := newDatabase(b)
+	defer d.Close()
+
+	deposits := make([]db.Deposit, 500)
+	for i := range deposits {
+		deposits[i] = newTestDeposit(common.BigToHash(big.NewInt(int64(i))), uint(i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(i + 1))),
+			ParentHash: common.BigToHash(big.NewInt(int64(i))),
+			Number:     uint64(i + 1),
+			Timestamp:  uint64(i + 1),
+			Deposits:   deposits,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetDepositsByAddress measures listing a page of deposits and
+// looking up their token, with and without the statement cache.
+func BenchmarkGetDepositsByAddress(b *testing.B) {
+	for _, disabled := range []bool{false, true} {
+		name := "prepared"
+		if disabled {
+			name = "adhoc"
+		}
+		b.Run(name, func(b *testing.B) {
+			d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+				DSN:                   newTestDSN(b),
+				DisableStatementCache: disabled,
+			})
+			require.Nil(b, err)
+			defer d.Close()
+
+			deposits := make([]db.Deposit, 50)
+			for i := range deposits {
+				deposits[i] = newTestDeposit(common.BigToHash(big.NewInt(int64(i))), uint(i))
+			}
+			err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+				Hash:       common.HexToHash("0x01"),
+				ParentHash: common.HexToHash("0x00"),
+				Number:     1,
+				Timestamp:  1,
+				Deposits:   deposits,
+			})
+			require.Nil(b, err)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := d.GetDepositsByAddress(context.Background(), testFromAddress, db.PaginationParam{Limit: 10})
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, err = d.GetL1TokenByAddress(context.Background(), db.ETHL1Token.Address)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestGetWithdrawalsByAddressStatus asserts that withdrawals can be filtered
+// by whether they were finalized on L1.
+func TestGetWithdrawalsByAddressStatus(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	pending := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	finalized := newTestWithdrawal(common.HexToHash("0xee02"), 1)
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{pending, finalized},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  2,
+	})
+	require.Nil(t, err)
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(
+		"UPDATE withdrawals SET l1_block_hash = $1 WHERE tx_hash = $2",
+		common.HexToHash("0x01").String(), finalized.TxHash.String(),
+	)
+	require.Nil(t, err)
+
+	tests := []struct {
+		status    db.WithdrawalStatus
+		expTxHash []common.Hash
+	}{
+		{"", []common.Hash{pending.TxHash, finalized.TxHash}},
+		{db.WithdrawalStatusAll, []common.Hash{pending.TxHash, finalized.TxHash}},
+		{db.WithdrawalStatusPending, []common.Hash{pending.TxHash}},
+		{db.WithdrawalStatusFinalized, []common.Hash{finalized.TxHash}},
+	}
+	for _, test := range tests {
+		withdrawals, err := d.GetWithdrawals(ctx, db.ActivityFilter{
+			Address:          &testFromAddress,
+			WithdrawalStatus: test.status,
+		}, db.PaginationParam{Limit: 10})
+		require.Nil(t, err)
+		require.Equal(t, uint64(len(test.expTxHash)), withdrawals.Page.Total)
+
+		var txHashes []common.Hash
+		for _, withdrawal := range withdrawals.Withdrawals {
+			txHashes = append(txHashes, common.HexToHash(withdrawal.TxHash))
+		}
+		require.ElementsMatch(t, test.expTxHash, txHashes)
+	}
+
+	_, err = d.GetWithdrawals(ctx, db.ActivityFilter{
+		Address:          &testFromAddress,
+		WithdrawalStatus: "unknown",
+	}, db.PaginationParam{Limit: 10})
+	require.True(t, errors.Is(err, db.ErrInvalidWithdrawalStatus))
+}
+
+// TestGetDepositsByAddressCursor asserts that cursor pagination resumes right
+// after the cursor by default and at the cursor when it is inclusive.
+func TestGetDepositsByAddressCursor(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xdd02"), 2),
+			newTestDeposit(common.HexToHash("0xdd00"), 0),
+			newTestDeposit(common.HexToHash("0xdd01"), 1),
+		},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x01"),
+		Number:     2,
+		Timestamp:  2,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xdd10"), 0),
+			newTestDeposit(common.HexToHash("0xdd11"), 1),
+		},
+	})
+	require.Nil(t, err)
+
+	txHashes := func(deposits []db.DepositJSON) []common.Hash {
+		var hashes []common.Hash
+		for _, deposit := range deposits {
+			hashes = append(hashes, common.HexToHash(deposit.TxHash))
+		}
+		return hashes
+	}
+
+	first, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 2})
+	require.Nil(t, err)
+	require.Equal(t, []common.Hash{
+		common.HexToHash("0xdd00"), common.HexToHash("0xdd01"),
+	}, txHashes(first.Deposits))
+	require.NotEmpty(t, first.Page.NextCursor)
+
+	next, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:  2,
+		Cursor: first.Page.NextCursor,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []common.Hash{
+		common.HexToHash("0xdd02"), common.HexToHash("0xdd10"),
+	}, txHashes(next.Deposits))
+
+	inclusive, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:           2,
+		Cursor:          first.Page.NextCursor,
+		InclusiveCursor: true,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []common.Hash{
+		common.HexToHash("0xdd01"), common.HexToHash("0xdd02"),
+	}, txHashes(inclusive.Deposits))
+
+	last, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:  2,
+		Cursor: next.Page.NextCursor,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []common.Hash{common.HexToHash("0xdd11")}, txHashes(last.Deposits))
+	require.Empty(t, last.Page.NextCursor)
+
+	_, err = d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:  2,
+		Cursor: "not a cursor",
+	})
+	require.True(t, errors.Is(err, db.ErrInvalidCursor))
+}
+
+// TestGetWithdrawalStatus asserts that a finalized withdrawal is read back
+// with its L1 and L2 blocks, and that ErrWithdrawalNotFound is returned for an
+// unknown hash.
+func TestGetWithdrawalStatus(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     2,
+		Timestamp:  3,
+	})
+	require.Nil(t, err)
+
+	pending, err := d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.Equal(t, withdrawal.TxHash.String(), pending.TxHash)
+	require.Equal(t, uint64(1), pending.L2BlockNumber)
+	require.Nil(t, pending.L1BlockNumber)
+	require.Nil(t, pending.L1BlockTimestamp)
+	encoded, err := json.Marshal(pending)
+	require.Nil(t, err)
+	require.Contains(t, string(encoded), `"l1BlockNumber":null`)
+	require.Contains(t, string(encoded), `"l1BlockTimestamp":null`)
+
+	err = d.MarkWithdrawalFinalized(ctx, withdrawal.TxHash, common.HexToHash("0x01"))
+	require.Nil(t, err)
+
+	status, err := d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.Equal(t, withdrawal.TxHash.String(), status.TxHash)
+	require.Equal(t, strings.ToLower(testFromAddress.String()), status.FromAddress)
+	require.Equal(t, "1", status.Amount)
+	require.Equal(t, uint64(2), *status.L1BlockNumber)
+	require.Equal(t, "3", *status.L1BlockTimestamp)
+	require.Equal(t, uint64(1), status.L2BlockNumber)
+	require.Equal(t, "ETH", status.L2Token.Symbol)
+
+	_, err = d.GetWithdrawalStatus(ctx, common.HexToHash("0xee02"))
+	require.True(t, errors.Is(err, db.ErrWithdrawalNotFound))
+}
+
+// TestGetWithdrawalProofData asserts that the fields needed to prove a
+// withdrawal are returned, and that ErrWithdrawalNotFound is returned for an
+// unknown hash.
+func TestGetWithdrawalProofData(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.Data = []byte{0x01, 0x02}
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      7,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	proof, err := d.GetWithdrawalProofData(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.NotEmpty(t, proof.GUID)
+	require.Equal(t, withdrawal.TxHash, proof.TxHash)
+	require.Equal(t, uint64(7), proof.L2BlockNumber)
+	require.Equal(t, withdrawal.Data, proof.Data)
+
+	_, err = d.GetWithdrawalProofData(ctx, common.HexToHash("0xee02"))
+	require.True(t, errors.Is(err, db.ErrWithdrawalNotFound))
+}
+
+// TestGetDepositsByAddressSort asserts that deposits are listed in the
+// requested order, and that the total is unaffected by the order.
+func TestGetDepositsByAddressSort(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	amounts := []int64{20, 300, 1}
+	for i, amount := range amounts {
+		deposit := newTestDeposit(common.BigToHash(big.NewInt(int64(0xdd00+i))), 0)
+		deposit.Amount = big.NewInt(amount)
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(i + 1))),
+			ParentHash: common.BigToHash(big.NewInt(int64(i))),
+			Number:     uint64(i + 1),
+			Timestamp:  uint64(i + 1),
+			Deposits:   []db.Deposit{deposit},
+		})
+		require.Nil(t, err)
+	}
+
+	tests := []struct {
+		by         db.SortBy
+		dir        db.SortDir
+		expAmounts []string
+	}{
+		{"", "", []string{"20", "300", "1"}},
+		{db.SortByTimestamp, db.SortDesc, []string{"1", "300", "20"}},
+		{db.SortByBlockNumber, db.SortAsc, []string{"20", "300", "1"}},
+		{db.SortByAmount, db.SortAsc, []string{"1", "20", "300"}},
+		{db.SortByAmount, db.SortDesc, []string{"300", "20", "1"}},
+	}
+	for _, test := range tests {
+		deposits, err := d.GetDeposits(ctx, db.ActivityFilter{
+			Address: &testFromAddress,
+			SortBy:  test.by,
+			SortDir: test.dir,
+		}, db.PaginationParam{Limit: 10})
+		require.Nil(t, err)
+		require.Equal(t, uint64(3), deposits.Page.Total)
+
+		var amounts []string
+		for _, deposit := range deposits.Deposits {
+			amounts = append(amounts, deposit.Amount)
+		}
+		require.Equal(t, test.expAmounts, amounts)
+	}
+
+	_, err := d.GetDeposits(ctx, db.ActivityFilter{
+		Address: &testFromAddress,
+		SortBy:  "name",
+	}, db.PaginationParam{Limit: 10})
+	require.True(t, errors.Is(err, db.ErrInvalidSort))
+}
+
+// TestGetDepositsByToken asserts that only deposits of the given L1 token are
+// returned, along with the token's metadata.
+func TestGetDepositsByToken(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	token := common.HexToAddress("0xcc01")
+	err := d.AddL1Token(ctx, token.String(), &db.Token{
+		Address:  token.String(),
+		Name:     "Token",
+		Symbol:   "TKN",
+		Decimals: 6,
+	})
+	require.Nil(t, err)
+	require.Nil(t, d.SetL1TokenVerified(ctx, token.String(), true))
+
+	tokenDeposit := newTestDeposit(common.HexToHash("0xdd01"), 0)
+	tokenDeposit.L1Token = token
+	ethDeposit := newTestDeposit(common.HexToHash("0xdd02"), 1)
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{tokenDeposit, ethDeposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByToken(ctx, token, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, tokenDeposit.TxHash.String(), deposits.Deposits[0].TxHash)
+	require.Equal(t, strings.ToLower(token.String()), deposits.Deposits[0].L1Token.Address)
+	require.Equal(t, "TKN", deposits.Deposits[0].L1Token.Symbol)
+	require.Equal(t, uint8(6), deposits.Deposits[0].L1Token.Decimals)
+}
+
+// TestSkipTotal asserts that listings skipping the total fetch one extra row
+// to report whether more rows follow, and trim it from the page.
+func TestSkipTotal(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xdd01"), 0),
+			newTestDeposit(common.HexToHash("0xdd02"), 1),
+			newTestDeposit(common.HexToHash("0xdd03"), 2),
+		},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:       common.HexToHash("0x11"),
+		ParentHash: common.HexToHash("0x10"),
+		Number:     1,
+		Timestamp:  1,
+		Withdrawals: []db.Withdrawal{
+			newTestWithdrawal(common.HexToHash("0xee01"), 0),
+			newTestWithdrawal(common.HexToHash("0xee02"), 1),
+			newTestWithdrawal(common.HexToHash("0xee03"), 2),
+		},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:     2,
+		SkipTotal: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 2)
+	require.True(t, deposits.Page.HasMore)
+	require.Zero(t, deposits.Page.Total)
+
+	deposits, err = d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:     2,
+		Offset:    2,
+		SkipTotal: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+	require.False(t, deposits.Page.HasMore)
+
+	withdrawals, err := d.GetWithdrawalsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:     2,
+		SkipTotal: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, withdrawals.Withdrawals, 2)
+	require.True(t, withdrawals.Page.HasMore)
+
+	withdrawals, err = d.GetWithdrawalsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:     3,
+		SkipTotal: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, withdrawals.Withdrawals, 3)
+	require.False(t, withdrawals.Page.HasMore)
+}
+
+// TestGetDepositsByAddressSortNulls asserts that deposits without a source
+// address come last whichever the sort direction.
+func TestGetDepositsByAddressSortNulls(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	unknown := newTestDeposit(common.HexToHash("0xdd01"), 0)
+	first := newTestDeposit(common.HexToHash("0xdd02"), 1)
+	first.SourceAddress = common.HexToAddress("0x1101")
+	second := newTestDeposit(common.HexToHash("0xdd03"), 2)
+	second.SourceAddress = common.HexToAddress("0x1102")
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{unknown, first, second},
+	})
+	require.Nil(t, err)
+
+	// Deposits indexed before source addresses were recorded have none.
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(
+		"UPDATE deposits SET source_address = NULL WHERE tx_hash = $1",
+		unknown.TxHash.String(),
+	)
+	require.Nil(t, err)
+
+	tests := []struct {
+		dir       db.SortDir
+		expTxHash []common.Hash
+	}{
+		{db.SortAsc, []common.Hash{first.TxHash, second.TxHash, unknown.TxHash}},
+		{db.SortDesc, []common.Hash{second.TxHash, first.TxHash, unknown.TxHash}},
+	}
+	for _, test := range tests {
+		for _, limit := range []uint64{1, 10} {
+			var txHashes []common.Hash
+			for offset := uint64(0); offset < 3; offset += limit {
+				deposits, err := d.GetDeposits(ctx, db.ActivityFilter{
+					Address: &testFromAddress,
+					SortBy:  db.SortBySourceAddress,
+					SortDir: test.dir,
+				}, db.PaginationParam{
+					Limit:  limit,
+					Offset: offset,
+				})
+				require.Nil(t, err)
+				for _, deposit := range deposits.Deposits {
+					txHashes = append(txHashes, common.HexToHash(deposit.TxHash))
+				}
+			}
+			require.Equal(t, test.expTxHash, txHashes)
+		}
+	}
+}
+
+// TestGetDepositByTxHash asserts that a deposit is looked up by both its
+// transaction hash and log index, and that ErrDepositNotFound is returned
+// otherwise.
+func TestGetDepositByTxHash(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	txHash := common.HexToHash("0xdd01")
+	first := newTestDeposit(txHash, 0)
+	second := newTestDeposit(txHash, 1)
+	second.Amount = big.NewInt(2)
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{first, second},
+	})
+	require.Nil(t, err)
+
+	deposit, err := d.GetDepositByTxHash(ctx, txHash, 1)
+	require.Nil(t, err)
+	require.Equal(t, txHash.String(), deposit.TxHash)
+	require.Equal(t, uint64(1), deposit.LogIndex)
+	require.Equal(t, "2", deposit.Amount)
+	require.Equal(t, uint64(1), deposit.BlockNumber)
+	require.Equal(t, "ETH", deposit.L1Token.Symbol)
+
+	_, err = d.GetDepositByTxHash(ctx, txHash, 2)
+	require.True(t, errors.Is(err, db.ErrDepositNotFound))
+
+	_, err = d.GetDepositByTxHash(ctx, common.HexToHash("0xdd02"), 0)
+	require.True(t, errors.Is(err, db.ErrDepositNotFound))
+}
+
+// TestGetDepositByGUID asserts that a deposit is looked up by its guid in any
+// casing, that ErrDepositNotFound is returned for an unknown guid and that
+// ErrInvalidGUID is returned for a malformed one.
+func TestGetDepositByGUID(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	txHash := common.HexToHash("0xdd03")
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{newTestDeposit(txHash, 0)},
+	})
+	require.Nil(t, err)
+
+	expected, err := d.GetDepositByTxHash(ctx, txHash, 0)
+	require.Nil(t, err)
+
+	deposit, err := d.GetDepositByGUID(ctx, strings.ToUpper(expected.GUID))
+	require.Nil(t, err)
+	require.Equal(t, expected, deposit)
+
+	_, err = d.GetDepositByGUID(ctx, db.NewGUID())
+	require.True(t, errors.Is(err, db.ErrDepositNotFound))
+
+	_, err = d.GetDepositByGUID(ctx, "not-a-guid")
+	require.True(t, errors.Is(err, db.ErrInvalidGUID))
+}
+
+// TestGetWithdrawalByGUID asserts that a withdrawal is looked up by its guid
+// along with the L1 block finalizing it, that ErrWithdrawalNotFound is
+// returned for an unknown guid and that ErrInvalidGUID is returned for a
+// malformed one.
+func TestGetWithdrawalByGUID(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee03"), 2)
+	withdrawal.Data = []byte{0x01, 0x02}
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      7,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	proof, err := d.GetWithdrawalProofData(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+
+	found, err := d.GetWithdrawalByGUID(ctx, proof.GUID)
+	require.Nil(t, err)
+	require.Equal(t, proof.GUID, found.GUID)
+	require.Equal(t, withdrawal.TxHash.String(), found.TxHash)
+	require.Equal(t, uint64(2), found.LogIndex)
+	require.Equal(t, uint64(7), found.L2BlockNumber)
+	require.Equal(t, withdrawal.Data, found.Data)
+	require.Equal(t, uint64(2), found.DataLength)
+	require.Nil(t, found.L1BlockNumber)
+
+	_, err = d.GetWithdrawalByGUID(ctx, db.NewGUID())
+	require.True(t, errors.Is(err, db.ErrWithdrawalNotFound))
+
+	_, err = d.GetWithdrawalByGUID(ctx, "")
+	require.True(t, errors.Is(err, db.ErrInvalidGUID))
+}
+
+// TestGetDepositsByAddressNewestFirstCursor asserts that a newest-first
+// listing continued through its cursor loads the older deposits without
+// overlap or gap.
+func TestGetDepositsByAddressNewestFirstCursor(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	var expTxHashes []common.Hash
+	for number := uint64(1); number <= 3; number++ {
+		var deposits []db.Deposit
+		for logIndex := uint(0); logIndex < 2; logIndex++ {
+			txHash := common.BigToHash(new(big.Int).SetUint64(0xdd00 + number*10 + uint64(logIndex)))
+			deposits = append(deposits, newTestDeposit(txHash, logIndex))
+			expTxHashes = append([]common.Hash{txHash}, expTxHashes...)
+		}
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(new(big.Int).SetUint64(number)),
+			ParentHash: common.BigToHash(new(big.Int).SetUint64(number - 1)),
+			Number:     number,
+			Timestamp:  number,
+			Deposits:   deposits,
+		})
+		require.Nil(t, err)
+	}
+
+	var txHashes []common.Hash
+	var cursor string
+	for {
+		deposits, err := d.GetDeposits(ctx, db.ActivityFilter{
+			Address: &testFromAddress,
+			SortDir: db.SortDesc,
+		}, db.PaginationParam{
+			Limit:  4,
+			Cursor: cursor,
+		})
+		require.Nil(t, err)
+		for _, deposit := range deposits.Deposits {
+			txHashes = append(txHashes, common.HexToHash(deposit.TxHash))
+		}
+		if deposits.Page.NextCursor == "" {
+			break
+		}
+		cursor = deposits.Page.NextCursor
+	}
+	require.Equal(t, expTxHashes, txHashes)
+}
+
+// TestGetNetFlowSeries asserts that deposits and withdrawals of a token in
+// the same bucket are netted, and that buckets with activity on one side only
+// are returned as well.
+func TestGetNetFlowSeries(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	const hour = 60 * 60
+
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.Amount = big.NewInt(10)
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  hour + 10,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.Amount = big.NewInt(4)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   hour + 20,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	withdrawal = newTestWithdrawal(common.HexToHash("0xee02"), 0)
+	withdrawal.Amount = big.NewInt(7)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x12"),
+		ParentHash:  common.HexToHash("0x11"),
+		Number:      2,
+		Timestamp:   2*hour + 30,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	series, err := d.GetNetFlowSeries(ctx, 0, 3*hour, "hour")
+	require.Nil(t, err)
+	require.Len(t, series, 2)
+
+	require.Equal(t, uint64(hour), series[0].Timestamp)
+	require.Equal(t, db.ETHL1Token.Address, series[0].Token)
+	require.Equal(t, big.NewInt(10), series[0].DepositVolume)
+	require.Equal(t, big.NewInt(4), series[0].WithdrawalVolume)
+	require.Equal(t, big.NewInt(6), series[0].NetFlow)
+
+	require.Equal(t, uint64(2*hour), series[1].Timestamp)
+	require.Zero(t, series[1].DepositVolume.Sign())
+	require.Equal(t, big.NewInt(-7), series[1].NetFlow)
+
+	_, err = d.GetNetFlowSeries(ctx, 0, 3*hour, "minute")
+	require.True(t, errors.Is(err, db.ErrInvalidBucket))
+}
+
+// TestGetDepositsByDay asserts that deposits are counted per day, that days
+// without deposits are included with a zero count, and that invalid ranges
+// are rejected.
+func TestGetDepositsByDay(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	const day = 24 * 60 * 60
+
+	for i, timestamp := range []uint64{day + 10, day + 20, 3*day + 30} {
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(i + 1))),
+			ParentHash: common.BigToHash(big.NewInt(int64(i))),
+			Number:     uint64(i + 1),
+			Timestamp:  timestamp,
+			Deposits:   []db.Deposit{newTestDeposit(common.BigToHash(big.NewInt(int64(0xff01+i))), 0)},
+		})
+		require.Nil(t, err)
+	}
+
+	series, err := d.GetDepositsByDay(ctx, day+5, 4*day-1)
+	require.Nil(t, err)
+	require.Equal(t, []db.DailyCount{
+		{Day: day, Count: 2},
+		{Day: 2 * day, Count: 0},
+		{Day: 3 * day, Count: 1},
+	}, series)
+
+	series, err = d.GetWithdrawalsByDay(ctx, day, day)
+	require.Nil(t, err)
+	require.Equal(t, []db.DailyCount{{Day: day, Count: 0}}, series)
+
+	_, err = d.GetDepositsByDay(ctx, 2*day, day)
+	require.True(t, errors.Is(err, db.ErrInvalidTimeRange))
+
+	_, err = d.GetDepositsByDay(ctx, 0, 100*365*day)
+	require.True(t, errors.Is(err, db.ErrInvalidTimeRange))
+}
+
+// TestHealthCheck asserts that a reachable database passes the health check
+// and that a closed one fails it.
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+
+	require.Nil(t, d.HealthCheck(context.Background()))
+	require.NotZero(t, d.Stats().OpenConnections)
+
+	require.Nil(t, d.Close())
+	require.NotNil(t, d.HealthCheck(context.Background()))
+}
+
+// TestGetActivityWithUnknownToken asserts that deposits and withdrawals of a
+// token whose metadata is not indexed are listed with empty metadata rather
+// than dropped.
+func TestGetActivityWithUnknownToken(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	unknownToken := common.HexToAddress("0xcc01")
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.L2Token = unknownToken
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	withdrawals, err := d.GetWithdrawalsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), withdrawals.Page.Total)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	require.Equal(t, strings.ToLower(unknownToken.String()), withdrawals.Withdrawals[0].L2Token.Address)
+	require.Empty(t, withdrawals.Withdrawals[0].L2Token.Symbol)
+
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.L1Token = unknownToken
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Empty(t, deposits.Deposits)
+
+	deposits, err = d.GetDeposits(ctx, db.ActivityFilter{
+		Address:           &testFromAddress,
+		IncludeUnverified: true,
+	}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, strings.ToLower(unknownToken.String()), deposits.Deposits[0].L1Token.Address)
+	require.Empty(t, deposits.Deposits[0].L1Token.Name)
+}
+
+// TestGetWithdrawalsByAddressCursor asserts that withdrawals are paged by
+// cursor without skipping or repeating rows when withdrawals are indexed
+// between two requests.
+func TestGetWithdrawalsByAddressCursor(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:       common.HexToHash("0x11"),
+		ParentHash: common.HexToHash("0x10"),
+		Number:     1,
+		Timestamp:  1,
+		Withdrawals: []db.Withdrawal{
+			newTestWithdrawal(common.HexToHash("0xee01"), 1),
+			newTestWithdrawal(common.HexToHash("0xee00"), 0),
+			newTestWithdrawal(common.HexToHash("0xee02"), 2),
+		},
+	})
+	require.Nil(t, err)
+
+	txHashes := func(withdrawals []db.WithdrawalJSON) []common.Hash {
+		var hashes []common.Hash
+		for _, withdrawal := range withdrawals {
+			hashes = append(hashes, common.HexToHash(withdrawal.TxHash))
+		}
+		return hashes
+	}
+
+	first, err := d.GetWithdrawalsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 2})
+	require.Nil(t, err)
+	require.Equal(t, []common.Hash{
+		common.HexToHash("0xee00"), common.HexToHash("0xee01"),
+	}, txHashes(first.Withdrawals))
+	require.NotEmpty(t, first.Page.NextCursor)
+
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x12"),
+		ParentHash:  common.HexToHash("0x11"),
+		Number:      2,
+		Timestamp:   2,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee10"), 0)},
+	})
+	require.Nil(t, err)
+
+	next, err := d.GetWithdrawalsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:  2,
+		Cursor: first.Page.NextCursor,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []common.Hash{
+		common.HexToHash("0xee02"), common.HexToHash("0xee10"),
+	}, txHashes(next.Withdrawals))
+
+	_, err = d.GetWithdrawals(ctx, db.ActivityFilter{
+		Address: &testFromAddress,
+		SortBy:  db.SortBySourceAddress,
+	}, db.PaginationParam{Limit: 2})
+	require.True(t, errors.Is(err, db.ErrInvalidSort))
+}
+
+// TestGetIndexedBlockByNumber asserts that blocks are looked up by number,
+// that their events are only loaded on request, and that ErrBlockNotFound is
+// returned for blocks that are not indexed.
+func TestGetIndexedBlockByNumber(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xff01"), 0)},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee01"), 0)},
+	})
+	require.Nil(t, err)
+
+	l1Block, err := d.GetIndexedL1BlockByNumber(ctx, 1, false)
+	require.Nil(t, err)
+	require.Equal(t, common.HexToHash("0x01"), l1Block.Hash)
+	require.Empty(t, l1Block.Deposits)
+
+	l1Block, err = d.GetIndexedL1BlockByNumber(ctx, 1, true)
+	require.Nil(t, err)
+	require.Len(t, l1Block.Deposits, 1)
+	require.Equal(t, common.HexToHash("0xff01"), l1Block.Deposits[0].TxHash)
+
+	l2Block, err := d.GetIndexedL2BlockByNumber(ctx, 1, true)
+	require.Nil(t, err)
+	require.Equal(t, common.HexToHash("0x11"), l2Block.Hash)
+	require.Len(t, l2Block.Withdrawals, 1)
+	require.Equal(t, common.HexToHash("0xee01"), l2Block.Withdrawals[0].TxHash)
+
+	_, err = d.GetIndexedL1BlockByNumber(ctx, 2, false)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+	_, err = d.GetIndexedL2BlockByNumber(ctx, 2, true)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+}
+
+// TestGetIndexedL2BlockByHash asserts that an L2 block is looked up by hash
+// along with its withdrawals, and that an unknown hash is reported as not
+// found.
+func TestGetIndexedL2BlockByHash(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee01"), 0)},
+	})
+	require.Nil(t, err)
+
+	block, err := d.GetIndexedL2BlockByHash(ctx, common.HexToHash("0x11"), true)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), block.Number)
+	require.Equal(t, common.HexToHash("0x10"), block.ParentHash)
+	require.Len(t, block.Withdrawals, 1)
+	require.Equal(t, common.HexToHash("0xee01"), block.Withdrawals[0].TxHash)
+
+	_, err = d.GetIndexedL2BlockByHash(ctx, common.HexToHash("0x12"), false)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+}
+
+// TestGetDepositsByAddressTokenCounts asserts that deposits matching the
+// query are counted per token beyond the page bounds, and only on request.
+func TestGetDepositsByAddressTokenCounts(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	usdc := common.HexToAddress("0xcc01")
+	require.Nil(t, d.AddL1Token(ctx, usdc.String(), &db.Token{Name: "USD Coin", Symbol: "USDC", Decimals: 6}))
+	require.Nil(t, d.SetL1TokenVerified(ctx, usdc.String(), true))
+
+	var deposits []db.Deposit
+	for i := 0; i < 3; i++ {
+		deposit := newTestDeposit(common.BigToHash(big.NewInt(int64(0xff00+i))), uint(i))
+		if i > 0 {
+			deposit.L1Token = usdc
+		}
+		deposits = append(deposits, deposit)
+	}
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   deposits,
+	})
+	require.Nil(t, err)
+
+	page, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 1})
+	require.Nil(t, err)
+	require.Nil(t, page.Page.TokenCounts)
+
+	page, err = d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{
+		Limit:              1,
+		IncludeTokenCounts: true,
+	})
+	require.Nil(t, err)
+	require.Len(t, page.Deposits, 1)
+	require.Equal(t, map[string]uint64{
+		db.ETHL1Token.Address: 1,
+		usdc.String():         2,
+	}, page.Page.TokenCounts)
+}
+
+// TestCountDepositsAndWithdrawals asserts that deposits and withdrawals are
+// counted whatever the filter, including an empty one, and that withdrawals
+// are counted by finalization status.
+func TestCountDepositsAndWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	other := newTestDeposit(common.HexToHash("0xff03"), 0)
+	other.FromAddress = common.HexToAddress("0xaa03")
+	for i, deposits := range [][]db.Deposit{
+		{newTestDeposit(common.HexToHash("0xff01"), 0), newTestDeposit(common.HexToHash("0xff02"), 1)},
+		{other},
+	} {
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(i + 1))),
+			ParentHash: common.BigToHash(big.NewInt(int64(i))),
+			Number:     uint64(i + 1),
+			Timestamp:  uint64(i + 1),
+			Deposits:   deposits,
+		})
+		require.Nil(t, err)
+	}
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee01"), 0)},
+	})
+	require.Nil(t, err)
+
+	count, err := d.CountDeposits(ctx, db.ActivityFilter{})
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), count)
+
+	count, err = d.CountDeposits(ctx, db.ActivityFilter{Address: &testFromAddress})
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), count)
+
+	count, err = d.CountDeposits(ctx, db.ActivityFilter{FromBlock: 2, ToBlock: 2})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), count)
+
+	count, err = d.CountWithdrawals(ctx, db.ActivityFilter{})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), count)
+
+	otherToken := common.HexToAddress("0xcc01")
+	count, err = d.CountWithdrawals(ctx, db.ActivityFilter{Token: &otherToken})
+	require.Nil(t, err)
+	require.Zero(t, count)
+
+	count, err = d.CountWithdrawals(ctx, db.ActivityFilter{WithdrawalStatus: db.WithdrawalStatusPending})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), count)
+
+	count, err = d.CountWithdrawals(ctx, db.ActivityFilter{WithdrawalStatus: db.WithdrawalStatusFinalized})
+	require.Nil(t, err)
+	require.Zero(t, count)
+
+	_, err = d.CountWithdrawals(ctx, db.ActivityFilter{WithdrawalStatus: "unknown"})
+	require.True(t, errors.Is(err, db.ErrInvalidWithdrawalStatus))
+}
+
+// TestGetLatestActivity asserts that the latest deposits and withdrawals of
+// all addresses are returned newest first, up to the limit.
+func TestGetLatestActivity(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	other := common.HexToAddress("0xaa03")
+	for number := uint64(1); number <= 2; number++ {
+		first := newTestDeposit(common.BigToHash(big.NewInt(int64(2*number))), 0)
+		second := newTestDeposit(common.BigToHash(big.NewInt(int64(2*number+1))), 1)
+		second.FromAddress = other
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number,
+			Deposits:   []db.Deposit{first, second},
+		})
+		require.Nil(t, err)
+
+		withdrawal := newTestWithdrawal(common.BigToHash(big.NewInt(int64(number))), 0)
+		withdrawal.FromAddress = other
+		err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+			Hash:        common.BigToHash(big.NewInt(int64(number))),
+			ParentHash:  common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:      number,
+			Timestamp:   number,
+			Withdrawals: []db.Withdrawal{withdrawal},
+		})
+		require.Nil(t, err)
+	}
+
+	deposits, err := d.GetLatestDeposits(ctx, 3)
+	require.Nil(t, err)
+	require.Len(t, deposits, 3)
+	require.Equal(t, common.BigToHash(big.NewInt(5)), common.HexToHash(deposits[0].TxHash))
+	require.Equal(t, common.BigToHash(big.NewInt(4)), common.HexToHash(deposits[1].TxHash))
+	require.Equal(t, common.BigToHash(big.NewInt(3)), common.HexToHash(deposits[2].TxHash))
+
+	withdrawals, err := d.GetLatestWithdrawals(ctx, 10)
+	require.Nil(t, err)
+	require.Len(t, withdrawals, 2)
+	require.Equal(t, uint64(2), withdrawals[0].L2BlockNumber)
+	require.Equal(t, uint64(1), withdrawals[1].L2BlockNumber)
+}
+
+// TestGetDepositsByBlockRange asserts that the block range is inclusive on
+// both ends, and that a range ending before it starts is rejected.
+func TestGetDepositsByBlockRange(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	for number := uint64(1); number <= 4; number++ {
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number,
+			Deposits: []db.Deposit{
+				newTestDeposit(common.BigToHash(big.NewInt(int64(100+number))), 0),
+			},
+		})
+		require.Nil(t, err)
+	}
+
+	tests := []struct {
+		from, to   uint64
+		expNumbers []uint64
+	}{
+		{2, 3, []uint64{2, 3}},
+		{3, 3, []uint64{3}},
+		{0, 1, []uint64{1}},
+		{5, 9, nil},
+		{0, 0, nil},
+	}
+	for _, test := range tests {
+		deposits, err := d.GetDepositsByBlockRange(ctx, test.from, test.to, db.PaginationParam{Limit: 10})
+		require.Nil(t, err)
+		var numbers []uint64
+		for _, deposit := range deposits.Deposits {
+			numbers = append(numbers, deposit.BlockNumber)
+		}
+		require.Equal(t, test.expNumbers, numbers)
+		require.Equal(t, uint64(len(test.expNumbers)), deposits.Page.Total)
+	}
+
+	_, err := d.GetDepositsByBlockRange(ctx, 3, 2, db.PaginationParam{Limit: 10})
+	require.True(t, errors.Is(err, db.ErrInvalidBlockRange))
+}
+
+// TestGetDepositsByAddressSince asserts that only the deposits of the address
+// in blocks after the given one are returned, in ascending block order rather
+// than timestamp order, and counted.
+func TestGetDepositsByAddressSince(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	for number := uint64(1); number <= 4; number++ {
+		deposit := newTestDeposit(common.BigToHash(big.NewInt(int64(100+number))), 0)
+		if number == 4 {
+			deposit.FromAddress = testToAddress
+		}
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  10 - number,
+			Deposits:   []db.Deposit{deposit},
+		})
+		require.Nil(t, err)
+	}
+
+	deposits, err := d.GetDepositsByAddressSince(ctx, testFromAddress, 1, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 2)
+	require.Equal(t, uint64(2), deposits.Deposits[0].BlockNumber)
+	require.Equal(t, uint64(3), deposits.Deposits[1].BlockNumber)
+	require.Equal(t, uint64(2), deposits.Page.Total)
+
+	deposits, err = d.GetDepositsByAddressSince(ctx, testFromAddress, 3, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Empty(t, deposits.Deposits)
+	require.Equal(t, uint64(0), deposits.Page.Total)
+}
+
+// TestGetWithdrawalsByBlockRange asserts that only withdrawals of L2 blocks
+// within the inclusive range are listed and counted, and that inverted bounds
+// are rejected.
+func TestGetWithdrawalsByBlockRange(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	unknown := common.HexToAddress("0xdd01")
+	for number := uint64(1); number <= 4; number++ {
+		withdrawal := newTestWithdrawal(common.BigToHash(big.NewInt(int64(100+number))), 0)
+		if number == 3 {
+			withdrawal.L2Token = unknown
+		}
+		err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+			Hash:        common.BigToHash(big.NewInt(int64(0x10 + number))),
+			ParentHash:  common.BigToHash(big.NewInt(int64(0x10 + number - 1))),
+			Number:      number,
+			Timestamp:   number,
+			Withdrawals: []db.Withdrawal{withdrawal},
+		})
+		require.Nil(t, err)
+	}
+
+	tests := []struct {
+		from, to   uint64
+		expNumbers []uint64
+	}{
+		{2, 3, []uint64{2, 3}},
+		{3, 3, []uint64{3}},
+		{0, 1, []uint64{1}},
+		{5, 9, nil},
+		{0, 0, nil},
+	}
+	for _, test := range tests {
+		withdrawals, err := d.GetWithdrawalsByBlockRange(ctx, test.from, test.to, db.PaginationParam{Limit: 10})
+		require.Nil(t, err)
+		var numbers []uint64
+		for _, withdrawal := range withdrawals.Withdrawals {
+			numbers = append(numbers, withdrawal.L2BlockNumber)
+		}
+		require.Equal(t, test.expNumbers, numbers)
+		require.Equal(t, uint64(len(test.expNumbers)), withdrawals.Page.Total)
+	}
+
+	_, err := d.GetWithdrawalsByBlockRange(ctx, 3, 2, db.PaginationParam{Limit: 10})
+	require.True(t, errors.Is(err, db.ErrInvalidBlockRange))
+}
@@ -17,6 +17,9 @@ type Deposit struct {
 	Amount      *big.Int
 	Data        []byte
 	LogIndex    uint
+
+	// SourceAddress is the bridge contract that emitted the deposit.
+	SourceAddress common.Address
 }
 
 // String returns the tx hash for the deposit.
@@ -33,8 +36,16 @@ type DepositJSON struct {
 	L2Token        string `json:"l2Token"`
 	Amount         string `json:"amount"`
 	Data           []byte `json:"data"`
+	DataLength     uint64 `json:"dataLength"`
 	LogIndex       uint64 `json:"logIndex"`
 	BlockNumber    uint64 `json:"blockNumber"`
 	BlockTimestamp string `json:"blockTimestamp"`
 	TxHash         string `json:"transactionHash"`
+	SourceAddress  string `json:"sourceAddress,omitempty"`
+
+	ConfirmationStatus ConfirmationStatus `json:"confirmationStatus"`
+
+	// USDValue is the value of the deposit in USD, if a PriceProvider is
+	// configured and knows the price of the token.
+	USDValue *float64 `json:"usdValue,omitempty"`
 }
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedDialect signals that an operation relies on features of a
+// backend other than the one the Database is connected to.
+var ErrUnsupportedDialect = errors.New("operation not supported by the database backend")
+
+// dialect identifies the SQL backend a Database is connected to.
+//
+// Postgres is the default and the only backend suitable for production.
+// SQLite is meant for tests and small local deployments. It is selected by
+// prefixing the DSN with the name of a registered SQLite driver as scheme,
+// e.g. "sqlite3://file::memory:?cache=shared" for github.com/mattn/go-sqlite3
+// or "sqlite://indexer.db" for modernc.org/sqlite. This package does not
+// depend on any SQLite driver, so the caller must import one.
+//
+// Against SQLite, the following behaves differently:
+//   - Statements are written with Postgres' $N placeholders, which are
+//     rewritten to SQLite's equivalent ?N on the fly.
+//   - Amounts are stored as text, and compared and aggregated as NUMERIC,
+//     which SQLite stores as a 64-bit float beyond the range of a 64-bit
+//     integer. Amount filters, sorts and sums are therefore approximate for
+//     very large amounts.
+//   - ON CONFLICT requires SQLite 3.24, UPDATE ... FROM requires 3.33 and
+//     octet_length, which every listing, export and lookup of a single
+//     deposit or withdrawal selects, requires 3.43. SQLite 3.43 is therefore
+//     the minimum supported version. FULL OUTER JOINs, which require 3.39,
+//     are avoided.
+//   - Guids are stored as text rather than as UUIDs, and amount CHECK
+//     constraints use GLOB patterns rather than regular expressions.
+//   - Foreign keys are only enforced if enabled on the connection, in which
+//     case deposits and withdrawals must still reference known tokens.
+//     Foreign keys can always be deferred through BulkOptions.
+//   - An in-memory database only lives as long as its connections, so
+//     connections are never recycled, and only one is opened unless
+//     MaxOpenConns is set. Statements are not cached, as preparing them
+//     takes a connection of its own.
+//   - GetTableSizes, ReplicaLag, RefreshL1TokenMetadata,
+//     RefreshL2TokenMetadata, GetL1TokensByAddresses, GetL2TokensByAddresses,
+//     VacuumAnalyze and Explain rely on Postgres catalogs, arrays or
+//     commands and fail with ErrUnsupportedDialect.
+//     BulkOptions.AsynchronousCommit is ignored.
+type dialect int
+
+const (
+	dialectPostgres dialect = iota
+	dialectSQLite
+)
+
+// sqliteDriverNames are the DSN schemes selecting the SQLite dialect. Each is
+// also the name the corresponding driver registers itself under.
+var sqliteDriverNames = []string{"sqlite3", "sqlite"}
+
+// parseDSN returns the dialect selected by the scheme of dsn, along with the
+// name of the driver to open it with and the DSN to hand to that driver.
+// Connection strings without a SQLite scheme are passed to the postgres
+// driver unchanged.
+func parseDSN(dsn string) (dialect, string, string) {
+	for _, name := range sqliteDriverNames {
+		if rest := strings.TrimPrefix(dsn, name+"://"); rest != dsn {
+			return dialectSQLite, name, rest
+		}
+	}
+	return dialectPostgres, "postgres", dsn
+}
+
+// withStatementTimeout returns dsn set up for Postgres to cancel statements
+// running for longer than timeout. The setting is passed to lib/pq as a
+// run-time parameter, which it sends to the server when connecting. dsn is
+// returned unchanged if timeout is not set or if it selects SQLite.
+func withStatementTimeout(dsn string, timeout time.Duration) string {
+	if timeout <= 0 {
+		return dsn
+	}
+	if dialect, _, _ := parseDSN(dsn); dialect != dialectPostgres {
+		return dsn
+	}
+
+	// A timeout of zero disables it, so shorter timeouts are rounded up.
+	ms := timeout.Milliseconds()
+	if ms == 0 {
+		ms = 1
+	}
+	value := strconv.FormatInt(ms, 10)
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// Leave reporting the malformed DSN to the driver.
+			return dsn
+		}
+		query := u.Query()
+		query.Set("statement_timeout", value)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(dsn + " statement_timeout=" + value)
+}
+
+// redactedPassword replaces the passwords of redacted DSNs.
+const redactedPassword = "***"
+
+// keyValuePasswordRegexp matches the password setting of a key/value DSN,
+// whose value is either quoted, with backslash escapes, or runs up to the next
+// whitespace.
+var keyValuePasswordRegexp = regexp.MustCompile(`(^|\s)password\s*=\s*('(?:[^'\\]|\\.)*'|\S*)`)
+
+// redactDSN returns dsn with its password replaced by redactedPassword, in
+// either of the URL and key/value forms lib/pq accepts. A URL that cannot be
+// parsed is redacted entirely, as the password cannot be told apart.
+func redactDSN(dsn string) string {
+	if dialect, _, _ := parseDSN(dsn); dialect != dialectPostgres {
+		return dsn
+	}
+
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return keyValuePasswordRegexp.ReplaceAllString(dsn, "${1}password="+redactedPassword)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return redactedPassword
+	}
+	// The query is redacted by hand rather than re-encoded, which would
+	// escape the redacted password and reorder the parameters.
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		if key := strings.SplitN(param, "=", 2)[0]; key == "password" {
+			params[i] = "password=" + redactedPassword
+		}
+	}
+	u.RawQuery = strings.Join(params, "&")
+
+	// url.UserPassword would escape the redacted password, so the
+	// placeholder of URL.Redacted is swapped for it instead. The username is
+	// escaped, so the first match is the password.
+	return strings.Replace(u.Redacted(), ":xxxxx@", ":"+redactedPassword+"@", 1)
+}
+
+// openDB opens a connection pool for dsn using the driver selected by its
+// scheme.
+func openDB(dsn string) (*sql.DB, dialect, error) {
+	dialect, driverName, driverDSN := parseDSN(dsn)
+	db, err := sql.Open(driverName, driverDSN)
+	if err != nil {
+		return nil, dialect, err
+	}
+	if dialect == dialectPostgres {
+		return db, dialect, nil
+	}
+
+	// sql.Open only resolves the driver, so the pool can be swapped for one
+	// rewriting the placeholders without having connected yet.
+	connector := rebindConnector{driver: db.Driver(), dsn: driverDSN}
+	if err := db.Close(); err != nil {
+		return nil, dialect, err
+	}
+	return sql.OpenDB(connector), dialect, nil
+}
+
+// rebind rewrites the $N placeholders of a Postgres statement to the
+// equivalent ?N placeholders of SQLite. Quoted strings and identifiers are
+// left untouched.
+func rebind(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			c = '?'
+		}
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// rebindConnector opens connections of driver whose statements have their
+// placeholders rewritten by rebind.
+type rebindConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return rebindConn{conn}, nil
+}
+
+func (c rebindConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// rebindConn rewrites the placeholders of every statement before handing it
+// to the wrapped connection. Statements are executed directly when the
+// wrapped connection supports it, since SQLite drivers only prepare the first
+// statement of a multi-statement string.
+type rebindConn struct {
+	driver.Conn
+}
+
+func (c rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, rebind(query))
+	}
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c rebindConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return execer.ExecContext(ctx, rebind(query), args)
+}
+
+func (c rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return queryer.QueryContext(ctx, rebind(query), args)
+}
@@ -0,0 +1,50 @@
+//go:build dbtest
+// +build dbtest
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// droppedTables lists every table created by the migrations, with tables
+// referencing others listed before the tables they reference.
+var droppedTables = []string{
+	"deposits",
+	"withdrawals",
+	"pending_finalizations",
+	"bridged_balances",
+	"airdrops",
+	"airdrop_history",
+	"settings",
+	"l1_tokens",
+	"l2_tokens",
+	"l1_blocks",
+	"l2_blocks",
+	"schema_migrations",
+}
+
+// DropAllTables drops every table created by the migrations, including the
+// record of applied migrations, so that a test database can be torn down or
+// migrated again from scratch. It is only compiled with the dbtest build tag
+// so that it cannot be called from production code.
+func (d *Database) DropAllTables(ctx context.Context) error {
+	const dropTableStatement = `
+	DROP TABLE IF EXISTS %s
+	`
+
+	defer d.finalizedWithdrawals.purge()
+	defer d.l1Tokens.purge()
+	defer d.l2Tokens.purge()
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		for _, table := range droppedTables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(dropTableStatement, table)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+//go:build dbtest
+// +build dbtest
+
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestDropAllTables asserts that every table created by the migrations is
+// dropped, and that the database can be migrated again afterwards.
+func TestDropAllTables(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	conn := openConn(t, d)
+	defer conn.Close()
+
+	countTables := func() int {
+		var count int
+		err := conn.QueryRow(
+			"SELECT count(*) FROM information_schema.tables WHERE table_schema = 'public'",
+		).Scan(&count)
+		require.Nil(t, err)
+		return count
+	}
+	require.NotZero(t, countTables())
+
+	ctx := context.Background()
+	require.Nil(t, d.DropAllTables(ctx))
+	require.Zero(t, countTables())
+
+	version, err := d.CurrentSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Zero(t, version)
+
+	require.Nil(t, d.Migrate(ctx))
+	require.NotZero(t, countTables())
+}
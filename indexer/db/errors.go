@@ -0,0 +1,54 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrTokenNotFound signals that no token is indexed at the requested
+	// address.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrDepositNotFound signals that no deposit matching the request is
+	// indexed.
+	ErrDepositNotFound = errors.New("deposit not found")
+
+	// ErrWithdrawalNotFound signals that no withdrawal matching the request is
+	// indexed.
+	ErrWithdrawalNotFound = errors.New("withdrawal not found")
+
+	// ErrBlockNotFound signals that the requested block is not indexed.
+	ErrBlockNotFound = errors.New("block not found")
+
+	// ErrDuplicateBlock signals that a block with the same hash is already
+	// indexed, so that it can safely be skipped.
+	ErrDuplicateBlock = errors.New("duplicate block")
+
+	// ErrConflictingBlock signals that a different block is already indexed
+	// at the same number, i.e. that the chain was reorged since it was
+	// indexed. The block must go through the reorg path rather than be
+	// skipped, or its contents are lost.
+	ErrConflictingBlock = errors.New("conflicting block")
+
+	// ErrChainDiscontinuity signals that a block does not extend the indexed
+	// block preceding it, i.e. its parent hash is not the hash of the block
+	// indexed at the previous height.
+	ErrChainDiscontinuity = errors.New("chain discontinuity")
+)
+
+// uniqueViolation is the Postgres error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a unique constraint violation. The
+// SQLite driver is not known to this package, so its errors are matched by
+// message.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == uniqueViolation
+	}
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsUniqueViolation asserts that unique constraint violations are
+// recognized for both backends, even when wrapped, and that other errors are
+// not.
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		err error
+		exp bool
+	}{
+		{nil, false},
+		{errors.New("connection refused"), false},
+		{&pq.Error{Code: uniqueViolation}, true},
+		{fmt.Errorf("insert: %w", &pq.Error{Code: uniqueViolation}), true},
+		{&pq.Error{Code: "23503"}, false},
+		{errors.New("UNIQUE constraint failed: l1_blocks.hash"), true},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.exp, isUniqueViolation(test.err), fmt.Sprint(test.err))
+	}
+}
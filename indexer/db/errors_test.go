@@ -0,0 +1,126 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenNotFound asserts that looking up a token that is not indexed
+// returns ErrTokenNotFound.
+func TestTokenNotFound(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	_, err := d.GetL1TokenByAddress(ctx, "0xcc01")
+	require.True(t, errors.Is(err, db.ErrTokenNotFound))
+
+	_, err = d.GetL2TokenByAddress(ctx, "0xcc01")
+	require.True(t, errors.Is(err, db.ErrTokenNotFound))
+}
+
+// TestDuplicateBlock asserts that adding a block whose hash is already
+// indexed returns ErrDuplicateBlock, that adding a different block at an
+// indexed number returns ErrConflictingBlock instead, and that both leave the
+// stored block untouched.
+func TestDuplicateBlock(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	l1Block := &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+	}
+	require.Nil(t, d.AddIndexedL1Block(ctx, l1Block))
+
+	err := d.AddIndexedL1Block(ctx, l1Block)
+	require.True(t, errors.Is(err, db.ErrDuplicateBlock))
+	require.False(t, errors.Is(err, db.ErrConflictingBlock))
+
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  2,
+	})
+	require.True(t, errors.Is(err, db.ErrConflictingBlock))
+	require.False(t, errors.Is(err, db.ErrDuplicateBlock))
+
+	_, err = d.GetIndexedL1BlockByHash(ctx, common.HexToHash("0x02"), false)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+
+	l2Block := &db.IndexedL2Block{
+		Hash:       common.HexToHash("0x11"),
+		ParentHash: common.HexToHash("0x10"),
+		Number:     1,
+		Timestamp:  1,
+	}
+	require.Nil(t, d.AddIndexedL2Block(ctx, l2Block))
+
+	err = d.AddIndexedL2Block(ctx, l2Block)
+	require.True(t, errors.Is(err, db.ErrDuplicateBlock))
+	require.False(t, errors.Is(err, db.ErrConflictingBlock))
+
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x12"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   2,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee01"), 0)},
+	})
+	require.True(t, errors.Is(err, db.ErrConflictingBlock))
+	require.False(t, errors.Is(err, db.ErrDuplicateBlock))
+
+	withdrawals, err := d.GetWithdrawals(ctx, db.ActivityFilter{}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Zero(t, withdrawals.Page.Total)
+}
+
+// TestChainDiscontinuity asserts that a checked block whose parent hash does
+// not match the block indexed at the previous height returns
+// ErrChainDiscontinuity and is not inserted, while a block with no indexed
+// predecessor is inserted.
+func TestChainDiscontinuity(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	require.Nil(t, d.AddIndexedL1BlockChecked(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+	}))
+
+	err := d.AddIndexedL1BlockChecked(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0xff"),
+		Number:     2,
+		Timestamp:  2,
+	})
+	require.True(t, errors.Is(err, db.ErrChainDiscontinuity))
+
+	_, err = d.GetIndexedL1BlockByHash(ctx, common.HexToHash("0x02"), false)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+
+	require.Nil(t, d.AddIndexedL1BlockChecked(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x01"),
+		Number:     2,
+		Timestamp:  2,
+	}))
+}
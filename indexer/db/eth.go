@@ -18,7 +18,7 @@ var ETHL2Address = common.HexToAddress("0xDeadDeAddeAddEAddeadDEaDDEAdDeaDDeAD00
 // ETHL2Token is a placeholder token for differentiating ETH transactions from
 // ERC20 transactions on L2.
 var ETHL2Token = &Token{
-	Address:  "0xDeadDeAddeAddEAddeadDEaDDEAdDeaDDeAD0000",
+	Address:  "0xdeaddeaddeaddeaddeaddeaddeaddeaddead0000",
 	Name:     "Ethereum",
 	Symbol:   "ETH",
 	Decimals: 18,
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrExplainDisabled signals that Explain was called on a Database not
+	// configured with EnableExplain.
+	ErrExplainDisabled = errors.New("explain is disabled")
+
+	// ErrExplainUnsupported signals that Explain does not know the query of
+	// the requested method, or that it was given unexpected arguments.
+	ErrExplainUnsupported = errors.New("method cannot be explained")
+)
+
+// Explain runs the query of the given read method under EXPLAIN (ANALYZE,
+// BUFFERS) and returns the plan, one line per row, instead of its results.
+// The query is built exactly as the method builds it from args, so the plan
+// reflects the filters and page actually requested. The query is executed,
+// on a replica if any, so Explain takes as long as the method itself.
+//
+// The supported methods are GetDeposits and GetWithdrawals, which the other
+// deposit and withdrawal listings delegate to. Their args are an optional
+// ActivityFilter followed by an optional PaginationParam, in that order.
+// Explain fails with ErrExplainDisabled unless the Database is configured
+// with EnableExplain.
+func (d *Database) Explain(ctx context.Context, method string, args ...interface{}) (string, error) {
+	const explainStatement = `
+	EXPLAIN (ANALYZE, BUFFERS) %s
+	`
+
+	if !d.explain {
+		return "", ErrExplainDisabled
+	}
+	if d.dialect != dialectPostgres {
+		return "", ErrUnsupportedDialect
+	}
+
+	filter, page, err := explainArgs(method, args)
+	if err != nil {
+		return "", err
+	}
+
+	var statement string
+	var queryArgs []interface{}
+	switch method {
+	case "GetDeposits":
+		statement, queryArgs, err = d.depositsQuery(filter, &page)
+	case "GetWithdrawals":
+		statement, queryArgs, err = d.withdrawalsQuery(filter, &page)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrExplainUnsupported, method)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var plan []string
+	err = d.readTxn(ctx, func(tx *sql.Tx) error {
+		plan = nil
+
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(explainStatement, statement), queryArgs...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			plan = append(plan, line)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(plan, "\n"), nil
+}
+
+// explainArgs returns the filter and page given to Explain for method.
+func explainArgs(method string, args []interface{}) (ActivityFilter, PaginationParam, error) {
+	var filter ActivityFilter
+	var page PaginationParam
+	if len(args) > 2 {
+		return filter, page, fmt.Errorf("%w: %s takes at most 2 arguments, got %d",
+			ErrExplainUnsupported, method, len(args))
+	}
+	if len(args) > 0 {
+		f, ok := args[0].(ActivityFilter)
+		if !ok {
+			return filter, page, fmt.Errorf("%w: %s expects an ActivityFilter, got %T",
+				ErrExplainUnsupported, method, args[0])
+		}
+		filter = f
+	}
+	if len(args) > 1 {
+		p, ok := args[1].(PaginationParam)
+		if !ok {
+			return filter, page, fmt.Errorf("%w: %s expects a PaginationParam, got %T",
+				ErrExplainUnsupported, method, args[1])
+		}
+		page = p
+	}
+	return filter, page, nil
+}
@@ -0,0 +1,48 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExplain asserts that the plans of the listing queries are returned once
+// explaining is enabled, and that unknown methods and arguments are rejected.
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	_, err := d.Explain(ctx, "GetDeposits")
+	require.True(t, errors.Is(err, db.ErrExplainDisabled))
+
+	d, err = db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:           d.Config(),
+		EnableExplain: true,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	address := testFromAddress
+	plan, err := d.Explain(ctx, "GetDeposits", db.ActivityFilter{Address: &address}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Contains(t, plan, "Execution Time")
+
+	plan, err = d.Explain(ctx, "GetWithdrawals")
+	require.Nil(t, err)
+	require.Contains(t, plan, "Execution Time")
+
+	_, err = d.Explain(ctx, "GetDeposits", db.ActivityFilter{}, db.PaginationParam{Limit: 1000})
+	require.True(t, errors.Is(err, db.ErrPageLimitTooLarge))
+
+	_, err = d.Explain(ctx, "GetAirdrop")
+	require.True(t, errors.Is(err, db.ErrExplainUnsupported))
+
+	_, err = d.Explain(ctx, "GetDeposits", address)
+	require.True(t, errors.Is(err, db.ErrExplainUnsupported))
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// iterateDeposits calls fn for every deposit matching filter in the order
+// they were made, without holding the whole result set in memory. Deposits
+// invalidated by a reorg are skipped.
+func (d *Database) iterateDeposits(ctx context.Context, filter ActivityFilter, fn func(DepositJSON) error) error {
+	const selectDepositsStatement = `
+	SELECT
+		deposits.guid, deposits.from_address, deposits.to_address,
+		deposits.amount, deposits.tx_hash, deposits.data, octet_length(deposits.data),
+		deposits.l1_token, deposits.l2_token,
+		COALESCE(l1_tokens.name, ''), COALESCE(l1_tokens.symbol, ''), COALESCE(l1_tokens.decimals, 0),
+		deposits.log_index, l1_blocks.number, l1_blocks.timestamp,
+		deposits.source_address
+	FROM deposits
+		INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+		LEFT JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
+	WHERE %s AND deposits.reorged_at IS NULL
+	ORDER BY l1_blocks.number, deposits.log_index;
+	`
+
+	conditions, args := filter.conditions(depositTables, nil)
+	start := time.Now()
+	rows, err := d.reader().QueryContext(
+		ctx,
+		fmt.Sprintf(selectDepositsStatement, conditions),
+		args...,
+	)
+	d.logSlowQuery(start, args)
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		deposit, err := scanDepositRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(deposit); err != nil {
+			return err
+		}
+	}
+
+	return contextErr(ctx, rows.Err())
+}
+
+// iterateWithdrawals calls fn for every withdrawal matching filter in the
+// order they were made, without holding the whole result set in memory.
+func (d *Database) iterateWithdrawals(ctx context.Context, filter ActivityFilter, fn func(WithdrawalJSON) error) error {
+	const selectWithdrawalsStatement = `
+	SELECT
+		withdrawals.guid, withdrawals.from_address, withdrawals.to_address,
+		withdrawals.amount, withdrawals.tx_hash, withdrawals.data, octet_length(withdrawals.data),
+		withdrawals.l1_token, withdrawals.l2_token,
+		COALESCE(l2_tokens.name, ''), COALESCE(l2_tokens.symbol, ''), COALESCE(l2_tokens.decimals, 0),
+		withdrawals.log_index, l2_blocks.number, l2_blocks.timestamp,
+		NULL
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+		LEFT JOIN l2_tokens ON withdrawals.l2_token=l2_tokens.address
+	WHERE %s
+	ORDER BY l2_blocks.number, withdrawals.log_index;
+	`
+
+	conditions, args := filter.conditions(withdrawalTables, nil)
+	start := time.Now()
+	rows, err := d.reader().QueryContext(
+		ctx,
+		fmt.Sprintf(selectWithdrawalsStatement, conditions),
+		args...,
+	)
+	d.logSlowQuery(start, args)
+	if err != nil {
+		return contextErr(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		withdrawal, err := scanWithdrawalRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(withdrawal); err != nil {
+			return err
+		}
+	}
+
+	return contextErr(ctx, rows.Err())
+}
+
+// StreamDepositsByAddress calls fn for every deposit made by the given
+// address, in the order they were made. Rows are handed to fn as they are
+// scanned, so the history can be streamed to a client however long it is.
+// Iteration stops at the first error returned by fn, which is returned as is.
+// Deposits invalidated by a reorg are skipped.
+func (d *Database) StreamDepositsByAddress(ctx context.Context, address common.Address, fn func(DepositJSON) error) error {
+	return d.iterateDeposits(ctx, ActivityFilter{Address: &address}, fn)
+}
+
+// ExportDepositsNDJSON writes every deposit matching filter to w as
+// newline-delimited JSON, one deposit per line.
+func (d *Database) ExportDepositsNDJSON(ctx context.Context, filter ActivityFilter, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return d.iterateDeposits(ctx, filter, func(deposit DepositJSON) error {
+		return encoder.Encode(deposit)
+	})
+}
+
+// ExportWithdrawalsNDJSON writes every withdrawal matching filter to w as
+// newline-delimited JSON, one withdrawal per line.
+func (d *Database) ExportWithdrawalsNDJSON(ctx context.Context, filter ActivityFilter, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return d.iterateWithdrawals(ctx, filter, func(withdrawal WithdrawalJSON) error {
+		return encoder.Encode(withdrawal)
+	})
+}
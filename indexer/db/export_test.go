@@ -0,0 +1,149 @@
+package db_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// requireNDJSONLines asserts that every line of buf is a valid JSON object and
+// returns the number of lines.
+func requireNDJSONLines(t *testing.T, buf *bytes.Buffer) int {
+	var lines int
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var item map[string]interface{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &item))
+		lines++
+	}
+	require.Nil(t, scanner.Err())
+	return lines
+}
+
+// TestExportDepositsNDJSON asserts that one valid JSON line is written per
+// deposit matching the filter.
+func TestExportDepositsNDJSON(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	other := newTestDeposit(common.HexToHash("0xff03"), 0)
+	other.FromAddress = common.HexToAddress("0xaa03")
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xff01"), 0),
+			newTestDeposit(common.HexToHash("0xff02"), 1),
+		},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x01"),
+		Number:     2,
+		Timestamp:  2,
+		Deposits:   []db.Deposit{other},
+	})
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = d.ExportDepositsNDJSON(context.Background(), db.ActivityFilter{}, &buf)
+	require.Nil(t, err)
+	require.Equal(t, 3, requireNDJSONLines(t, &buf))
+
+	buf.Reset()
+	err = d.ExportDepositsNDJSON(context.Background(), db.ActivityFilter{
+		Address: &testFromAddress,
+	}, &buf)
+	require.Nil(t, err)
+	require.Equal(t, 2, requireNDJSONLines(t, &buf))
+
+	buf.Reset()
+	err = d.ExportDepositsNDJSON(context.Background(), db.ActivityFilter{
+		FromBlock: 2,
+		ToBlock:   2,
+	}, &buf)
+	require.Nil(t, err)
+	require.Equal(t, 1, requireNDJSONLines(t, &buf))
+}
+
+// TestStreamDepositsByAddress asserts that every deposit of the address is
+// streamed in order, and that an error returned by the callback stops the
+// stream.
+func TestStreamDepositsByAddress(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	other := newTestDeposit(common.HexToHash("0xff03"), 2)
+	other.FromAddress = common.HexToAddress("0xaa03")
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xff01"), 0),
+			newTestDeposit(common.HexToHash("0xff02"), 1),
+			other,
+		},
+	})
+	require.Nil(t, err)
+
+	var logIndexes []uint64
+	err = d.StreamDepositsByAddress(context.Background(), testFromAddress, func(deposit db.DepositJSON) error {
+		logIndexes = append(logIndexes, deposit.LogIndex)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []uint64{0, 1}, logIndexes)
+
+	errStop := errors.New("stop")
+	var streamed int
+	err = d.StreamDepositsByAddress(context.Background(), testFromAddress, func(deposit db.DepositJSON) error {
+		streamed++
+		return errStop
+	})
+	require.True(t, errors.Is(err, errStop))
+	require.Equal(t, 1, streamed)
+}
+
+// TestExportWithdrawalsNDJSON asserts that one valid JSON line is written per
+// withdrawal.
+func TestExportWithdrawalsNDJSON(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	err := d.AddIndexedL2Block(context.Background(), &db.IndexedL2Block{
+		Hash:       common.HexToHash("0x11"),
+		ParentHash: common.HexToHash("0x10"),
+		Number:     1,
+		Timestamp:  1,
+		Withdrawals: []db.Withdrawal{
+			newTestWithdrawal(common.HexToHash("0xee01"), 0),
+			newTestWithdrawal(common.HexToHash("0xee02"), 1),
+		},
+	})
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = d.ExportWithdrawalsNDJSON(context.Background(), db.ActivityFilter{}, &buf)
+	require.Nil(t, err)
+	require.Equal(t, 2, requireNDJSONLines(t, &buf))
+}
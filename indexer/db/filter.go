@@ -0,0 +1,154 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrInvalidBlockRange signals that a block range ends before it starts.
+var ErrInvalidBlockRange = errors.New("invalid block range")
+
+// ActivityFilter narrows down the deposits or withdrawals a query returns, and
+// sets the order listings return them in. It is shared by both listings so
+// that every filter applies to both, except for the fields documented as
+// matching only deposits or only withdrawals, which the other listing ignores.
+// Zero-valued fields do not filter. Exports only apply the fields up to
+// ToTimestamp.
+type ActivityFilter struct {
+	// Address only matches activity initiated by this address.
+	Address *common.Address
+
+	// ToAddress only matches activity received by this address.
+	ToAddress *common.Address
+
+	// AnyAddress only matches activity initiated or received by this
+	// address.
+	AnyAddress *common.Address
+
+	// Token only matches activity of this L1 token.
+	Token *common.Address
+
+	// MinAmount and MaxAmount bound, inclusively, the amount bridged.
+	MinAmount *big.Int
+	MaxAmount *big.Int
+
+	// FromBlock and ToBlock bound, inclusively, the number of the block the
+	// activity was initiated in, i.e. the L1 block of a deposit and the L2
+	// block of a withdrawal. ToBlock is unbounded when zero.
+	FromBlock uint64
+	ToBlock   uint64
+
+	// FromTimestamp and ToTimestamp bound, inclusively, the timestamp of the
+	// block the activity was initiated in. ToTimestamp is unbounded when zero.
+	FromTimestamp uint64
+	ToTimestamp   uint64
+
+	// IncludeReorged also matches deposits that were invalidated by a
+	// reorg. It is meant for audit tooling and is never set by the REST
+	// middleware.
+	IncludeReorged bool
+
+	// IncludeUnverified also matches deposits of tokens that have not been
+	// marked as verified, including tokens whose metadata is not indexed.
+	IncludeUnverified bool
+
+	// SourceAddress only matches deposits made through the bridge contract
+	// at this address, such as the standard bridge.
+	SourceAddress *common.Address
+
+	// WithdrawalStatus only matches withdrawals with the given finalization
+	// status.
+	WithdrawalStatus WithdrawalStatus
+
+	// SortBy selects the column listings are ordered by. They are ordered by
+	// timestamp when empty.
+	SortBy SortBy
+
+	// SortDir selects the direction listings are ordered in. They are
+	// ordered ascending when empty.
+	SortDir SortDir
+}
+
+// activityTables names the tables an ActivityFilter is applied to.
+type activityTables struct {
+	// activity is the table holding the deposits or withdrawals.
+	activity string
+
+	// blocks is the table holding the blocks the activity was initiated in.
+	blocks string
+}
+
+var (
+	depositTables    = activityTables{activity: "deposits", blocks: "l1_blocks"}
+	withdrawalTables = activityTables{activity: "withdrawals", blocks: "l2_blocks"}
+)
+
+// conditions returns the SQL condition matching f on the given tables, along
+// with args extended by the values it binds. Its parameters are numbered after
+// the args already bound by the enclosing statement.
+func (f ActivityFilter) conditions(tables activityTables, args []interface{}) (string, []interface{}) {
+	var conditions []string
+	bind := func(column, operator string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions,
+			fmt.Sprintf("%s %s $%d", column, operator, len(args)))
+	}
+
+	activity := func(column string) string {
+		return tables.activity + "." + column
+	}
+	blocks := func(column string) string {
+		return tables.blocks + "." + column
+	}
+
+	if f.Address != nil {
+		bind(activity("from_address"), "=", addressString(*f.Address))
+	}
+	if f.ToAddress != nil {
+		bind(activity("to_address"), "=", addressString(*f.ToAddress))
+	}
+	if f.AnyAddress != nil {
+		args = append(args, addressString(*f.AnyAddress))
+		conditions = append(conditions, fmt.Sprintf("(%s = $%d OR %s = $%[2]d)",
+			activity("from_address"), len(args), activity("to_address")))
+	}
+	if f.Token != nil {
+		bind(activity("l1_token"), "=", addressString(*f.Token))
+	}
+	if f.MinAmount != nil {
+		bind("CAST("+activity("amount")+" AS NUMERIC)", ">=", f.MinAmount.String())
+	}
+	if f.MaxAmount != nil {
+		bind("CAST("+activity("amount")+" AS NUMERIC)", "<=", f.MaxAmount.String())
+	}
+	if f.FromBlock != 0 {
+		bind(blocks("number"), ">=", f.FromBlock)
+	}
+	if f.ToBlock != 0 {
+		bind(blocks("number"), "<=", f.ToBlock)
+	}
+	if f.FromTimestamp != 0 {
+		bind(blocks("timestamp"), ">=", f.FromTimestamp)
+	}
+	if f.ToTimestamp != 0 {
+		bind(blocks("timestamp"), "<=", f.ToTimestamp)
+	}
+
+	if len(conditions) == 0 {
+		return "true", args
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// sourceAddress returns the canonical form of f.SourceAddress, or an empty
+// string, which matches every bridge, when it is not set.
+func (f ActivityFilter) sourceAddress() string {
+	if f.SourceAddress == nil {
+		return ""
+	}
+	return addressString(*f.SourceAddress)
+}
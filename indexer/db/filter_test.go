@@ -0,0 +1,123 @@
+package db_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestActivityFilter asserts that the same filter selects the same activity
+// from both the deposit and the withdrawal listings.
+func TestActivityFilter(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	token := common.HexToAddress("0xcc01")
+	otherAddress := common.HexToAddress("0xaa03")
+
+	err := d.AddL1Token(ctx, token.String(), &db.Token{
+		Address:  token.String(),
+		Name:     "Token",
+		Symbol:   "TKN",
+		Decimals: 18,
+	})
+	require.Nil(t, err)
+
+	// The same activity is indexed as deposits and as withdrawals, in blocks
+	// with the same numbers and timestamps on both layers.
+	type activity struct {
+		blockNumber uint64
+		from        common.Address
+		token       common.Address
+		amount      int64
+	}
+	activities := []activity{
+		{1, testFromAddress, common.HexToAddress(db.ETHL1Token.Address), 1},
+		{2, testFromAddress, common.HexToAddress(db.ETHL1Token.Address), 5},
+		{2, otherAddress, token, 10},
+	}
+
+	for number := uint64(1); number <= 2; number++ {
+		var deposits []db.Deposit
+		var withdrawals []db.Withdrawal
+		for i, a := range activities {
+			if a.blockNumber != number {
+				continue
+			}
+			deposit := newTestDeposit(common.BigToHash(big.NewInt(int64(i+1))), uint(i))
+			deposit.FromAddress = a.from
+			deposit.L1Token = a.token
+			deposit.Amount = big.NewInt(a.amount)
+			deposits = append(deposits, deposit)
+
+			withdrawal := newTestWithdrawal(common.BigToHash(big.NewInt(int64(i+1))), uint(i))
+			withdrawal.FromAddress = a.from
+			withdrawal.L1Token = a.token
+			withdrawal.Amount = big.NewInt(a.amount)
+			withdrawals = append(withdrawals, withdrawal)
+		}
+
+		err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number * 100,
+			Deposits:   deposits,
+		})
+		require.Nil(t, err)
+		err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+			Hash:        common.BigToHash(big.NewInt(int64(number))),
+			ParentHash:  common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:      number,
+			Timestamp:   number * 100,
+			Withdrawals: withdrawals,
+		})
+		require.Nil(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   db.ActivityFilter
+		expCount int
+	}{
+		{"none", db.ActivityFilter{}, 3},
+		{"address", db.ActivityFilter{Address: &testFromAddress}, 2},
+		{"to address", db.ActivityFilter{ToAddress: &testToAddress}, 3},
+		{"any address sender", db.ActivityFilter{AnyAddress: &otherAddress}, 1},
+		{"any address recipient", db.ActivityFilter{AnyAddress: &testToAddress}, 3},
+		{"token", db.ActivityFilter{Token: &token}, 1},
+		{"min amount", db.ActivityFilter{MinAmount: big.NewInt(5)}, 2},
+		{"max amount", db.ActivityFilter{MaxAmount: big.NewInt(4)}, 1},
+		{"from block", db.ActivityFilter{FromBlock: 2}, 2},
+		{"to block", db.ActivityFilter{ToBlock: 1}, 1},
+		{"from timestamp", db.ActivityFilter{FromTimestamp: 150}, 2},
+		{"to timestamp", db.ActivityFilter{ToTimestamp: 150}, 1},
+		{"timestamp range", db.ActivityFilter{FromTimestamp: 100, ToTimestamp: 200}, 3},
+		{"empty timestamp range", db.ActivityFilter{FromTimestamp: 101, ToTimestamp: 199}, 0},
+		{"combined", db.ActivityFilter{Address: &testFromAddress, FromBlock: 2}, 1},
+	}
+
+	page := db.PaginationParam{Limit: 10}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := test.filter
+			filter.IncludeUnverified = true
+			deposits, err := d.GetDeposits(ctx, filter, page)
+			require.Nil(t, err)
+			require.Len(t, deposits.Deposits, test.expCount)
+			require.Equal(t, uint64(test.expCount), deposits.Page.Total)
+
+			withdrawals, err := d.GetWithdrawals(ctx, filter, page)
+			require.Nil(t, err)
+			require.Len(t, withdrawals.Withdrawals, test.expCount)
+			require.Equal(t, uint64(test.expCount), withdrawals.Page.Total)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultFinalizationPeriodSeconds is the finalization period assumed when the
+// Database is not configured with FinalizationPeriodSeconds. It matches the
+// seven day fault proof window of mainnet.
+const DefaultFinalizationPeriodSeconds = 7 * 24 * 60 * 60
+
+// WithdrawalStatusChange describes a withdrawal that transitioned to a new
+// status.
+type WithdrawalStatusChange struct {
+	GUID        string
+	TxHash      common.Hash
+	LogIndex    uint
+	Status      WithdrawalStatus
+	L1BlockHash common.Hash
+}
+
+// MarkWithdrawalFinalized links the withdrawals of the given L2 transaction
+// that are still pending to the L1 block they were finalized in. Once the
+// change has committed, the configured OnWithdrawalStatusChange callback is
+// called for every withdrawal that transitioned. Nothing is reported if the
+// transaction fails, e.g. because the L1 block is not indexed.
+//
+// The L2 and L1 indexers run independently, so a withdrawal may be finalized
+// before its L2 block is indexed. Such a finalization is recorded as pending
+// and applied, and reported, by AddIndexedL2Block once the withdrawals of the
+// transaction are indexed. Pending finalizations are dropped along with their
+// L1 block when it is rolled back.
+func (d *Database) MarkWithdrawalFinalized(ctx context.Context, txHash, l1BlockHash common.Hash) error {
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		var err error
+		changes, err = finalizeWithdrawals(ctx, tx, txHash, l1BlockHash)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// AddIndexedL1BlockAndFinalizeWithdrawals inserts the indexed block like
+// AddIndexedL1Block and links to it the pending withdrawals of the given L2
+// transactions like MarkWithdrawalFinalized, in a single transaction. A crash
+// can therefore not leave the block indexed without the withdrawals it
+// finalized: either both are written or neither is. Status changes are
+// reported once the transaction has committed.
+func (d *Database) AddIndexedL1BlockAndFinalizeWithdrawals(ctx context.Context, block *IndexedL1Block, txHashes []common.Hash) error {
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		var err error
+		changes, err = d.addIndexedL1Block(ctx, tx, block)
+		if err != nil {
+			return err
+		}
+
+		for _, txHash := range txHashes {
+			finalized, err := finalizeWithdrawals(ctx, tx, txHash, block.Hash)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, finalized...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// finalizeWithdrawals links within tx the pending withdrawals of the given L2
+// transaction to the L1 block they were finalized in, and returns their status
+// changes. If the transaction holds no indexed withdrawal yet, the
+// finalization is recorded as pending instead, see MarkWithdrawalFinalized.
+func finalizeWithdrawals(ctx context.Context, tx *sql.Tx, txHash, l1BlockHash common.Hash) ([]WithdrawalStatusChange, error) {
+	const finalizeWithdrawalsStatement = `
+	UPDATE withdrawals SET l1_block_hash = $2
+	WHERE tx_hash = $1 AND l1_block_hash IS NULL
+	RETURNING guid, log_index;
+	`
+
+	const selectWithdrawalExistsStatement = `
+	SELECT EXISTS (SELECT 1 FROM withdrawals WHERE tx_hash = $1);
+	`
+
+	const upsertPendingFinalizationStatement = `
+	INSERT INTO pending_finalizations
+		(tx_hash, l1_block_hash)
+	VALUES
+		($1, $2)
+	ON CONFLICT (tx_hash) DO UPDATE SET l1_block_hash = EXCLUDED.l1_block_hash;
+	`
+
+	rows, err := tx.QueryContext(ctx, finalizeWithdrawalsStatement,
+		txHash.String(), l1BlockHash.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []WithdrawalStatusChange
+	for rows.Next() {
+		change := WithdrawalStatusChange{
+			TxHash:      txHash,
+			Status:      WithdrawalStatusFinalized,
+			L1BlockHash: l1BlockHash,
+		}
+		if err := rows.Scan(&change.GUID, &change.LogIndex); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		return changes, nil
+	}
+
+	// Withdrawals that are indexed but were not updated were finalized
+	// already, so only a transaction without any is recorded.
+	var exists bool
+	err = tx.QueryRowContext(ctx, selectWithdrawalExistsStatement, txHash.String()).Scan(&exists)
+	if err != nil || exists {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, upsertPendingFinalizationStatement,
+		txHash.String(), l1BlockHash.String())
+	return nil, err
+}
+
+// applyPendingFinalization finalizes within tx the withdrawals of the given
+// L2 transaction if a finalization was recorded as pending for it, see
+// MarkWithdrawalFinalized, and returns their status changes.
+func applyPendingFinalization(ctx context.Context, tx *sql.Tx, txHash common.Hash) ([]WithdrawalStatusChange, error) {
+	const deletePendingFinalizationStatement = `
+	DELETE FROM pending_finalizations WHERE tx_hash = $1
+	RETURNING l1_block_hash;
+	`
+
+	var l1BlockHash string
+	err := tx.QueryRowContext(ctx, deletePendingFinalizationStatement, txHash.String()).Scan(&l1BlockHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return finalizeWithdrawals(ctx, tx, txHash, common.HexToHash(l1BlockHash))
+}
+
+// notifyWithdrawalStatusChanges passes committed status changes to the
+// configured callback, if any.
+func (d *Database) notifyWithdrawalStatusChanges(changes []WithdrawalStatusChange) {
+	if d.onWithdrawalStatusChange == nil {
+		return
+	}
+	for _, change := range changes {
+		d.onWithdrawalStatusChange(change)
+	}
+}
+
+// GetOverdueWithdrawals returns the withdrawals that are still not finalized
+// although they became finalizable more than overdueSeconds before now, a
+// unix timestamp in seconds. A withdrawal becomes finalizable once the
+// configured finalization period has passed since its L2 block. Such
+// withdrawals hint at a failing relayer and funds stuck on the bridge. They
+// are ordered by ascending timestamp, i.e. the most overdue first.
+func (d *Database) GetOverdueWithdrawals(ctx context.Context, now, overdueSeconds uint64, page PaginationParam) (*PaginatedWithdrawals, error) {
+	// A withdrawal is overdue if its L2 block is strictly older than the
+	// cutoff. As the filter bound is inclusive, it is one second earlier.
+	wait := d.finalizationPeriodSeconds + overdueSeconds
+	if now <= wait+1 {
+		return &PaginatedWithdrawals{Page: newPageInfo(page)}, nil
+	}
+
+	return d.GetWithdrawals(ctx, ActivityFilter{
+		ToTimestamp:      now - wait - 1,
+		WithdrawalStatus: WithdrawalStatusPending,
+	}, page)
+}
+
+// GetWithdrawalsPendingFinalization returns up to limit withdrawals that are
+// not linked to an L1 block yet, oldest L2 block first, e.g. as the batch a
+// finalization worker processes each cycle. Listings may be served by a
+// lagging replica, so a batch may hold withdrawals that were just finalized,
+// which MarkWithdrawalFinalized leaves untouched. See GetLatestDeposits for
+// the bounds of limit.
+func (d *Database) GetWithdrawalsPendingFinalization(ctx context.Context, limit uint64) ([]WithdrawalJSON, error) {
+	withdrawals, err := d.GetWithdrawals(ctx, ActivityFilter{
+		WithdrawalStatus: WithdrawalStatusPending,
+		SortBy:           SortByBlockNumber,
+		SortDir:          SortAsc,
+	}, PaginationParam{
+		Limit:     limit,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return withdrawals.Withdrawals, nil
+}
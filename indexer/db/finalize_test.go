@@ -0,0 +1,369 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMarkWithdrawalFinalized asserts that the status change callback fires
+// with the finalized status once MarkWithdrawalFinalized commits, and that it
+// does not fire when the transaction is rolled back or nothing changed.
+func TestMarkWithdrawalFinalized(t *testing.T) {
+	t.Parallel()
+
+	var changes []db.WithdrawalStatusChange
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN: newTestDSN(t),
+		OnWithdrawalStatusChange: func(change db.WithdrawalStatusChange) {
+			changes = append(changes, change)
+		},
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 3)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	// The L1 block is not indexed yet, so the update violates the foreign
+	// key and is rolled back.
+	err = d.MarkWithdrawalFinalized(ctx, withdrawal.TxHash, common.HexToHash("0x01"))
+	require.NotNil(t, err)
+	require.Empty(t, changes)
+
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  2,
+	})
+	require.Nil(t, err)
+
+	err = d.MarkWithdrawalFinalized(ctx, withdrawal.TxHash, common.HexToHash("0x01"))
+	require.Nil(t, err)
+	require.Len(t, changes, 1)
+	require.NotEmpty(t, changes[0].GUID)
+	require.Equal(t, withdrawal.TxHash, changes[0].TxHash)
+	require.Equal(t, withdrawal.LogIndex, changes[0].LogIndex)
+	require.Equal(t, db.WithdrawalStatusFinalized, changes[0].Status)
+	require.Equal(t, common.HexToHash("0x01"), changes[0].L1BlockHash)
+
+	err = d.MarkWithdrawalFinalized(ctx, withdrawal.TxHash, common.HexToHash("0x01"))
+	require.Nil(t, err)
+	require.Len(t, changes, 1)
+}
+
+// TestMarkWithdrawalFinalizedBeforeIndexed asserts that a withdrawal finalized
+// before its L2 block is indexed is finalized, and reported, once the block is
+// indexed, unless the L1 block it was finalized in is rolled back first.
+func TestMarkWithdrawalFinalizedBeforeIndexed(t *testing.T) {
+	t.Parallel()
+
+	var changes []db.WithdrawalStatusChange
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN: newTestDSN(t),
+		OnWithdrawalStatusChange: func(change db.WithdrawalStatusChange) {
+			changes = append(changes, change)
+		},
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	for _, block := range []*db.IndexedL1Block{
+		{Hash: common.HexToHash("0x01"), ParentHash: common.HexToHash("0x00"), Number: 1, Timestamp: 2},
+		{Hash: common.HexToHash("0x02"), ParentHash: common.HexToHash("0x01"), Number: 2, Timestamp: 3},
+	} {
+		require.Nil(t, d.AddIndexedL1Block(ctx, block))
+	}
+
+	finalized := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	err = d.MarkWithdrawalFinalized(ctx, finalized.TxHash, common.HexToHash("0x01"))
+	require.Nil(t, err)
+	rolledBack := newTestWithdrawal(common.HexToHash("0xee02"), 0)
+	err = d.MarkWithdrawalFinalized(ctx, rolledBack.TxHash, common.HexToHash("0x02"))
+	require.Nil(t, err)
+	require.Empty(t, changes)
+
+	err = d.DeleteL1BlocksFrom(ctx, 2)
+	require.Nil(t, err)
+
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{finalized, rolledBack},
+	})
+	require.Nil(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, finalized.TxHash, changes[0].TxHash)
+	require.Equal(t, common.HexToHash("0x01"), changes[0].L1BlockHash)
+
+	status, err := d.GetWithdrawalStatus(ctx, finalized.TxHash)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), *status.L1BlockNumber)
+
+	status, err = d.GetWithdrawalStatus(ctx, rolledBack.TxHash)
+	require.Nil(t, err)
+	require.Nil(t, status.L1BlockNumber)
+
+	// Rolling back the L2 block keeps the finalization for when the
+	// withdrawal is indexed again.
+	err = d.DeleteL2BlocksFrom(ctx, 1)
+	require.Nil(t, err)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x12"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{finalized},
+	})
+	require.Nil(t, err)
+	require.Len(t, changes, 2)
+
+	status, err = d.GetWithdrawalStatus(ctx, finalized.TxHash)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), *status.L1BlockNumber)
+}
+
+// TestGetOverdueWithdrawals asserts that only pending withdrawals that became
+// finalizable more than the overdue threshold ago are returned.
+func TestGetOverdueWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:                       newTestDSN(t),
+		FinalizationPeriodSeconds: 100,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	overdue := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1000,
+		Withdrawals: []db.Withdrawal{overdue},
+	})
+	require.Nil(t, err)
+	recent := newTestWithdrawal(common.HexToHash("0xee02"), 0)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x12"),
+		ParentHash:  common.HexToHash("0x11"),
+		Number:      2,
+		Timestamp:   1900,
+		Withdrawals: []db.Withdrawal{recent},
+	})
+	require.Nil(t, err)
+
+	// The first withdrawal became finalizable at 1100 and the second at
+	// 2000, so at 2050 only the first is more than 60 seconds overdue.
+	withdrawals, err := d.GetOverdueWithdrawals(ctx, 2050, 60, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), withdrawals.Page.Total)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	require.Equal(t, overdue.TxHash.String(), withdrawals.Withdrawals[0].TxHash)
+
+	withdrawals, err = d.GetOverdueWithdrawals(ctx, 100, 60, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Empty(t, withdrawals.Withdrawals)
+}
+
+// TestAddIndexedL1BlockAndFinalizeWithdrawals asserts that the block and the
+// withdrawals it finalized are committed together, and that neither is when
+// the block cannot be inserted.
+func TestAddIndexedL1BlockAndFinalizeWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	var changes []db.WithdrawalStatusChange
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN: newTestDSN(t),
+		OnWithdrawalStatusChange: func(change db.WithdrawalStatusChange) {
+			changes = append(changes, change)
+		},
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	block := &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  2,
+	}
+	require.Nil(t, d.AddIndexedL1Block(ctx, block))
+
+	// The block is already indexed, so the withdrawal stays pending.
+	err = d.AddIndexedL1BlockAndFinalizeWithdrawals(ctx, block, []common.Hash{withdrawal.TxHash})
+	require.True(t, errors.Is(err, db.ErrDuplicateBlock))
+	require.Empty(t, changes)
+
+	status, err := d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.Nil(t, status.L1BlockNumber)
+
+	err = d.AddIndexedL1BlockAndFinalizeWithdrawals(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x02"),
+		ParentHash: common.HexToHash("0x01"),
+		Number:     2,
+		Timestamp:  3,
+	}, []common.Hash{withdrawal.TxHash})
+	require.Nil(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, common.HexToHash("0x02"), changes[0].L1BlockHash)
+
+	status, err = d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), *status.L1BlockNumber)
+}
+
+// TestAddIndexedL1BlockWithdrawals asserts that the withdrawals of an indexed
+// L1 block are linked to it whether or not the block holds deposits.
+func TestAddIndexedL1BlockWithdrawals(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	first := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	second := newTestWithdrawal(common.HexToHash("0xee02"), 0)
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{first, second},
+	})
+	require.Nil(t, err)
+
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:        common.HexToHash("0x01"),
+		ParentHash:  common.HexToHash("0x00"),
+		Number:      1,
+		Timestamp:   2,
+		Withdrawals: []db.Withdrawal{{TxHash: first.TxHash}},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:        common.HexToHash("0x02"),
+		ParentHash:  common.HexToHash("0x01"),
+		Number:      2,
+		Timestamp:   3,
+		Deposits:    []db.Deposit{newTestDeposit(common.HexToHash("0xff01"), 0)},
+		Withdrawals: []db.Withdrawal{{TxHash: second.TxHash}},
+	})
+	require.Nil(t, err)
+
+	for i, withdrawal := range []db.Withdrawal{first, second} {
+		status, err := d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+		require.Nil(t, err)
+		require.NotNil(t, status.L1BlockNumber)
+		require.Equal(t, uint64(i+1), *status.L1BlockNumber)
+	}
+}
+
+// TestGetHighestFinalizedL2Block asserts that the highest L2 block holding a
+// finalized withdrawal is returned, and nil while none is finalized.
+func TestGetHighestFinalizedL2Block(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	var withdrawals []db.Withdrawal
+	for number := uint64(1); number <= 3; number++ {
+		withdrawal := newTestWithdrawal(common.BigToHash(new(big.Int).SetUint64(0xee00+number)), 0)
+		withdrawals = append(withdrawals, withdrawal)
+		err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+			Hash:        common.BigToHash(new(big.Int).SetUint64(0x10 + number)),
+			ParentHash:  common.BigToHash(new(big.Int).SetUint64(0x10 + number - 1)),
+			Number:      number,
+			Timestamp:   number,
+			Withdrawals: []db.Withdrawal{withdrawal},
+		})
+		require.Nil(t, err)
+	}
+
+	block, err := d.GetHighestFinalizedL2Block(ctx)
+	require.Nil(t, err)
+	require.Nil(t, block)
+
+	err = d.AddIndexedL1BlockAndFinalizeWithdrawals(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  4,
+	}, []common.Hash{withdrawals[0].TxHash, withdrawals[1].TxHash})
+	require.Nil(t, err)
+
+	block, err = d.GetHighestFinalizedL2Block(ctx)
+	require.Nil(t, err)
+	require.Equal(t, &db.BlockLocator{Number: 2, Hash: common.HexToHash("0x12")}, block)
+}
+
+// TestGetWithdrawalsPendingFinalization asserts that only withdrawals that
+// are not finalized are returned, oldest first and up to the limit.
+func TestGetWithdrawalsPendingFinalization(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	var withdrawals []db.Withdrawal
+	for number := uint64(1); number <= 4; number++ {
+		withdrawal := newTestWithdrawal(common.BigToHash(new(big.Int).SetUint64(0xee00+number)), 0)
+		withdrawals = append(withdrawals, withdrawal)
+		err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+			Hash:        common.BigToHash(new(big.Int).SetUint64(0x10 + number)),
+			ParentHash:  common.BigToHash(new(big.Int).SetUint64(0x10 + number - 1)),
+			Number:      number,
+			Timestamp:   number,
+			Withdrawals: []db.Withdrawal{withdrawal},
+		})
+		require.Nil(t, err)
+	}
+
+	err := d.AddIndexedL1BlockAndFinalizeWithdrawals(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  5,
+	}, []common.Hash{withdrawals[1].TxHash})
+	require.Nil(t, err)
+
+	pending, err := d.GetWithdrawalsPendingFinalization(ctx, 2)
+	require.Nil(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, uint64(1), pending[0].L2BlockNumber)
+	require.Equal(t, uint64(3), pending[1].L2BlockNumber)
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrInvalidBucket signals that a time series was requested with a bucket
+// width other than hour, day or week.
+var ErrInvalidBucket = errors.New("invalid bucket")
+
+// ErrInvalidTimeRange signals that a time series was requested over a range
+// that ends before it starts or that spans more days than are served.
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
+// bucketSeconds maps the supported bucket names to their width in seconds.
+// Buckets are aligned to the unix epoch, so weeks start on Thursdays.
+var bucketSeconds = map[string]uint64{
+	"hour": 60 * 60,
+	"day":  24 * 60 * 60,
+	"week": 7 * 24 * 60 * 60,
+}
+
+// NetFlowBucket is the volume of a token bridged during a time bucket.
+type NetFlowBucket struct {
+	// Timestamp is the unix timestamp at which the bucket starts.
+	Timestamp uint64 `json:"timestamp"`
+	// Token is the address of the L1 token.
+	Token string `json:"token"`
+
+	DepositVolume    *big.Int `json:"depositVolume"`
+	WithdrawalVolume *big.Int `json:"withdrawalVolume"`
+	// NetFlow is the deposit volume minus the withdrawal volume, i.e. the
+	// amount of the token that moved onto L2 during the bucket. It is
+	// negative when more was withdrawn than deposited.
+	NetFlow *big.Int `json:"netFlow"`
+}
+
+// GetNetFlowSeries returns, per token, the volume deposited and withdrawn in
+// each bucket of the given width between the start and end timestamps,
+// inclusive. Deposits are bucketed by the timestamp of their L1 block and
+// withdrawals by that of their L2 block. Buckets in which a token was neither
+// deposited nor withdrawn are omitted. The series is ordered by bucket, then
+// token. Deposits invalidated by a reorg are excluded.
+func (d *Database) GetNetFlowSeries(ctx context.Context, start, end uint64, bucket string) ([]NetFlowBucket, error) {
+	const selectNetFlowSeriesStatement = `
+	WITH deposit_volumes AS (
+		SELECT
+			l1_blocks.timestamp - l1_blocks.timestamp % $3 AS bucket,
+			deposits.l1_token AS token,
+			SUM(CAST(deposits.amount AS NUMERIC)) AS volume
+		FROM deposits
+			INNER JOIN l1_blocks ON deposits.l1_block_hash = l1_blocks.hash
+		WHERE l1_blocks.timestamp >= $1 AND l1_blocks.timestamp <= $2
+			AND deposits.reorged_at IS NULL
+		GROUP BY 1, 2
+	), withdrawal_volumes AS (
+		SELECT
+			l2_blocks.timestamp - l2_blocks.timestamp % $3 AS bucket,
+			withdrawals.l1_token AS token,
+			SUM(CAST(withdrawals.amount AS NUMERIC)) AS volume
+		FROM withdrawals
+			INNER JOIN l2_blocks ON withdrawals.l2_block_hash = l2_blocks.hash
+		WHERE l2_blocks.timestamp >= $1 AND l2_blocks.timestamp <= $2
+		GROUP BY 1, 2
+	), flows AS (
+		SELECT bucket, token FROM deposit_volumes
+		UNION
+		SELECT bucket, token FROM withdrawal_volumes
+	)
+	SELECT
+		flows.bucket,
+		flows.token,
+		COALESCE(deposit_volumes.volume, 0),
+		COALESCE(withdrawal_volumes.volume, 0),
+		COALESCE(deposit_volumes.volume, 0) - COALESCE(withdrawal_volumes.volume, 0)
+	FROM flows
+		LEFT JOIN deposit_volumes
+			ON deposit_volumes.bucket = flows.bucket
+			AND deposit_volumes.token = flows.token
+		LEFT JOIN withdrawal_volumes
+			ON withdrawal_volumes.bucket = flows.bucket
+			AND withdrawal_volumes.token = flows.token
+	ORDER BY 1, 2;
+	`
+
+	width, ok := bucketSeconds[bucket]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBucket, bucket)
+	}
+
+	var series []NetFlowBucket
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		series = nil
+
+		rows, err := tx.QueryContext(ctx, selectNetFlowSeriesStatement, start, end, width)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var flow NetFlowBucket
+			var depositVolume, withdrawalVolume, netFlow string
+			err := rows.Scan(&flow.Timestamp, &flow.Token, &depositVolume, &withdrawalVolume, &netFlow)
+			if err != nil {
+				return err
+			}
+
+			if flow.DepositVolume, err = parseAmount(depositVolume); err != nil {
+				return err
+			}
+			if flow.WithdrawalVolume, err = parseAmount(withdrawalVolume); err != nil {
+				return err
+			}
+			if flow.NetFlow, err = parseAmount(netFlow); err != nil {
+				return err
+			}
+
+			series = append(series, flow)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// maxDailyCountDays caps the days of a daily count series, since a row is
+// generated for every day of the range whether or not anything happened.
+const maxDailyCountDays = 10 * 366
+
+// DailyCount is the number of deposits or withdrawals made during a day.
+type DailyCount struct {
+	// Day is the unix timestamp at which the day starts, at midnight UTC.
+	Day   uint64 `json:"day"`
+	Count uint64 `json:"count"`
+}
+
+// GetDepositsByDay returns the number of deposits made on every day between
+// the from and to timestamps, inclusive, ordered by day. Deposits are counted
+// on the day of their L1 block. Days without deposits are included with a
+// zero count, so that the series can be charted as is. Deposits invalidated
+// by a reorg are excluded. It fails with ErrInvalidTimeRange if from is
+// greater than to or if the range spans more than ten years.
+func (d *Database) GetDepositsByDay(ctx context.Context, from, to uint64) ([]DailyCount, error) {
+	const selectDepositCountsStatement = `
+	SELECT l1_blocks.timestamp - l1_blocks.timestamp % $3 AS day, count(*) AS count
+	FROM deposits
+		INNER JOIN l1_blocks ON deposits.l1_block_hash = l1_blocks.hash
+	WHERE l1_blocks.timestamp >= $1 AND l1_blocks.timestamp <= $2
+		AND deposits.reorged_at IS NULL
+	GROUP BY 1
+	`
+
+	return d.getCountsByDay(ctx, selectDepositCountsStatement, from, to)
+}
+
+// GetWithdrawalsByDay is the withdrawal equivalent of GetDepositsByDay.
+// Withdrawals are counted on the day of their L2 block.
+func (d *Database) GetWithdrawalsByDay(ctx context.Context, from, to uint64) ([]DailyCount, error) {
+	const selectWithdrawalCountsStatement = `
+	SELECT l2_blocks.timestamp - l2_blocks.timestamp % $3 AS day, count(*) AS count
+	FROM withdrawals
+		INNER JOIN l2_blocks ON withdrawals.l2_block_hash = l2_blocks.hash
+	WHERE l2_blocks.timestamp >= $1 AND l2_blocks.timestamp <= $2
+	GROUP BY 1
+	`
+
+	return d.getCountsByDay(ctx, selectWithdrawalCountsStatement, from, to)
+}
+
+// getCountsByDay joins the counts per day selected by countsStatement to
+// every day of the range, which are generated by a recursive query rather
+// than generate_series so that SQLite supports it too.
+func (d *Database) getCountsByDay(ctx context.Context, countsStatement string, from, to uint64) ([]DailyCount, error) {
+	const selectDailyCountsStatement = `
+	WITH RECURSIVE days(day) AS (
+		SELECT CAST($1 AS BIGINT) - CAST($1 AS BIGINT) %% $3
+		UNION ALL
+		SELECT day + $3 FROM days WHERE day + $3 <= $2
+	), counts AS (%s)
+	SELECT days.day, COALESCE(counts.count, 0)
+	FROM days
+		LEFT JOIN counts ON days.day = counts.day
+	ORDER BY 1;
+	`
+
+	day := bucketSeconds["day"]
+	if from > to {
+		return nil, fmt.Errorf("%w: from %d is greater than to %d", ErrInvalidTimeRange, from, to)
+	}
+	if days := to/day - from/day + 1; days > maxDailyCountDays {
+		return nil, fmt.Errorf("%w: %d days exceed maximum of %d", ErrInvalidTimeRange, days, maxDailyCountDays)
+	}
+
+	var series []DailyCount
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		series = nil
+
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(selectDailyCountsStatement, countsStatement), from, to, day)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var count DailyCount
+			if err := rows.Scan(&count.Day, &count.Count); err != nil {
+				return err
+			}
+			series = append(series, count)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
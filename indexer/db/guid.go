@@ -1,8 +1,33 @@
 package db
 
-import "github.com/google/uuid"
+import (
+	"errors"
+	"fmt"
 
-// NewGUID returns a new guid.
+	"github.com/google/uuid"
+)
+
+// ErrInvalidGUID signals that a deposit or withdrawal guid is malformed.
+var ErrInvalidGUID = errors.New("invalid guid")
+
+// NewGUID returns a new time-ordered (version 7) guid. Guids created in
+// sequence sort close to each other, so that inserting them appends to the
+// primary key indexes rather than writing all over them.
 func NewGUID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// newRandomGUID returns a new random (version 4) guid, see RandomGUIDs.
+func newRandomGUID() string {
 	return uuid.New().String()
 }
+
+// ParseGUID parses a guid given in any casing, so that it matches the
+// canonical form guids are stored in, as returned by NewGUID.
+func ParseGUID(s string) (string, error) {
+	guid, err := uuid.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %v", ErrInvalidGUID, s, err)
+	}
+	return guid.String(), nil
+}
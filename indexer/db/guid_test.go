@@ -0,0 +1,105 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testGUID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+// TestParseGUID asserts that guids are normalized regardless of their casing,
+// and that malformed guids are rejected.
+func TestParseGUID(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expErr bool
+	}{
+		{"lowercase", testGUID, false},
+		{"uppercase", strings.ToUpper(testGUID), false},
+		{"too short", testGUID[:35], true},
+		{"not hex", strings.Replace(testGUID, "0f", "zz", 1), true},
+		{"empty", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guid, err := db.ParseGUID(test.input)
+			if test.expErr {
+				require.True(t, errors.Is(err, db.ErrInvalidGUID))
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, testGUID, guid)
+		})
+	}
+
+	guid, err := db.ParseGUID(db.NewGUID())
+	require.Nil(t, err)
+	require.NotEmpty(t, guid)
+}
+
+// TestNewGUID asserts that guids are time-ordered, so that guids created in
+// sequence sort in that sequence.
+func TestNewGUID(t *testing.T) {
+	first := db.NewGUID()
+	second := db.NewGUID()
+
+	guid, err := uuid.Parse(first)
+	require.Nil(t, err)
+	require.Equal(t, uuid.Version(7), guid.Version())
+	require.Less(t, first, second)
+}
+
+// TestRandomGUIDs asserts that deposits get time-ordered guids by default,
+// and random ones if the Database is configured with RandomGUIDs.
+func TestRandomGUIDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		randomGUIDs bool
+		version     uuid.Version
+	}{
+		{"time-ordered", false, 7},
+		{"random", true, 4},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+				DSN:         newTestDSN(t),
+				RandomGUIDs: test.randomGUIDs,
+			})
+			require.Nil(t, err)
+			defer d.Close()
+
+			ctx := context.Background()
+			err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+				Hash:       common.HexToHash("0x01"),
+				ParentHash: common.HexToHash("0x00"),
+				Number:     1,
+				Timestamp:  1,
+				Deposits:   []db.Deposit{newTestDeposit(common.HexToHash("0xdd01"), 0)},
+			})
+			require.Nil(t, err)
+
+			block, err := d.GetIndexedL1BlockByHash(ctx, common.HexToHash("0x01"), true)
+			require.Nil(t, err)
+			require.Len(t, block.Deposits, 1)
+			guid, err := uuid.Parse(block.Deposits[0].GUID)
+			require.Nil(t, err)
+			require.Equal(t, test.version, guid.Version())
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrInvalidHash signals that a block or transaction hash is malformed.
+var ErrInvalidHash = errors.New("invalid hash")
+
+// ParseHash parses a block or transaction hash given in any casing, with or
+// without the 0x prefix, so that it matches the canonical form hashes are
+// stored in. Unlike common.HexToHash, it rejects malformed input instead of
+// silently truncating or padding it.
+func ParseHash(s string) (common.Hash, error) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(hex) != 2*common.HashLength {
+		return common.Hash{}, fmt.Errorf("%w: %q must be %d hex characters", ErrInvalidHash, s, 2*common.HashLength)
+	}
+	if !isHex(hex) {
+		return common.Hash{}, fmt.Errorf("%w: %q is not hexadecimal", ErrInvalidHash, s)
+	}
+	return common.HexToHash(hex), nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
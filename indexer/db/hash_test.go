@@ -0,0 +1,78 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const testHash = "0xabcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789"
+
+// TestParseHash asserts that hashes are normalized regardless of their casing
+// and prefix, and that malformed hashes are rejected.
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expErr bool
+	}{
+		{"lowercase", testHash, false},
+		{"uppercase", "0x" + strings.ToUpper(testHash[2:]), false},
+		{"uppercase prefix", "0X" + testHash[2:], false},
+		{"mixed case", "0xABCdef0123456789abcdef0123456789ABCDEF0123456789abcdef0123456789", false},
+		{"unprefixed", testHash[2:], false},
+		{"too short", testHash[:64], true},
+		{"too long", testHash + "00", true},
+		{"not hex", "0x" + strings.Repeat("zz", 32), true},
+		{"empty", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := db.ParseHash(test.input)
+			if test.expErr {
+				require.True(t, errors.Is(err, db.ErrInvalidHash))
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, common.HexToHash(testHash), hash)
+			require.Equal(t, testHash, hash.String())
+		})
+	}
+}
+
+// TestGetIndexedL1BlockByHashCasing asserts that a block is found whatever
+// the casing of the hash it is looked up with.
+func TestGetIndexedL1BlockByHashCasing(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	err := d.AddIndexedL1Block(context.Background(), &db.IndexedL1Block{
+		Hash:       common.HexToHash(testHash),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+	})
+	require.Nil(t, err)
+
+	for _, input := range []string{
+		testHash,
+		"0x" + strings.ToUpper(testHash[2:]),
+		testHash[2:],
+	} {
+		hash, err := db.ParseHash(input)
+		require.Nil(t, err)
+
+		block, err := d.GetIndexedL1BlockByHash(context.Background(), hash, false)
+		require.Nil(t, err)
+		require.NotNil(t, block)
+		require.Equal(t, uint64(1), block.Number)
+	}
+}
@@ -5,6 +5,8 @@ import (
 )
 
 // IndexedL1Block contains the L1 block including the deposits in it.
+// Withdrawals are the withdrawals finalized in the block. They are matched to
+// the indexed withdrawals by TxHash, and the other fields are ignored.
 type IndexedL1Block struct {
 	Hash        common.Hash
 	ParentHash  common.Hash
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSchemaVersionMismatch signals that the database schema version differs
+// from the SchemaVersion this package was built against.
+var ErrSchemaVersionMismatch = errors.New("schema version mismatch")
+
+// ErrInvalidSchemaVersion signals that a requested schema version is unknown
+// or would require rolling back applied migrations.
+var ErrInvalidSchemaVersion = errors.New("invalid schema version")
+
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+`
+
+// migration is a single versioned schema change.
+type migration struct {
+	version   int
+	statement string
+
+	// sqlite replaces statement when migrating a SQLite database.
+	sqlite string
+}
+
+// statementFor returns the statement applying m to a database of dialect.
+func (m migration) statementFor(dialect dialect) string {
+	if dialect == dialectSQLite && m.sqlite != "" {
+		return m.sqlite
+	}
+	return m.statement
+}
+
+// Migrate applies all pending migrations in version order. Each migration is
+// applied in its own transaction together with the schema_migrations row
+// recording it, so a failed migration leaves the recorded version unchanged.
+func (d *Database) Migrate(ctx context.Context) error {
+	return d.migrateTo(ctx, SchemaVersion)
+}
+
+// MigrateTo applies the pending migrations up to and including version, like
+// Migrate, so that operators can upgrade in steps. Migrations cannot be rolled
+// back, so it fails with ErrInvalidSchemaVersion if version is older than the
+// current schema version or newer than SchemaVersion.
+func (d *Database) MigrateTo(ctx context.Context, version int) error {
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: version %d is newer than the supported version %d",
+			ErrInvalidSchemaVersion, version, SchemaVersion)
+	}
+
+	current, err := d.CurrentSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version < current {
+		return fmt.Errorf("%w: database is already at version %d, cannot roll back to %d",
+			ErrInvalidSchemaVersion, current, version)
+	}
+
+	return d.migrateTo(ctx, version)
+}
+
+// migrateTo applies the pending migrations up to and including version.
+func (d *Database) migrateTo(ctx context.Context, version int) error {
+	const insertSchemaVersionStatement = `
+	INSERT INTO schema_migrations (version) VALUES ($1)
+	`
+
+	_, err := d.db.ExecContext(ctx, createSchemaMigrationsTable)
+	if err != nil {
+		return err
+	}
+
+	current, err := d.CurrentSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current || m.version > version {
+			continue
+		}
+
+		start := time.Now()
+		err := d.txn(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.statementFor(d.dialect)); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, insertSchemaVersionStatement, m.version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error applying migration %d: %w", m.version, err)
+		}
+
+		d.logger.Info("applied migration",
+			"version", m.version, "duration", time.Since(start))
+	}
+
+	return nil
+}
+
+// CurrentSchemaVersion returns the highest applied migration version, or zero
+// if no migration has been applied yet.
+func (d *Database) CurrentSchemaVersion(ctx context.Context) (int, error) {
+	const selectSchemaVersionStatement = `
+	SELECT COALESCE(MAX(version), 0) FROM schema_migrations
+	`
+
+	const selectSchemaMigrationsExistsStatement = `
+	SELECT to_regclass('schema_migrations') IS NOT NULL
+	`
+
+	const selectSchemaMigrationsExistsStatementSQLite = `
+	SELECT EXISTS (
+		SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'
+	)
+	`
+
+	existsStatement := selectSchemaMigrationsExistsStatement
+	if d.dialect == dialectSQLite {
+		existsStatement = selectSchemaMigrationsExistsStatementSQLite
+	}
+
+	var exists bool
+	err := d.db.QueryRowContext(ctx, existsStatement).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err = d.db.QueryRowContext(ctx, selectSchemaVersionStatement).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// checkSchemaVersion returns ErrSchemaVersionMismatch if the database schema
+// is older or newer than SchemaVersion.
+func (d *Database) checkSchemaVersion(ctx context.Context) error {
+	version, err := d.CurrentSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version < SchemaVersion {
+		return fmt.Errorf("%w: database is at version %d but %d is required, "+
+			"enable migrations to upgrade", ErrSchemaVersionMismatch, version, SchemaVersion)
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: database is at version %d which is newer than the "+
+			"supported version %d", ErrSchemaVersionMismatch, version, SchemaVersion)
+	}
+
+	return nil
+}
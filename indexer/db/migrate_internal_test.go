@@ -0,0 +1,27 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrationVersions asserts that migration versions are strictly
+// increasing and that SchemaVersion matches the latest migration.
+func TestMigrationVersions(t *testing.T) {
+	for i := 1; i < len(migrations); i++ {
+		require.Greater(t, migrations[i].version, migrations[i-1].version)
+	}
+	require.Equal(t, SchemaVersion, migrations[len(migrations)-1].version)
+}
+
+// TestMigrationStatementFor asserts that the SQLite statement of a migration
+// is only used for SQLite, and that the default statement is used otherwise.
+func TestMigrationStatementFor(t *testing.T) {
+	m := migration{version: 1, statement: "postgres", sqlite: "sqlite"}
+	require.Equal(t, "postgres", m.statementFor(dialectPostgres))
+	require.Equal(t, "sqlite", m.statementFor(dialectSQLite))
+
+	m = migration{version: 1, statement: "both"}
+	require.Equal(t, "both", m.statementFor(dialectSQLite))
+}
@@ -0,0 +1,315 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrateLogsAppliedMigrations asserts that Migrate logs the version and
+// duration of every migration it applies.
+func TestMigrateLogsAppliedMigrations(t *testing.T) {
+	t.Parallel()
+
+	var records []*log.Record
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r)
+		return nil
+	}))
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:    newTestDSN(t),
+		Logger: logger,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	// Roll the recorded version back by two so the next Migrate call
+	// performs a two-migration upgrade. Every migration is idempotent, so
+	// re-applying them is safe.
+	conn := openConn(t, d)
+	defer conn.Close()
+	var latest int
+	err = conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&latest)
+	require.Nil(t, err)
+	_, err = conn.Exec("DELETE FROM schema_migrations WHERE version > $1", latest-2)
+	require.Nil(t, err)
+
+	records = nil
+	err = d.Migrate(context.Background())
+	require.Nil(t, err)
+
+	require.Len(t, records, 2)
+	for i, record := range records {
+		require.Equal(t, "applied migration", record.Msg)
+		require.Equal(t, "version", record.Ctx[0])
+		require.Equal(t, latest-1+i, record.Ctx[1])
+		require.Equal(t, "duration", record.Ctx[2])
+	}
+}
+
+// TestSchemaVersionMismatch asserts that opening a database whose schema is
+// older or newer than SchemaVersion fails unless migrations can upgrade it.
+func TestSchemaVersionMismatch(t *testing.T) {
+	t.Parallel()
+
+	dsn := newTestDSN(t)
+	d, err := db.NewDatabase(dsn)
+	require.Nil(t, err)
+	defer d.Close()
+
+	conn := openConn(t, d)
+	defer conn.Close()
+
+	// Too old: the latest migration has not been applied.
+	_, err = conn.Exec("DELETE FROM schema_migrations WHERE version = $1", db.SchemaVersion)
+	require.Nil(t, err)
+
+	_, err = db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:               dsn,
+		DisableMigrations: true,
+	})
+	require.True(t, errors.Is(err, db.ErrSchemaVersionMismatch))
+
+	upgraded, err := db.NewDatabaseWithConfig(db.DatabaseConfig{DSN: dsn})
+	require.Nil(t, err)
+	require.Nil(t, upgraded.Close())
+
+	// Too new: the database was migrated by a newer binary.
+	_, err = conn.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", db.SchemaVersion+1)
+	require.Nil(t, err)
+
+	_, err = db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:               dsn,
+		DisableMigrations: true,
+	})
+	require.True(t, errors.Is(err, db.ErrSchemaVersionMismatch))
+
+	_, err = db.NewDatabaseWithConfig(db.DatabaseConfig{DSN: dsn})
+	require.True(t, errors.Is(err, db.ErrSchemaVersionMismatch))
+}
+
+// TestMigrateTo asserts that MigrateTo upgrades the schema up to the requested
+// version only, and that it refuses to roll back or to go beyond
+// SchemaVersion.
+func TestMigrateTo(t *testing.T) {
+	t.Parallel()
+
+	dsn := newTestDSN(t)
+	d, err := db.NewDatabase(dsn)
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	version, err := d.CurrentSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, db.SchemaVersion, version)
+
+	// Roll the recorded version back by two. Every migration is idempotent,
+	// so re-applying them is safe.
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec("DELETE FROM schema_migrations WHERE version > $1", db.SchemaVersion-2)
+	require.Nil(t, err)
+
+	old, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:                    dsn,
+		DisableMigrations:      true,
+		SkipSchemaVersionCheck: true,
+	})
+	require.Nil(t, err)
+	defer old.Close()
+
+	version, err = old.CurrentSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, db.SchemaVersion-2, version)
+
+	require.Nil(t, old.MigrateTo(ctx, db.SchemaVersion-1))
+	version, err = old.CurrentSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, db.SchemaVersion-1, version)
+
+	err = old.MigrateTo(ctx, db.SchemaVersion-2)
+	require.True(t, errors.Is(err, db.ErrInvalidSchemaVersion))
+
+	err = old.MigrateTo(ctx, db.SchemaVersion+1)
+	require.True(t, errors.Is(err, db.ErrInvalidSchemaVersion))
+
+	require.Nil(t, old.MigrateTo(ctx, db.SchemaVersion))
+	version, err = old.CurrentSchemaVersion(ctx)
+	require.Nil(t, err)
+	require.Equal(t, db.SchemaVersion, version)
+}
+
+// TestAmountsAreNumeric asserts that amounts are stored as NUMERIC, and that
+// amounts beyond the range of a 64-bit integer round-trip exactly.
+func TestAmountsAreNumeric(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	for _, table := range []string{"deposits", "withdrawals"} {
+		var dataType string
+		err := conn.QueryRow(
+			"SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = 'amount'",
+			table,
+		).Scan(&dataType)
+		require.Nil(t, err)
+		require.Equal(t, "numeric", dataType)
+	}
+
+	ctx := context.Background()
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.Amount = maxUint256
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDeposits(ctx, db.ActivityFilter{MinAmount: maxUint256}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, maxUint256.String(), deposits.Deposits[0].Amount)
+
+	block, err := d.GetIndexedL1BlockByHash(ctx, common.HexToHash("0x01"), true)
+	require.Nil(t, err)
+	require.Len(t, block.Deposits, 1)
+	require.Equal(t, 0, maxUint256.Cmp(block.Deposits[0].Amount))
+}
+
+// TestActivityIndexes asserts that lookups of deposits and withdrawals by the
+// columns they are filtered and joined on are planned as index scans.
+func TestActivityIndexes(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	conn := openConn(t, d)
+	defer conn.Close()
+
+	tests := []struct {
+		query    string
+		expIndex string
+	}{
+		{"SELECT * FROM deposits WHERE from_address = 'a'", "deposits_from_address"},
+		{"SELECT * FROM deposits WHERE to_address = 'a'", "deposits_to_address"},
+		{"SELECT * FROM deposits WHERE l1_token = 'a'", "deposits_l1_token"},
+		{"SELECT * FROM deposits WHERE tx_hash = 'a'", "deposits_tx_hash"},
+		{"SELECT * FROM deposits WHERE l1_block_hash = 'a'", "deposits_l1_block_hash"},
+		{"SELECT * FROM deposits WHERE l2_block_hash = 'a'", "deposits_l2_block_hash"},
+		{"SELECT * FROM withdrawals WHERE from_address = 'a'", "withdrawals_from_address"},
+		{"SELECT * FROM withdrawals WHERE to_address = 'a'", "withdrawals_to_address"},
+		{"SELECT * FROM withdrawals WHERE l1_token = 'a'", "withdrawals_l1_token"},
+		{"SELECT * FROM withdrawals WHERE tx_hash = 'a'", "withdrawals_tx_hash"},
+		{"SELECT * FROM withdrawals WHERE l1_block_hash = 'a'", "withdrawals_l1_block_hash"},
+		{"SELECT * FROM withdrawals WHERE l2_block_hash = 'a'", "withdrawals_l2_block_hash"},
+		{"SELECT * FROM l1_blocks WHERE number = 1", "l1_blocks_number"},
+		{"SELECT * FROM l2_blocks WHERE number = 1", "l2_blocks_number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expIndex, func(t *testing.T) {
+			// The tables are empty, so sequential scans must be ruled out
+			// for the planner to pick the indexes at all.
+			tx, err := conn.Begin()
+			require.Nil(t, err)
+			defer tx.Rollback()
+			_, err = tx.Exec("SET LOCAL enable_seqscan = off")
+			require.Nil(t, err)
+
+			rows, err := tx.Query("EXPLAIN " + test.query)
+			require.Nil(t, err)
+			defer rows.Close()
+
+			var plan []string
+			for rows.Next() {
+				var line string
+				require.Nil(t, rows.Scan(&line))
+				plan = append(plan, line)
+			}
+			require.Nil(t, rows.Err())
+			require.Contains(t, strings.Join(plan, "\n"), test.expIndex)
+		})
+	}
+}
+
+// TestLowercaseAddresses asserts that addresses written in checksummed form
+// before addresses were normalized are lowercased by the migration, and that
+// bridged balances kept under both casings are merged.
+func TestLowercaseAddresses(t *testing.T) {
+	t.Parallel()
+
+	dsn := newTestDSN(t)
+	d, err := db.NewDatabase(dsn)
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	token := common.HexToAddress("0xAbCdEf0000000000000000000000000000000001")
+	deposit := newTestDeposit(common.HexToHash("0xff01"), 0)
+	deposit.L1Token = token
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	// Rewrite the rows the way they used to be written.
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec("UPDATE deposits SET from_address = $1, l1_token = $2",
+		testFromAddress.String(), token.String())
+	require.Nil(t, err)
+	_, err = conn.Exec("INSERT INTO l1_tokens (address, name, symbol, decimals) VALUES ($1, 'Token', 'TKN', 18)",
+		token.String())
+	require.Nil(t, err)
+	_, err = conn.Exec("INSERT INTO bridged_balances (address, token, net_amount) VALUES ($1, $2, 2)",
+		testFromAddress.String(), token.String())
+	require.Nil(t, err)
+	_, err = conn.Exec("DELETE FROM schema_migrations WHERE version = $1", db.SchemaVersion)
+	require.Nil(t, err)
+
+	migrated, err := db.NewDatabase(dsn)
+	require.Nil(t, err)
+	defer migrated.Close()
+
+	var fromAddress, l1Token string
+	err = conn.QueryRow("SELECT from_address, l1_token FROM deposits").Scan(&fromAddress, &l1Token)
+	require.Nil(t, err)
+	require.Equal(t, strings.ToLower(testFromAddress.String()), fromAddress)
+	require.Equal(t, strings.ToLower(token.String()), l1Token)
+
+	_, err = migrated.GetL1TokenByAddress(ctx, token.String())
+	require.Nil(t, err)
+
+	balance, err := migrated.GetBridgedBalance(ctx, testFromAddress, token)
+	require.Nil(t, err)
+	require.Equal(t, big.NewInt(3), balance)
+
+	deposits, err := migrated.GetDeposits(ctx, db.ActivityFilter{
+		Address:           &testFromAddress,
+		IncludeUnverified: true,
+	}, db.PaginationParam{})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+}
@@ -1,20 +1,177 @@
 package db
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMaxPageOffset is the deepest row served when the Database is
+	// not configured with a MaxPageOffset.
+	DefaultMaxPageOffset = 10000
+
+	// DefaultPageLimit is the number of rows in a page requested without a
+	// Limit.
+	DefaultPageLimit = 10
+
+	// DefaultMaxPageLimit is the largest page served when the Database is
+	// not configured with a MaxPageLimit.
+	DefaultMaxPageLimit = 100
+)
+
+var (
+	// ErrPageOffsetTooLarge signals that the requested page lies beyond the
+	// maximum result window. Deep pages force the database to scan and
+	// discard every preceding row, so callers must narrow their query
+	// instead.
+	ErrPageOffsetTooLarge = errors.New("page offset too large")
+
+	// ErrPageLimitTooLarge signals that more rows were requested in a single
+	// page than the Database serves.
+	ErrPageLimitTooLarge = errors.New("page limit too large")
+)
+
 // PaginationParam holds the pagination fields passed through by the REST
 // middleware and queried by the database to page through deposits and
-// withdrawals.
+// withdrawals. It only selects which page of the matching rows to load and
+// what to load along with them. Which rows match, and the order they are
+// listed in, is set by the ActivityFilter given alongside it.
 type PaginationParam struct {
 	Limit  uint64 `json:"limit"`
 	Offset uint64 `json:"offset"`
+
+	// IncludeRelatedDeposits links withdrawals to the deposit they most
+	// likely round-trip.
+	IncludeRelatedDeposits bool `json:"-"`
+
+	// OmitData leaves out the potentially large data of each item. Only its
+	// length is returned, and the data itself is fetched on demand.
+	OmitData bool `json:"-"`
+
+	// Cursor resumes a listing from the NextCursor of a previous page rather
+	// than from Offset. Unlike an offset, a cursor does not skip or repeat
+	// rows when new rows are indexed between two requests.
+	Cursor string `json:"-"`
+
+	// InclusiveCursor includes the row the Cursor points at in the page,
+	// which is useful to refresh a listing from a known row.
+	InclusiveCursor bool `json:"-"`
+
+	// SkipTotal skips counting the total, which is costly on large tables.
+	// PageInfo.HasMore is reported instead, which is all infinite scrolling
+	// needs.
+	SkipTotal bool `json:"-"`
+
+	// IncludeTokenCounts counts the deposits matching the query per L1 token
+	// into PageInfo.TokenCounts, e.g. to show per-token tallies next to the
+	// page.
+	IncludeTokenCounts bool `json:"-"`
+}
+
+// PageInfo describes a page returned by a listing: the bounds it was loaded
+// with, and what was learned about the matching rows while loading it.
+type PageInfo struct {
+	Limit  uint64 `json:"limit"`
+	Offset uint64 `json:"offset"`
 	Total  uint64 `json:"total"`
+
+	// ByteSize is the size of the page's items once serialized to JSON, so
+	// that clients on metered connections can adapt their page size.
+	ByteSize uint64 `json:"byteSize"`
+
+	// HasMore reports whether rows follow the page. It is only set when
+	// PaginationParam.SkipTotal is.
+	HasMore bool `json:"hasMore,omitempty"`
+
+	// NextCursor points at the last row of the page if the page is full, so
+	// that more rows may follow. It is empty otherwise.
+	NextCursor string `json:"nextCursor,omitempty"`
+
+	// TokenCounts maps the address of every L1 token to the number of
+	// deposits of it matching the query, regardless of the page bounds. It is
+	// only set when PaginationParam.IncludeTokenCounts is.
+	TokenCounts map[string]uint64 `json:"tokenCounts,omitempty"`
+}
+
+// newPageInfo returns the PageInfo of a page loaded with the given params,
+// before anything is learned while loading it.
+func newPageInfo(page PaginationParam) *PageInfo {
+	return &PageInfo{
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
 }
 
 type PaginatedDeposits struct {
-	Param    *PaginationParam `json:"pagination"`
-	Deposits []DepositJSON    `json:"items"`
+	Page     *PageInfo     `json:"pagination"`
+	Deposits []DepositJSON `json:"items"`
 }
 
 type PaginatedWithdrawals struct {
-	Param       *PaginationParam `json:"pagination"`
+	Page        *PageInfo        `json:"pagination"`
 	Withdrawals []WithdrawalJSON `json:"items"`
 }
+
+type PaginatedTokens struct {
+	Page   *PageInfo `json:"pagination"`
+	Tokens []Token   `json:"items"`
+}
+
+// Validate checks the bounds of the page before it is queried. A zero Limit
+// is replaced by DefaultPageLimit. A Limit above maxLimit is rejected with
+// ErrPageLimitTooLarge rather than clamped, so that callers cannot mistake a
+// truncated page for a short one. Limit and Offset are unsigned, so neither
+// can be negative.
+func (p *PaginationParam) Validate(maxLimit uint64) error {
+	if p.Limit == 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > maxLimit {
+		return fmt.Errorf("%w: limit %d exceeds maximum of %d",
+			ErrPageLimitTooLarge, p.Limit, maxLimit)
+	}
+	return nil
+}
+
+// validatePage validates page against the limits the Database is configured
+// with, see PaginationParam.Validate and checkPageOffset.
+func (d *Database) validatePage(page *PaginationParam) error {
+	if err := page.Validate(d.maxPageLimit); err != nil {
+		return err
+	}
+	return d.checkPageOffset(*page)
+}
+
+// checkPageOffset returns ErrPageOffsetTooLarge if the page ends beyond the
+// configured maximum result window, i.e. if its offset plus its limit exceeds
+// it. Pages loaded by cursor ignore their offset, so they are not checked.
+func (d *Database) checkPageOffset(page PaginationParam) error {
+	if page.Cursor != "" {
+		return nil
+	}
+	// Written so as not to overflow for huge offsets.
+	if page.Offset > d.maxPageOffset || page.Limit > d.maxPageOffset-page.Offset {
+		return fmt.Errorf("%w: offset %d and limit %d exceed maximum of %d, narrow the query instead",
+			ErrPageOffsetTooLarge, page.Offset, page.Limit, d.maxPageOffset)
+	}
+	return nil
+}
+
+// pageLimit returns the number of rows to fetch for page. One extra row is
+// fetched when the total is skipped, to tell whether more rows follow.
+func pageLimit(page PaginationParam) uint64 {
+	if page.SkipTotal {
+		return page.Limit + 1
+	}
+	return page.Limit
+}
+
+// pageByteSize returns the size of the given page items serialized to JSON.
+func pageByteSize(items interface{}) (uint64, error) {
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(encoded)), nil
+}
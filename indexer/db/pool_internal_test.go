@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigurePool asserts that the configured pool settings are applied,
+// and that the defaults are used for unset settings.
+func TestConfigurePool(t *testing.T) {
+	conn, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	configurePool(conn, dialectPostgres, DatabaseConfig{})
+	require.Equal(t, DefaultMaxOpenConns, conn.Stats().MaxOpenConnections)
+
+	configurePool(conn, dialectPostgres, DatabaseConfig{
+		MaxOpenConns:    3,
+		MaxIdleConns:    1,
+		ConnMaxLifetime: time.Minute,
+	})
+	require.Equal(t, 3, conn.Stats().MaxOpenConnections)
+
+	configurePool(conn, dialectSQLite, DatabaseConfig{})
+	require.Equal(t, 1, conn.Stats().MaxOpenConnections)
+}
+
+// TestStatementTimeout asserts that the configured statement_timeout is set
+// on the connections of the Database.
+func TestStatementTimeout(t *testing.T) {
+	dbName := uuid.NewString()
+	conn, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+	_, err = conn.Exec(fmt.Sprintf("CREATE DATABASE \"%s\";", dbName))
+	require.Nil(t, err)
+	require.Nil(t, conn.Close())
+
+	d, err := NewDatabaseWithConfig(DatabaseConfig{
+		DSN:              fmt.Sprintf("%s dbname=%s", testDSN, dbName),
+		StatementTimeout: 1500 * time.Millisecond,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	var timeout string
+	err = d.db.QueryRowContext(context.Background(), "SHOW statement_timeout").Scan(&timeout)
+	require.Nil(t, err)
+	require.Equal(t, "1500ms", timeout)
+}
+
+// unreachableDSN is a DSN that countingDriver fails to connect to.
+const unreachableDSN = "unreachable"
+
+// failingCloseDSN is a DSN whose countingDriver connections fail to close,
+// although they are counted as closed.
+const failingCloseDSN = "failing-close"
+
+// countingDriver opens connections that fail every statement, and counts
+// the connections left open. It is registered under the name of a SQLite
+// driver so that DSNs select it through their scheme.
+type countingDriver struct {
+	open int64
+}
+
+var leakDriver = new(countingDriver)
+
+func init() {
+	sql.Register("sqlite", leakDriver)
+}
+
+func (d *countingDriver) Open(name string) (driver.Conn, error) {
+	if name == unreachableDSN {
+		return nil, errors.New("unreachable")
+	}
+	atomic.AddInt64(&d.open, 1)
+	return countingConn{d, name == failingCloseDSN}, nil
+}
+
+type countingConn struct {
+	driver    *countingDriver
+	failClose bool
+}
+
+func (c countingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("statements not supported")
+}
+
+func (c countingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (c countingConn) Close() error {
+	atomic.AddInt64(&c.driver.open, -1)
+	if c.failClose {
+		return errors.New("close failed")
+	}
+	return nil
+}
+
+// TestNewDatabaseCloses asserts that the primary and replica pools opened by
+// NewDatabaseWithConfig are closed whenever it fails, whether opening a
+// replica or migrating fails.
+func TestNewDatabaseCloses(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicaDSNs []string
+	}{
+		{"unreachable replica", []string{"sqlite://replica", "sqlite://" + unreachableDSN}},
+		{"failed migration", []string{"sqlite://replica"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewDatabaseWithConfig(DatabaseConfig{
+				DSN:         "sqlite://primary",
+				ReplicaDSNs: test.replicaDSNs,
+			})
+			require.NotNil(t, err)
+			require.Zero(t, atomic.LoadInt64(&leakDriver.open))
+		})
+	}
+}
+
+// TestCloseClosesAllPools asserts that Close closes the primary and every
+// replica pool even if closing one of them fails, and returns that error.
+func TestCloseClosesAllPools(t *testing.T) {
+	var pools []*sql.DB
+	for _, name := range []string{"primary", failingCloseDSN, "replica"} {
+		pool, err := sql.Open("sqlite", name)
+		require.Nil(t, err)
+		// Ping opens a connection, which Close has to close.
+		require.Nil(t, pool.Ping())
+		pools = append(pools, pool)
+	}
+	require.Equal(t, int64(3), atomic.LoadInt64(&leakDriver.open))
+
+	d := &Database{
+		db:         pools[0],
+		ownsDB:     true,
+		replicas:   pools[1:],
+		statements: newStatementCache(true),
+	}
+	require.EqualError(t, d.Close(), "close failed")
+	require.Zero(t, atomic.LoadInt64(&leakDriver.open))
+}
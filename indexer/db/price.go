@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"math/big"
+)
+
+// PriceProvider quotes token prices. The database does not track prices
+// itself, so callers wanting USD values attached to listed rows supply one
+// through DatabaseConfig.
+type PriceProvider interface {
+	// TokenPriceUSD returns the USD price of one whole token at the given L1
+	// address. ok is false if the price of the token is unknown.
+	TokenPriceUSD(ctx context.Context, address string) (price float64, ok bool, err error)
+}
+
+// usdValue returns the USD value of amount, given in the token's base units,
+// at price per whole token.
+func usdValue(amount string, decimals uint8, price float64) (float64, bool) {
+	value, ok := new(big.Float).SetString(amount)
+	if !ok {
+		return 0, false
+	}
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	value.Quo(value, scale)
+	value.Mul(value, big.NewFloat(price))
+
+	usd, _ := value.Float64()
+	return usd, true
+}
+
+// enrichDeposits attaches the USD value of every deposit whose token has a
+// known price. Prices are fetched once per token. Failing lookups are logged
+// and leave the value unset, so that a price outage does not fail listings.
+func (d *Database) enrichDeposits(ctx context.Context, deposits []DepositJSON) {
+	if d.prices == nil {
+		return
+	}
+
+	type quote struct {
+		price float64
+		ok    bool
+	}
+	quotes := make(map[string]quote)
+	for i := range deposits {
+		token := deposits[i].L1Token
+		q, cached := quotes[token.Address]
+		if !cached {
+			price, ok, err := d.prices.TokenPriceUSD(ctx, token.Address)
+			if err != nil {
+				d.logger.Warn("error querying token price",
+					"token", token.Address, "err", err)
+			}
+			q = quote{price: price, ok: ok && err == nil}
+			quotes[token.Address] = q
+		}
+		if !q.ok {
+			continue
+		}
+
+		if value, ok := usdValue(deposits[i].Amount, token.Decimals, q.price); ok {
+			deposits[i].USDValue = &value
+		}
+	}
+}
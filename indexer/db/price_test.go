@@ -0,0 +1,52 @@
+package db_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePriceProvider quotes fixed prices keyed by token address.
+type fakePriceProvider map[string]float64
+
+func (p fakePriceProvider) TokenPriceUSD(ctx context.Context, address string) (float64, bool, error) {
+	price, ok := p[address]
+	return price, ok, nil
+}
+
+// TestGetDepositsByAddressPriceProvider asserts that deposits are enriched
+// with their USD value when the price of their token is known.
+func TestGetDepositsByAddressPriceProvider(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN: newTestDSN(t),
+		PriceProvider: fakePriceProvider{
+			db.ETHL1Token.Address: 2000,
+		},
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	deposit := newTestDeposit(common.HexToHash("0xdd01"), 0)
+	deposit.Amount, _ = new(big.Int).SetString("1500000000000000000", 10)
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Len(t, deposits.Deposits, 1)
+	require.NotNil(t, deposits.Deposits[0].USDValue)
+	require.InDelta(t, 3000, *deposits.Deposits[0].USDValue, 1e-9)
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DeleteL1BlocksFrom rolls back every indexed L1 block with a number greater
+// than or equal to the given one, along with the deposits they contain.
+// Withdrawals finalized in those blocks are kept, but are no longer linked to
+// an L1 block. Bridged balances are adjusted for the deleted deposits. Rolling
+// back many blocks should be followed by VacuumAnalyze. Finalizations recorded
+// as pending in those blocks are dropped, see MarkWithdrawalFinalized.
+//
+// If the Database is configured with SoftDeleteReorged, the blocks and their
+// deposits are tombstoned rather than deleted: their reorged_at is set, which
+// hides them from every getter but leaves them for audit until PurgeReorged
+// deletes them.
+//
+// The L1 ingestion checkpoint is moved back to the block preceding number in
+// the same transaction if it was at or beyond it, see GetL1Checkpoint.
+func (d *Database) DeleteL1BlocksFrom(ctx context.Context, number uint64) error {
+	defer d.finalizedWithdrawals.purge()
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		if err := deleteL1Blocks(ctx, tx, ">=", number, d.softDeleteReorged); err != nil {
+			return err
+		}
+		return clampL1Checkpoint(ctx, tx, number)
+	})
+}
+
+// PurgeReorged deletes the deposits and L1 blocks tombstoned before the given
+// time, see DeleteL1BlocksFrom. Blocks still holding deposits tombstoned
+// later, e.g. blocks that became canonical again, are kept.
+func (d *Database) PurgeReorged(ctx context.Context, before time.Time) error {
+	const deleteDepositsStatement = `
+	DELETE FROM deposits WHERE reorged_at < $1;
+	`
+
+	const deleteBlocksStatement = `
+	DELETE FROM l1_blocks
+	WHERE reorged_at < $1
+		AND NOT EXISTS (SELECT 1 FROM deposits WHERE deposits.l1_block_hash = l1_blocks.hash);
+	`
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		for _, statement := range []string{deleteDepositsStatement, deleteBlocksStatement} {
+			if _, err := tx.ExecContext(ctx, statement, before.UTC()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceIndexedL1Block inserts the indexed block in place of the block
+// indexed at the same number, if any, in a single transaction. The replaced
+// block is rolled back as by DeleteL1BlocksFrom, but blocks after it are
+// kept. Unlike AddIndexedL1Block, it is idempotent, so that a block can be
+// indexed again after a reorg. The L1 ingestion checkpoint is moved back below
+// the replaced block as by DeleteL1BlocksFrom.
+func (d *Database) ReplaceIndexedL1Block(ctx context.Context, block *IndexedL1Block) error {
+	defer d.finalizedWithdrawals.purge()
+
+	var changes []WithdrawalStatusChange
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		if err := deleteL1Blocks(ctx, tx, "=", block.Number, d.softDeleteReorged); err != nil {
+			return err
+		}
+		if err := clampL1Checkpoint(ctx, tx, block.Number); err != nil {
+			return err
+		}
+
+		var err error
+		changes, err = d.addIndexedL1Block(ctx, tx, block)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	d.notifyWithdrawalStatusChanges(changes)
+	return nil
+}
+
+// deleteL1Blocks rolls back within tx the L1 blocks whose number compares to
+// the given one with operator, tombstoning them if softDelete is set, see
+// DeleteL1BlocksFrom.
+func deleteL1Blocks(ctx context.Context, tx *sql.Tx, operator string, number uint64, softDelete bool) error {
+	const unlinkWithdrawalsStatement = `
+	UPDATE withdrawals SET l1_block_hash = NULL
+	WHERE l1_block_hash IN (SELECT hash FROM l1_blocks WHERE number %s $1);
+	`
+
+	const revertBridgedBalancesStatement = `
+	UPDATE bridged_balances SET net_amount = bridged_balances.net_amount - reverted.amount
+	FROM (
+		SELECT deposits.from_address, deposits.l1_token, SUM(CAST(deposits.amount AS NUMERIC)) AS amount
+		FROM deposits
+			INNER JOIN l1_blocks ON deposits.l1_block_hash=l1_blocks.hash
+		WHERE l1_blocks.number %s $1 AND deposits.reorged_at IS NULL
+		GROUP BY deposits.from_address, deposits.l1_token
+	) AS reverted
+	WHERE bridged_balances.address = reverted.from_address
+		AND bridged_balances.token = reverted.l1_token;
+	`
+
+	const deletePendingFinalizationsStatement = `
+	DELETE FROM pending_finalizations
+	WHERE l1_block_hash IN (SELECT hash FROM l1_blocks WHERE number %s $1);
+	`
+
+	const deleteDepositsStatement = `
+	DELETE FROM deposits
+	WHERE l1_block_hash IN (SELECT hash FROM l1_blocks WHERE number %s $1);
+	`
+
+	const deleteBlocksStatement = `
+	DELETE FROM l1_blocks WHERE number %s $1;
+	`
+
+	const tombstoneDepositsStatement = `
+	UPDATE deposits SET reorged_at = CURRENT_TIMESTAMP
+	WHERE reorged_at IS NULL
+		AND l1_block_hash IN (SELECT hash FROM l1_blocks WHERE number %s $1 AND reorged_at IS NULL);
+	`
+
+	const tombstoneBlocksStatement = `
+	UPDATE l1_blocks SET reorged_at = CURRENT_TIMESTAMP
+	WHERE number %s $1 AND reorged_at IS NULL;
+	`
+
+	statements := []string{
+		unlinkWithdrawalsStatement,
+		deletePendingFinalizationsStatement,
+		revertBridgedBalancesStatement,
+		deleteDepositsStatement,
+		deleteBlocksStatement,
+	}
+	if softDelete {
+		statements = []string{
+			unlinkWithdrawalsStatement,
+			deletePendingFinalizationsStatement,
+			revertBridgedBalancesStatement,
+			tombstoneDepositsStatement,
+			tombstoneBlocksStatement,
+		}
+	}
+
+	for _, statement := range statements {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(statement, operator), number)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteL2BlocksFrom rolls back every indexed L2 block with a number greater
+// than or equal to the given one, along with the withdrawals they contain.
+// Deposits finalized in those blocks are kept, but are no longer linked to an
+// L2 block. Bridged balances are adjusted for the deleted withdrawals. The
+// finalizations of deleted withdrawals are recorded as pending, so that they
+// are applied again once their transactions are indexed anew, see
+// MarkWithdrawalFinalized.
+func (d *Database) DeleteL2BlocksFrom(ctx context.Context, number uint64) error {
+	const keepFinalizationsStatement = `
+	INSERT INTO pending_finalizations
+		(tx_hash, l1_block_hash)
+	SELECT DISTINCT tx_hash, l1_block_hash FROM withdrawals
+	WHERE l1_block_hash IS NOT NULL
+		AND l2_block_hash IN (SELECT hash FROM l2_blocks WHERE number >= $1)
+	ON CONFLICT (tx_hash) DO NOTHING;
+	`
+
+	const unlinkDepositsStatement = `
+	UPDATE deposits SET l2_block_hash = NULL
+	WHERE l2_block_hash IN (SELECT hash FROM l2_blocks WHERE number >= $1);
+	`
+
+	const revertBridgedBalancesStatement = `
+	UPDATE bridged_balances SET net_amount = bridged_balances.net_amount + reverted.amount
+	FROM (
+		SELECT withdrawals.from_address, withdrawals.l1_token, SUM(CAST(withdrawals.amount AS NUMERIC)) AS amount
+		FROM withdrawals
+			INNER JOIN l2_blocks ON withdrawals.l2_block_hash=l2_blocks.hash
+		WHERE l2_blocks.number >= $1
+		GROUP BY withdrawals.from_address, withdrawals.l1_token
+	) AS reverted
+	WHERE bridged_balances.address = reverted.from_address
+		AND bridged_balances.token = reverted.l1_token;
+	`
+
+	const deleteWithdrawalsStatement = `
+	DELETE FROM withdrawals
+	WHERE l2_block_hash IN (SELECT hash FROM l2_blocks WHERE number >= $1);
+	`
+
+	const deleteBlocksStatement = `
+	DELETE FROM l2_blocks WHERE number >= $1;
+	`
+
+	defer d.finalizedWithdrawals.purge()
+
+	return d.txn(ctx, func(tx *sql.Tx) error {
+		for _, statement := range []string{
+			keepFinalizationsStatement,
+			unlinkDepositsStatement,
+			revertBridgedBalancesStatement,
+			deleteWithdrawalsStatement,
+			deleteBlocksStatement,
+		} {
+			if _, err := tx.ExecContext(ctx, statement, number); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,257 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeleteL1BlocksFrom asserts that rolling back L1 blocks deletes their
+// deposits and only unlinks the withdrawals they finalized.
+func TestDeleteL1BlocksFrom(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	for number := uint64(1); number <= 3; number++ {
+		err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number,
+			Deposits: []db.Deposit{
+				newTestDeposit(common.BigToHash(big.NewInt(int64(100+number))), 0),
+			},
+		})
+		require.Nil(t, err)
+	}
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	_, err = conn.Exec(
+		"UPDATE withdrawals SET l1_block_hash = $1 WHERE tx_hash = $2",
+		common.BigToHash(big.NewInt(3)).String(), withdrawal.TxHash.String(),
+	)
+	require.Nil(t, err)
+
+	err = d.DeleteL1BlocksFrom(ctx, 2)
+	require.Nil(t, err)
+
+	highest, err := d.GetHighestL1Block(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), highest.Number)
+
+	deposits, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+
+	var l1BlockHash sql.NullString
+	err = conn.QueryRow(
+		"SELECT l1_block_hash FROM withdrawals WHERE tx_hash = $1",
+		withdrawal.TxHash.String(),
+	).Scan(&l1BlockHash)
+	require.Nil(t, err)
+	require.False(t, l1BlockHash.Valid)
+
+	// One deposit remains and the withdrawal is still indexed.
+	balance, err := d.GetBridgedBalance(ctx, testFromAddress, common.HexToAddress(db.ETHL1Token.Address))
+	require.Nil(t, err)
+	require.Equal(t, 0, balance.Cmp(big.NewInt(0)))
+}
+
+// TestSoftDeleteReorged asserts that rolled back L1 blocks and deposits are
+// tombstoned rather than deleted when SoftDeleteReorged is set, that a
+// tombstoned block can be indexed again, and that PurgeReorged deletes the
+// tombstones.
+func TestSoftDeleteReorged(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:               newTestDSN(t),
+		SoftDeleteReorged: true,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	var blocks []*db.IndexedL1Block
+	for number := uint64(1); number <= 3; number++ {
+		block := &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number,
+			Deposits: []db.Deposit{
+				newTestDeposit(common.BigToHash(big.NewInt(int64(100+number))), 0),
+			},
+		}
+		require.Nil(t, d.AddIndexedL1Block(ctx, block))
+		blocks = append(blocks, block)
+	}
+
+	require.Nil(t, d.DeleteL1BlocksFrom(ctx, 2))
+
+	highest, err := d.GetHighestL1Block(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), highest.Number)
+	_, err = d.GetIndexedL1BlockByNumber(ctx, 2, false)
+	require.True(t, errors.Is(err, db.ErrBlockNotFound))
+
+	deposits, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	deposits, err = d.GetDeposits(ctx, db.ActivityFilter{
+		Address:        &testFromAddress,
+		IncludeReorged: true,
+	}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), deposits.Page.Total)
+
+	// The tombstoned block becomes canonical again, while a new block takes
+	// the place of the other.
+	require.Nil(t, d.AddIndexedL1Block(ctx, blocks[1]))
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x33"),
+		ParentHash: blocks[1].Hash,
+		Number:     3,
+		Timestamp:  3,
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(ctx, blocks[1])
+	require.True(t, errors.Is(err, db.ErrDuplicateBlock))
+
+	block, err := d.GetIndexedL1BlockByNumber(ctx, 2, true)
+	require.Nil(t, err)
+	require.Equal(t, blocks[1].Hash, block.Hash)
+	require.Len(t, block.Deposits, 1)
+
+	require.Nil(t, d.PurgeReorged(ctx, time.Now().Add(time.Minute)))
+
+	conn := openConn(t, d)
+	defer conn.Close()
+	var tombstones int
+	err = conn.QueryRow(`
+		SELECT (SELECT count(*) FROM deposits WHERE reorged_at IS NOT NULL)
+			+ (SELECT count(*) FROM l1_blocks WHERE reorged_at IS NOT NULL)
+	`).Scan(&tombstones)
+	require.Nil(t, err)
+	require.Zero(t, tombstones)
+
+	deposits, err = d.GetDeposits(ctx, db.ActivityFilter{
+		Address:        &testFromAddress,
+		IncludeReorged: true,
+	}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), deposits.Page.Total)
+}
+
+// TestReplaceIndexedL1Block asserts that replacing a block rolls back the
+// block indexed at its number only, and that replacing the same block again
+// leaves the index unchanged.
+func TestReplaceIndexedL1Block(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	for number := uint64(1); number <= 2; number++ {
+		err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number,
+			Deposits: []db.Deposit{
+				newTestDeposit(common.BigToHash(big.NewInt(int64(100+number))), 0),
+			},
+		})
+		require.Nil(t, err)
+	}
+
+	replacement := &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x22"),
+		ParentHash: common.BigToHash(big.NewInt(1)),
+		Number:     2,
+		Timestamp:  2,
+		Deposits: []db.Deposit{
+			newTestDeposit(common.HexToHash("0xff01"), 0),
+			newTestDeposit(common.HexToHash("0xff02"), 1),
+		},
+	}
+	err := d.AddIndexedL1Block(ctx, replacement)
+	require.True(t, errors.Is(err, db.ErrConflictingBlock))
+
+	for i := 0; i < 2; i++ {
+		require.Nil(t, d.ReplaceIndexedL1Block(ctx, replacement))
+
+		block, err := d.GetIndexedL1BlockByNumber(ctx, 2, true)
+		require.Nil(t, err)
+		require.Equal(t, replacement.Hash, block.Hash)
+		require.Len(t, block.Deposits, 2)
+
+		deposits, err := d.GetDepositsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+		require.Nil(t, err)
+		require.Equal(t, uint64(3), deposits.Page.Total)
+
+		balance, err := d.GetBridgedBalance(ctx, testFromAddress, common.HexToAddress(db.ETHL1Token.Address))
+		require.Nil(t, err)
+		require.Equal(t, 0, balance.Cmp(big.NewInt(3)))
+	}
+}
+
+// TestDeleteL2BlocksFrom asserts that rolling back L2 blocks deletes their
+// withdrawals.
+func TestDeleteL2BlocksFrom(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	for number := uint64(1); number <= 3; number++ {
+		err := d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+			Hash:       common.BigToHash(big.NewInt(int64(number))),
+			ParentHash: common.BigToHash(big.NewInt(int64(number - 1))),
+			Number:     number,
+			Timestamp:  number,
+			Withdrawals: []db.Withdrawal{
+				newTestWithdrawal(common.BigToHash(big.NewInt(int64(100+number))), 0),
+			},
+		})
+		require.Nil(t, err)
+	}
+
+	err := d.DeleteL2BlocksFrom(ctx, 3)
+	require.Nil(t, err)
+
+	highest, err := d.GetHighestL2Block(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), highest.Number)
+
+	withdrawals, err := d.GetWithdrawalsByAddress(ctx, testFromAddress, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(2), withdrawals.Page.Total)
+
+	balance, err := d.GetBridgedBalance(ctx, testFromAddress, common.HexToAddress(db.ETHL1Token.Address))
+	require.Nil(t, err)
+	require.Equal(t, 0, balance.Cmp(big.NewInt(-2)))
+}
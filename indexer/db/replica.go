@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoReplica signals that a replica-only operation was attempted on a
+// Database configured without read replicas.
+var ErrNoReplica = errors.New("no read replica configured")
+
+// reader returns the pool read-only queries are sent to: the read replicas in
+// turn if any is configured, the primary otherwise.
+func (d *Database) reader() *sql.DB {
+	if len(d.replicas) == 0 {
+		return d.db
+	}
+	next := atomic.AddUint64(&d.nextReplica, 1)
+	return d.replicas[(next-1)%uint64(len(d.replicas))]
+}
+
+// ReplicaLag returns how far the most lagging read replica is behind the
+// primary, measured as the time since it last replayed a transaction. Since
+// an idle primary produces no transactions to replay, the lag also grows
+// while the primary is idle.
+func (d *Database) ReplicaLag(ctx context.Context) (time.Duration, error) {
+	const selectReplicaLagStatement = `
+	SELECT EXTRACT(EPOCH FROM (NOW() - pg_last_xact_replay_timestamp()));
+	`
+
+	if d.dialect != dialectPostgres {
+		return 0, ErrUnsupportedDialect
+	}
+	if len(d.replicas) == 0 {
+		return 0, ErrNoReplica
+	}
+
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	var maxLag time.Duration
+	for _, replica := range d.replicas {
+		var seconds sql.NullFloat64
+		err := replica.QueryRowContext(ctx, selectReplicaLagStatement).Scan(&seconds)
+		if err != nil {
+			return 0, err
+		}
+
+		lag, err := parseReplicaLag(seconds)
+		if err != nil {
+			return 0, err
+		}
+		if lag > maxLag {
+			maxLag = lag
+		}
+	}
+
+	return maxLag, nil
+}
+
+// parseReplicaLag converts the lag reported by a replica in seconds into a
+// duration. The lag is NULL if the server is not replaying a primary.
+func parseReplicaLag(seconds sql.NullFloat64) (time.Duration, error) {
+	if !seconds.Valid {
+		return 0, errors.New("replica has not replayed any transaction")
+	}
+	if seconds.Float64 < 0 {
+		return 0, nil
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseReplicaLag asserts that the lag reported by a replica is converted
+// into a duration, and that a server which is not replaying fails.
+func TestParseReplicaLag(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds sql.NullFloat64
+		expLag  time.Duration
+		expErr  bool
+	}{
+		{
+			name:    "not a replica",
+			seconds: sql.NullFloat64{},
+			expErr:  true,
+		},
+		{
+			name:    "fractional lag",
+			seconds: sql.NullFloat64{Float64: 1.5, Valid: true},
+			expLag:  1500 * time.Millisecond,
+		},
+		{
+			name:    "clock skew",
+			seconds: sql.NullFloat64{Float64: -0.25, Valid: true},
+			expLag:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lag, err := parseReplicaLag(test.seconds)
+			if test.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, test.expLag, lag)
+		})
+	}
+}
+
+// TestReplicaLagWithoutReplica asserts that ReplicaLag fails when no replica
+// is configured.
+func TestReplicaLagWithoutReplica(t *testing.T) {
+	d := &Database{}
+	_, err := d.ReplicaLag(context.Background())
+	require.Equal(t, ErrNoReplica, err)
+}
+
+// TestReader asserts that reads go to the primary without replicas, and are
+// spread over the replicas in turn otherwise.
+func TestReader(t *testing.T) {
+	primary, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+	defer primary.Close()
+
+	d := &Database{db: primary}
+	require.True(t, d.reader() == primary)
+	require.True(t, d.reader() == primary)
+
+	var replicas []*sql.DB
+	for i := 0; i < 2; i++ {
+		replica, err := sql.Open("postgres", testDSN)
+		require.Nil(t, err)
+		defer replica.Close()
+		replicas = append(replicas, replica)
+	}
+
+	d.replicas = replicas
+	require.True(t, d.reader() == replicas[0])
+	require.True(t, d.reader() == replicas[1])
+	require.True(t, d.reader() == replicas[0])
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+// slowQueryHelpers are the functions running queries on behalf of the
+// Database methods that slow queries are attributed to.
+var slowQueryHelpers = map[string]bool{
+	"logSlowQuery": true,
+	"txnOn":        true,
+	"txn":          true,
+	"readTxn":      true,
+}
+
+// logSlowQuery logs at WARN the method that ran a query or transaction
+// started at start, along with its duration, if it took longer than the
+// configured SlowQueryThreshold. The args of the query are only logged if
+// LogSlowQueryArgs is set, as they may hold addresses.
+func (d *Database) logSlowQuery(start time.Time, args []interface{}) {
+	if d.slowQueryThreshold == 0 {
+		return
+	}
+	duration := time.Since(start)
+	if duration < d.slowQueryThreshold {
+		return
+	}
+
+	logCtx := []interface{}{"method", callerMethod(), "duration", duration}
+	if d.logSlowQueryArgs && len(args) > 0 {
+		logCtx = append(logCtx, "args", args)
+	}
+	d.logger.Warn("slow query", logCtx...)
+}
+
+// callerMethod returns the name of the innermost function on the stack of
+// the caller that is neither a query helper nor part of the runtime.
+func callerMethod() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		name := frame.Function[strings.LastIndex(frame.Function, ".")+1:]
+		if !strings.HasPrefix(frame.Function, "runtime.") && !slowQueryHelpers[name] {
+			return name
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
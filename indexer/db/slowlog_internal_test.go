@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLogSlowQuery asserts that only queries slower than the threshold are
+// logged, attributed to their caller, and that their args are only logged
+// when asked to.
+func TestLogSlowQuery(t *testing.T) {
+	t.Parallel()
+
+	var records []*log.Record
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		records = append(records, r)
+		return nil
+	}))
+
+	d := &Database{logger: logger}
+	args := []interface{}{"0xaa01"}
+
+	d.logSlowQuery(time.Now().Add(-time.Hour), args)
+	require.Empty(t, records)
+
+	d.slowQueryThreshold = time.Minute
+	d.logSlowQuery(time.Now(), args)
+	require.Empty(t, records)
+
+	d.logSlowQuery(time.Now().Add(-time.Hour), args)
+	require.Len(t, records, 1)
+	require.Equal(t, log.LvlWarn, records[0].Lvl)
+	require.Equal(t, "slow query", records[0].Msg)
+	require.Equal(t, []interface{}{"method", "TestLogSlowQuery"}, records[0].Ctx[:2])
+	require.Len(t, records[0].Ctx, 4)
+
+	d.logSlowQueryArgs = true
+	d.logSlowQuery(time.Now().Add(-time.Hour), args)
+	require.Len(t, records, 2)
+	require.Equal(t, []interface{}{"args", args}, records[1].Ctx[4:])
+}
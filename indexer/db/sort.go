@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSort signals that a SortBy or SortDir is not one of the known
+// values, or cannot be combined with the other pagination params.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortBy selects the column listings are ordered by.
+type SortBy string
+
+const (
+	// SortByTimestamp orders by block timestamp. The zero value is
+	// equivalent.
+	SortByTimestamp SortBy = "timestamp"
+
+	// SortByBlockNumber orders by block number.
+	SortByBlockNumber SortBy = "block_number"
+
+	// SortByAmount orders by amount in the token's base units.
+	SortByAmount SortBy = "amount"
+
+	// SortBySourceAddress orders deposits by the bridge contract they were
+	// made through. Deposits indexed before it was recorded come last.
+	SortBySourceAddress SortBy = "source_address"
+)
+
+// SortDir selects the direction listings are ordered in.
+type SortDir string
+
+const (
+	// SortAsc orders from the smallest value up. The zero value is
+	// equivalent.
+	SortAsc SortDir = "asc"
+
+	// SortDesc orders from the largest value down.
+	SortDesc SortDir = "desc"
+)
+
+// orderBy returns the ORDER BY expressions listing the given tables by the
+// requested sort, or ErrInvalidSort if the sort is unknown. Rows are always
+// totally ordered by falling back to block number, log index and guid. Rows
+// with a NULL sort column come last in both directions, rather than flipping
+// sides with the direction as they do by default, so that pages stay stable.
+// Only the known values of SortBy and SortDir ever reach the query.
+func orderBy(tables activityTables, by SortBy, dir SortDir) (string, error) {
+	var direction string
+	switch dir {
+	case "", SortAsc:
+		direction = "ASC"
+	case SortDesc:
+		direction = "DESC"
+	default:
+		return "", fmt.Errorf("%w: direction %q", ErrInvalidSort, string(dir))
+	}
+
+	var columns []string
+	switch by {
+	case "", SortByTimestamp:
+		columns = append(columns, tables.blocks+".timestamp "+direction)
+	case SortByBlockNumber:
+	case SortByAmount:
+		columns = append(columns, "CAST("+tables.activity+".amount AS NUMERIC) "+direction)
+	case SortBySourceAddress:
+		columns = append(columns, tables.activity+".source_address "+direction+" NULLS LAST")
+	default:
+		return "", fmt.Errorf("%w: column %q", ErrInvalidSort, string(by))
+	}
+	for _, column := range []string{
+		tables.blocks + ".number",
+		tables.activity + ".log_index",
+		tables.activity + ".guid",
+	} {
+		columns = append(columns, column+" "+direction)
+	}
+
+	return strings.Join(columns, ", "), nil
+}
+
+// isBlockOrder reports whether the sort lists rows in block order, the order
+// cursors are defined over, and whether that order is descending.
+func isBlockOrder(by SortBy, dir SortDir) (ok, descending bool) {
+	switch by {
+	case "", SortByTimestamp, SortByBlockNumber:
+		return true, dir == SortDesc
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestOrderBy asserts that known sorts translate to the expected ORDER BY
+// expressions and that anything else is rejected with ErrInvalidSort.
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		by       SortBy
+		dir      SortDir
+		expOrder string
+	}{
+		{"", "", "l1_blocks.timestamp ASC, l1_blocks.number ASC, deposits.log_index ASC, deposits.guid ASC"},
+		{SortByBlockNumber, SortDesc, "l1_blocks.number DESC, deposits.log_index DESC, deposits.guid DESC"},
+		{SortByAmount, SortAsc, "CAST(deposits.amount AS NUMERIC) ASC, l1_blocks.number ASC, deposits.log_index ASC, deposits.guid ASC"},
+		{SortBySourceAddress, SortAsc, "deposits.source_address ASC NULLS LAST, l1_blocks.number ASC, deposits.log_index ASC, deposits.guid ASC"},
+		{SortBySourceAddress, SortDesc, "deposits.source_address DESC NULLS LAST, l1_blocks.number DESC, deposits.log_index DESC, deposits.guid DESC"},
+	}
+	for _, test := range tests {
+		order, err := orderBy(depositTables, test.by, test.dir)
+		require.Nil(t, err)
+		require.Equal(t, test.expOrder, order)
+	}
+
+	_, err := orderBy(depositTables, "amount; DROP TABLE deposits", "")
+	require.True(t, errors.Is(err, ErrInvalidSort))
+
+	_, err = orderBy(depositTables, "", "sideways")
+	require.True(t, errors.Is(err, ErrInvalidSort))
+}
@@ -65,7 +65,7 @@ CREATE TABLE IF NOT EXISTS withdrawals (
 	log_index INTEGER NOT NULL,
 	l1_block_hash VARCHAR REFERENCES l1_blocks(hash),
 	l2_block_hash VARCHAR NOT NULL REFERENCES l2_blocks(hash),
-	tx_hash VARCHAR NOT NULL,
+	tx_hash VARCHAR NOT NULL
 )
 `
 
@@ -110,15 +110,315 @@ CREATE TABLE IF NOT EXISTS airdrops (
 )
 `
 
-var schema = []string{
-	createL1BlocksTable,
-	createL2BlocksTable,
-	createL1TokensTable,
-	createL2TokensTable,
-	insertETHL1Token,
-	insertETHL2Token,
-	createDepositsTable,
-	createWithdrawalsTable,
-	createL1L2NumberIndex,
-	createAirdropsTable,
+// createAirdropsTableSQLite is createAirdropsTable for SQLite, which lacks
+// the regular expression operator.
+const createAirdropsTableSQLite = `
+CREATE TABLE IF NOT EXISTS airdrops (
+	address VARCHAR(42) PRIMARY KEY,
+	voter_amount VARCHAR NOT NULL DEFAULT '0' CHECK(voter_amount <> '' AND voter_amount NOT GLOB '*[^0-9]*'),
+	multisig_signer_amount VARCHAR NOT NULL DEFAULT '0' CHECK(multisig_signer_amount <> '' AND multisig_signer_amount NOT GLOB '*[^0-9]*'),
+	gitcoin_amount VARCHAR NOT NULL DEFAULT '0' CHECK(gitcoin_amount <> '' AND gitcoin_amount NOT GLOB '*[^0-9]*'),
+	active_bridged_amount VARCHAR NOT NULL DEFAULT '0' CHECK(active_bridged_amount <> '' AND active_bridged_amount NOT GLOB '*[^0-9]*'),
+	op_user_amount VARCHAR NOT NULL DEFAULT '0' CHECK(op_user_amount <> '' AND op_user_amount NOT GLOB '*[^0-9]*'),
+	op_repeat_user_amount VARCHAR NOT NULL DEFAULT '0' CHECK(op_repeat_user_amount <> '' AND op_repeat_user_amount NOT GLOB '*[^0-9]*'),
+	op_og_amount VARCHAR NOT NULL DEFAULT '0' CHECK(op_og_amount <> '' AND op_og_amount NOT GLOB '*[^0-9]*'),
+	bonus_amount VARCHAR NOT NULL DEFAULT '0' CHECK(bonus_amount <> '' AND bonus_amount NOT GLOB '*[^0-9]*'),
+	total_amount VARCHAR NOT NULL CHECK(total_amount <> '' AND total_amount NOT GLOB '*[^0-9]*')
+)
+`
+
+// addDepositsReorgedAtColumn tombstones deposits whose L1 block was reorged
+// out. Such deposits are kept for auditing but hidden from regular queries.
+const addDepositsReorgedAtColumn = `
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS reorged_at TIMESTAMPTZ
+`
+
+// addDepositsReorgedAtColumnSQLite is addDepositsReorgedAtColumn for SQLite,
+// which does not support IF NOT EXISTS on columns.
+const addDepositsReorgedAtColumnSQLite = `
+ALTER TABLE deposits ADD COLUMN reorged_at TIMESTAMPTZ
+`
+
+// addL1TokensVerifiedColumn flags tokens from a curated list so that deposits
+// of impersonating tokens can be hidden. ETH is always verified.
+const addL1TokensVerifiedColumn = `
+ALTER TABLE l1_tokens ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT false;
+UPDATE l1_tokens SET verified = true
+WHERE address = '0x0000000000000000000000000000000000000000';
+`
+
+// addL1TokensVerifiedColumnSQLite is addL1TokensVerifiedColumn for SQLite.
+const addL1TokensVerifiedColumnSQLite = `
+ALTER TABLE l1_tokens ADD COLUMN verified BOOLEAN NOT NULL DEFAULT false;
+UPDATE l1_tokens SET verified = true
+WHERE address = '0x0000000000000000000000000000000000000000';
+`
+
+// createBridgedBalancesTable materializes the net amount each address has
+// bridged per L1 token and backfills it from the already indexed history.
+const createBridgedBalancesTable = `
+CREATE TABLE IF NOT EXISTS bridged_balances (
+	address VARCHAR NOT NULL,
+	token VARCHAR NOT NULL,
+	net_amount NUMERIC NOT NULL DEFAULT 0,
+	PRIMARY KEY (address, token)
+);
+INSERT INTO bridged_balances
+	(address, token, net_amount)
+SELECT address, token, SUM(amount) FROM (
+	SELECT from_address AS address, l1_token AS token, CAST(amount AS NUMERIC) AS amount
+	FROM deposits WHERE reorged_at IS NULL
+	UNION ALL
+	SELECT from_address, l1_token, -CAST(amount AS NUMERIC)
+	FROM withdrawals
+) AS flows
+GROUP BY address, token
+ON CONFLICT (address, token) DO NOTHING;
+`
+
+// createBridgedBalancesTableSQLite is createBridgedBalancesTable for SQLite.
+// The backfill is omitted since SQLite databases are always created from
+// scratch.
+const createBridgedBalancesTableSQLite = `
+CREATE TABLE IF NOT EXISTS bridged_balances (
+	address VARCHAR NOT NULL,
+	token VARCHAR NOT NULL,
+	net_amount NUMERIC NOT NULL DEFAULT 0,
+	PRIMARY KEY (address, token)
+)
+`
+
+// createBlockTimestampIndexes speeds up queries over a time window.
+const createBlockTimestampIndexes = `
+CREATE INDEX IF NOT EXISTS l1_blocks_timestamp ON l1_blocks(timestamp);
+CREATE INDEX IF NOT EXISTS l2_blocks_timestamp ON l2_blocks(timestamp);
+`
+
+// addDepositsSourceAddressColumn records the bridge contract each deposit was
+// made through. It is NULL for deposits indexed before it was added.
+const addDepositsSourceAddressColumn = `
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS source_address VARCHAR;
+CREATE INDEX IF NOT EXISTS deposits_source_address ON deposits(source_address);
+`
+
+// addDepositsSourceAddressColumnSQLite is addDepositsSourceAddressColumn for
+// SQLite.
+const addDepositsSourceAddressColumnSQLite = `
+ALTER TABLE deposits ADD COLUMN source_address VARCHAR;
+CREATE INDEX IF NOT EXISTS deposits_source_address ON deposits(source_address);
+`
+
+// convertGUIDsToUUID stores guids as 16 byte UUIDs rather than text, which
+// keeps the primary key indexes compact. Both primary keys are rebuilt by the
+// type change.
+const convertGUIDsToUUID = `
+ALTER TABLE deposits ALTER COLUMN guid TYPE UUID USING guid::UUID;
+ALTER TABLE withdrawals ALTER COLUMN guid TYPE UUID USING guid::UUID;
+`
+
+// createSettingsTable holds named values that must be written atomically
+// with the indexed data, such as the ingestion checkpoint.
+const createSettingsTable = `
+CREATE TABLE IF NOT EXISTS settings (
+	name VARCHAR NOT NULL PRIMARY KEY,
+	value VARCHAR NOT NULL
+)
+`
+
+// makeForeignKeysDeferrable allows the foreign keys to be deferred to the end
+// of a transaction. They are still checked immediately unless deferred.
+// SQLite foreign keys can be deferred without altering them.
+const makeForeignKeysDeferrable = `
+ALTER TABLE deposits ALTER CONSTRAINT deposits_l1_block_hash_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE deposits ALTER CONSTRAINT deposits_l2_block_hash_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE withdrawals ALTER CONSTRAINT withdrawals_l1_block_hash_fkey DEFERRABLE INITIALLY IMMEDIATE;
+ALTER TABLE withdrawals ALTER CONSTRAINT withdrawals_l2_block_hash_fkey DEFERRABLE INITIALLY IMMEDIATE;
+`
+
+// dropTokenForeignKeys lets deposits and withdrawals be indexed before the
+// metadata of their token, so that listings never drop them for lack of it.
+const dropTokenForeignKeys = `
+ALTER TABLE deposits DROP CONSTRAINT IF EXISTS deposits_l1_token_fkey;
+ALTER TABLE withdrawals DROP CONSTRAINT IF EXISTS withdrawals_l2_token_fkey;
+`
+
+// createAirdropHistoryTable records the values every airdrop had before it
+// was corrected, for dispute resolution.
+const createAirdropHistoryTable = `
+CREATE TABLE IF NOT EXISTS airdrop_history (
+	id BIGSERIAL PRIMARY KEY,
+	address VARCHAR(42) NOT NULL,
+	voter_amount VARCHAR NOT NULL,
+	multisig_signer_amount VARCHAR NOT NULL,
+	gitcoin_amount VARCHAR NOT NULL,
+	active_bridged_amount VARCHAR NOT NULL,
+	op_user_amount VARCHAR NOT NULL,
+	op_repeat_user_amount VARCHAR NOT NULL,
+	op_og_amount VARCHAR NOT NULL,
+	bonus_amount VARCHAR NOT NULL,
+	total_amount VARCHAR NOT NULL,
+	replaced_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS airdrop_history_address ON airdrop_history(address);
+`
+
+// createAirdropHistoryTableSQLite is createAirdropHistoryTable for SQLite,
+// where only an INTEGER PRIMARY KEY is assigned automatically.
+const createAirdropHistoryTableSQLite = `
+CREATE TABLE IF NOT EXISTS airdrop_history (
+	id INTEGER PRIMARY KEY,
+	address VARCHAR(42) NOT NULL,
+	voter_amount VARCHAR NOT NULL,
+	multisig_signer_amount VARCHAR NOT NULL,
+	gitcoin_amount VARCHAR NOT NULL,
+	active_bridged_amount VARCHAR NOT NULL,
+	op_user_amount VARCHAR NOT NULL,
+	op_repeat_user_amount VARCHAR NOT NULL,
+	op_og_amount VARCHAR NOT NULL,
+	bonus_amount VARCHAR NOT NULL,
+	total_amount VARCHAR NOT NULL,
+	replaced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS airdrop_history_address ON airdrop_history(address);
+`
+
+// convertAmountsToNumeric stores amounts as numbers wide enough for any
+// uint256, so that they can be aggregated, compared and sorted without
+// casting. Amounts are still written and read as decimal strings. SQLite
+// keeps storing them as text, as its NUMERIC affinity would round amounts
+// beyond the range of a 64-bit integer.
+const convertAmountsToNumeric = `
+ALTER TABLE deposits ALTER COLUMN amount TYPE NUMERIC(78, 0) USING CAST(amount AS NUMERIC(78, 0));
+ALTER TABLE withdrawals ALTER COLUMN amount TYPE NUMERIC(78, 0) USING CAST(amount AS NUMERIC(78, 0));
+`
+
+// createActivityIndexes indexes the columns deposits and withdrawals are
+// filtered and joined on, so that listings by address, token or transaction
+// and the lookups of the activity of a block do not scan the whole tables.
+// Block numbers are already indexed by createL1L2NumberIndex. The indexes are
+// not built concurrently since migrations run in a transaction, so writes to
+// both tables are blocked while they are built.
+const createActivityIndexes = `
+CREATE INDEX IF NOT EXISTS deposits_from_address ON deposits(from_address);
+CREATE INDEX IF NOT EXISTS deposits_to_address ON deposits(to_address);
+CREATE INDEX IF NOT EXISTS deposits_l1_token ON deposits(l1_token);
+CREATE INDEX IF NOT EXISTS deposits_tx_hash ON deposits(tx_hash);
+CREATE INDEX IF NOT EXISTS deposits_l1_block_hash ON deposits(l1_block_hash);
+CREATE INDEX IF NOT EXISTS deposits_l2_block_hash ON deposits(l2_block_hash);
+CREATE INDEX IF NOT EXISTS withdrawals_from_address ON withdrawals(from_address);
+CREATE INDEX IF NOT EXISTS withdrawals_to_address ON withdrawals(to_address);
+CREATE INDEX IF NOT EXISTS withdrawals_l1_token ON withdrawals(l1_token);
+CREATE INDEX IF NOT EXISTS withdrawals_tx_hash ON withdrawals(tx_hash);
+CREATE INDEX IF NOT EXISTS withdrawals_l1_block_hash ON withdrawals(l1_block_hash);
+CREATE INDEX IF NOT EXISTS withdrawals_l2_block_hash ON withdrawals(l2_block_hash);
+`
+
+// lowercaseAddresses stores every address in lowercase hex, the canonical
+// form addresses are written and queried in, see addressString. Deposits,
+// withdrawals and tokens used to be written in whatever form the caller gave,
+// mostly EIP-55 checksummed, while airdrops were already lowercased. Tokens
+// and bridged balances known under several casings are merged.
+const lowercaseAddresses = `
+UPDATE deposits SET
+	from_address = LOWER(from_address), to_address = LOWER(to_address),
+	l1_token = LOWER(l1_token), l2_token = LOWER(l2_token),
+	source_address = LOWER(source_address);
+UPDATE withdrawals SET
+	from_address = LOWER(from_address), to_address = LOWER(to_address),
+	l1_token = LOWER(l1_token), l2_token = LOWER(l2_token);
+DELETE FROM l1_tokens
+WHERE address <> LOWER(address) AND LOWER(address) IN (SELECT address FROM l1_tokens);
+UPDATE l1_tokens SET address = LOWER(address) WHERE address <> LOWER(address);
+DELETE FROM l2_tokens
+WHERE address <> LOWER(address) AND LOWER(address) IN (SELECT address FROM l2_tokens);
+UPDATE l2_tokens SET address = LOWER(address) WHERE address <> LOWER(address);
+INSERT INTO bridged_balances
+	(address, token, net_amount)
+SELECT LOWER(address), LOWER(token), SUM(net_amount) FROM bridged_balances
+WHERE address <> LOWER(address) OR token <> LOWER(token)
+GROUP BY LOWER(address), LOWER(token)
+ON CONFLICT (address, token)
+	DO UPDATE SET net_amount = bridged_balances.net_amount + EXCLUDED.net_amount;
+DELETE FROM bridged_balances WHERE address <> LOWER(address) OR token <> LOWER(token);
+`
+
+// addL1BlocksReorgedAtColumn allows L1 blocks to be tombstoned rather than
+// deleted when they are rolled back, see SoftDeleteReorged. Block numbers
+// then only need to be unique among the blocks that were not rolled back.
+const addL1BlocksReorgedAtColumn = `
+ALTER TABLE l1_blocks ADD COLUMN IF NOT EXISTS reorged_at TIMESTAMPTZ;
+DROP INDEX IF EXISTS l1_blocks_number;
+CREATE UNIQUE INDEX IF NOT EXISTS l1_blocks_number ON l1_blocks(number) WHERE reorged_at IS NULL;
+`
+
+// addL1BlocksReorgedAtColumnSQLite is addL1BlocksReorgedAtColumn for SQLite,
+// which does not support IF NOT EXISTS on columns.
+const addL1BlocksReorgedAtColumnSQLite = `
+ALTER TABLE l1_blocks ADD COLUMN reorged_at TIMESTAMPTZ;
+DROP INDEX IF EXISTS l1_blocks_number;
+CREATE UNIQUE INDEX IF NOT EXISTS l1_blocks_number ON l1_blocks(number) WHERE reorged_at IS NULL;
+`
+
+// createPendingFinalizationsTable records the finalizations of withdrawals
+// that are not indexed yet, so that they can be applied once the L2 block
+// holding them is indexed.
+const createPendingFinalizationsTable = `
+CREATE TABLE IF NOT EXISTS pending_finalizations (
+	tx_hash VARCHAR NOT NULL PRIMARY KEY,
+	l1_block_hash VARCHAR NOT NULL REFERENCES l1_blocks(hash)
+)
+`
+
+// fixAirdropsAmountChecks makes op_repeat_user_amount and total_amount check
+// their own values, where createAirdropsTable had them check op_user_amount
+// and voter_amount. Postgres names a CHECK constraint after the column its
+// expression references, deduplicated with a number, hence the names of the
+// dropped constraints. createAirdropsTableSQLite creates the fixed checks.
+const fixAirdropsAmountChecks = `
+ALTER TABLE airdrops DROP CONSTRAINT IF EXISTS airdrops_op_user_amount_check1;
+ALTER TABLE airdrops DROP CONSTRAINT IF EXISTS airdrops_voter_amount_check1;
+ALTER TABLE airdrops ADD CONSTRAINT airdrops_op_repeat_user_amount_check CHECK(op_repeat_user_amount ~ '^\d+$');
+ALTER TABLE airdrops ADD CONSTRAINT airdrops_total_amount_check CHECK(total_amount ~ '^\d+$');
+`
+
+// noopMigration stands in for migrations that do not apply to a dialect, so
+// that versions stay aligned across dialects.
+const noopMigration = `
+SELECT 1
+`
+
+// SchemaVersion is the schema version this package expects. It MUST be bumped
+// together with every new entry in migrations.
+const SchemaVersion = 26
+
+// migrations lists every schema change in the order it must be applied.
+// Versions MUST be strictly increasing and MUST never be reused. Migrations
+// whose statement is not valid SQLite MUST provide a sqlite statement.
+var migrations = []migration{
+	{version: 1, statement: createL1BlocksTable},
+	{version: 2, statement: createL2BlocksTable},
+	{version: 3, statement: createL1TokensTable},
+	{version: 4, statement: createL2TokensTable},
+	{version: 5, statement: insertETHL1Token},
+	{version: 6, statement: insertETHL2Token},
+	{version: 7, statement: createDepositsTable},
+	{version: 8, statement: createWithdrawalsTable},
+	{version: 9, statement: createL1L2NumberIndex},
+	{version: 10, statement: createAirdropsTable, sqlite: createAirdropsTableSQLite},
+	{version: 11, statement: addDepositsReorgedAtColumn, sqlite: addDepositsReorgedAtColumnSQLite},
+	{version: 12, statement: addL1TokensVerifiedColumn, sqlite: addL1TokensVerifiedColumnSQLite},
+	{version: 13, statement: createBridgedBalancesTable, sqlite: createBridgedBalancesTableSQLite},
+	{version: 14, statement: createBlockTimestampIndexes},
+	{version: 15, statement: addDepositsSourceAddressColumn, sqlite: addDepositsSourceAddressColumnSQLite},
+	{version: 16, statement: convertGUIDsToUUID, sqlite: noopMigration},
+	{version: 17, statement: createSettingsTable},
+	{version: 18, statement: makeForeignKeysDeferrable, sqlite: noopMigration},
+	{version: 19, statement: dropTokenForeignKeys, sqlite: noopMigration},
+	{version: 20, statement: createAirdropHistoryTable, sqlite: createAirdropHistoryTableSQLite},
+	{version: 21, statement: convertAmountsToNumeric, sqlite: noopMigration},
+	{version: 22, statement: createActivityIndexes},
+	{version: 23, statement: lowercaseAddresses},
+	{version: 24, statement: addL1BlocksReorgedAtColumn, sqlite: addL1BlocksReorgedAtColumnSQLite},
+	{version: 25, statement: createPendingFinalizationsTable},
+	{version: 26, statement: fixAirdropsAmountChecks, sqlite: noopMigration},
 }
@@ -0,0 +1,114 @@
+//go:build sqlite
+// +build sqlite
+
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteDatabase opens a migrated in-memory SQLite database private to
+// the test.
+func newSQLiteDatabase(t *testing.T) *db.Database {
+	d, err := db.NewDatabase("sqlite3://file:" + uuid.NewString() + "?mode=memory")
+	require.Nil(t, err)
+	return d
+}
+
+// TestSQLiteListings asserts that deposits and withdrawals, including their
+// related deposits, can be indexed and listed against SQLite.
+func TestSQLiteListings(t *testing.T) {
+	t.Parallel()
+
+	d := newSQLiteDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	deposit := newTestDeposit(common.HexToHash("0xdd01"), 0)
+	deposit.ToAddress = testFromAddress
+	deposit.Data = []byte{0x01, 0x02}
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{deposit},
+	})
+	require.Nil(t, err)
+
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   2,
+		Withdrawals: []db.Withdrawal{newTestWithdrawal(common.HexToHash("0xee01"), 0)},
+	})
+	require.Nil(t, err)
+
+	deposits, err := d.GetDeposits(ctx, db.ActivityFilter{}, db.PaginationParam{Limit: 10})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), deposits.Page.Total)
+	require.Len(t, deposits.Deposits, 1)
+	require.Equal(t, uint64(2), deposits.Deposits[0].DataLength)
+
+	withdrawals, err := d.GetWithdrawals(ctx, db.ActivityFilter{}, db.PaginationParam{
+		Limit:                  10,
+		IncludeRelatedDeposits: true,
+	})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), withdrawals.Page.Total)
+	require.Len(t, withdrawals.Withdrawals, 1)
+	require.Equal(t, deposits.Deposits[0].GUID, withdrawals.Withdrawals[0].RelatedDepositGUID)
+}
+
+// TestSQLiteAirdrops asserts that airdrops can be looked up in batches
+// against SQLite, and that every amount is checked to be a number.
+func TestSQLiteAirdrops(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "indexer.db")
+	d, err := db.NewDatabase("sqlite3://" + path)
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	eligible := common.HexToAddress("0xbb01")
+	ineligible := common.HexToAddress("0xbb02")
+	err = d.AddAirdrops(ctx, []*db.Airdrop{{
+		Address:              eligible.String(),
+		VoterAmount:          "100",
+		MultisigSignerAmount: "0",
+		GitcoinAmount:        "0",
+		ActiveBridgedAmount:  "0",
+		OpUserAmount:         "0",
+		OpRepeatUserAmount:   "5",
+		OpOgAmount:           "0",
+		BonusAmount:          "0",
+		TotalAmount:          "105",
+	}})
+	require.Nil(t, err)
+
+	airdrops, err := d.GetAirdrops(ctx, []common.Address{eligible, ineligible})
+	require.Nil(t, err)
+	require.Len(t, airdrops, 1)
+	require.Equal(t, "105", airdrops[eligible].TotalAmount)
+
+	conn, err := sql.Open("sqlite3", path)
+	require.Nil(t, err)
+	defer conn.Close()
+	for _, column := range []string{"op_repeat_user_amount", "total_amount"} {
+		_, err = conn.Exec("UPDATE airdrops SET "+column+" = 'x' WHERE address = ?",
+			strings.ToLower(eligible.String()))
+		require.NotNil(t, err, column)
+	}
+}
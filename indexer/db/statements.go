@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// statementCache holds the statements of the hottest queries, prepared on
+// first use so that they are parsed once per connection rather than on every
+// call. Statements are keyed by their pool and text, so queries assembled
+// from filters are cached once per combination of clauses, on the primary and
+// on every replica alike. A query that fails to prepare is run ad hoc, and
+// prepared again on its next call, since the failure may be transient.
+type statementCache struct {
+	mu         sync.Mutex
+	disabled   bool
+	statements map[statementKey]*sql.Stmt
+}
+
+type statementKey struct {
+	db    *sql.DB
+	query string
+}
+
+func newStatementCache(disabled bool) *statementCache {
+	return &statementCache{
+		disabled:   disabled,
+		statements: make(map[statementKey]*sql.Stmt),
+	}
+}
+
+// prepare returns the statement of query prepared on db, preparing it if it
+// is not cached yet, or nil if it cannot be prepared. A statement returned
+// along with an error is still usable.
+func (c *statementCache) prepare(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+	if c.disabled {
+		return nil, nil
+	}
+
+	key := statementKey{db: db, query: query}
+	c.mu.Lock()
+	stmt, ok := c.statements[key]
+	c.mu.Unlock()
+	if ok {
+		return stmt, nil
+	}
+
+	// The statement is prepared without holding the lock, so that a slow
+	// preparation does not hold up the other queries. Should another call
+	// have cached it in the meantime, that statement is used instead.
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.statements[key]; ok {
+		return cached, stmt.Close()
+	}
+	c.statements[key] = stmt
+	return stmt, nil
+}
+
+// close closes every cached statement.
+func (c *statementCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for key, stmt := range c.statements {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.statements, key)
+	}
+	return firstErr
+}
+
+// stmt returns the cached statement of query bound to tx, a transaction on
+// db, or nil if it cannot be prepared, in which case the query should be run
+// ad hoc.
+func (d *Database) stmt(ctx context.Context, db *sql.DB, tx *sql.Tx, query string) *sql.Stmt {
+	stmt, err := d.statements.prepare(ctx, db, query)
+	if err != nil {
+		d.logger.Debug("unable to prepare statement, running it ad hoc", "err", err)
+	}
+	if stmt == nil {
+		return nil
+	}
+	return tx.StmtContext(ctx, stmt)
+}
+
+// queryPrepared runs query in tx, a transaction on db, through its cached
+// statement, falling back to running it ad hoc.
+func (d *Database) queryPrepared(ctx context.Context, db *sql.DB, tx *sql.Tx, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := d.stmt(ctx, db, tx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return tx.QueryContext(ctx, query, args...)
+}
+
+// queryRowPrepared is the single row equivalent of queryPrepared.
+func (d *Database) queryRowPrepared(ctx context.Context, db *sql.DB, tx *sql.Tx, query string, args ...interface{}) *sql.Row {
+	if stmt := d.stmt(ctx, db, tx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return tx.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatementCache asserts that hot queries are prepared once and reused,
+// and that a query failing to prepare is run ad hoc instead, without being
+// cached so that it is prepared again on its next call.
+func TestStatementCache(t *testing.T) {
+	t.Parallel()
+
+	d, r := newRecordingDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := d.GetL1TokenByAddress(ctx, ETHL1Token.Address)
+		require.Nil(t, err)
+	}
+	require.Len(t, d.statements.statements, 1)
+	require.Len(t, r.reset(), 1)
+
+	err := d.txn(ctx, func(tx *sql.Tx) error {
+		var one int
+		return d.queryRowPrepared(ctx, d.db, tx, "SELECT $1::INTEGER FROM no_such_table").Scan(&one)
+	})
+	require.NotNil(t, err)
+	_, ok := d.statements.statements[statementKey{db: d.db, query: "SELECT $1::INTEGER FROM no_such_table"}]
+	require.False(t, ok)
+}
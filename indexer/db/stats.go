@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+)
+
+// TokenStat is the volume of an L1 token bridged in one direction.
+type TokenStat struct {
+	// Token is the metadata of the L1 token. Its name and symbol are empty
+	// if the metadata is not indexed.
+	Token *Token `json:"token"`
+
+	// Total is the sum of the amounts bridged, in the token's base units.
+	Total *big.Int `json:"total"`
+
+	// Count is the number of deposits or withdrawals.
+	Count uint64 `json:"count"`
+}
+
+// GetTokenStats returns the total amount and number of deposits of every L1
+// token, largest total first. Deposits invalidated by a reorg are excluded.
+func (d *Database) GetTokenStats(ctx context.Context) ([]TokenStat, error) {
+	const selectDepositTokenStatsStatement = `
+	SELECT
+		deposits.l1_token,
+		COALESCE(l1_tokens.name, ''), COALESCE(l1_tokens.symbol, ''), COALESCE(l1_tokens.decimals, 0),
+		SUM(CAST(deposits.amount AS NUMERIC)) AS total, count(*)
+	FROM deposits
+		LEFT JOIN l1_tokens ON deposits.l1_token=l1_tokens.address
+	WHERE deposits.reorged_at IS NULL
+	GROUP BY deposits.l1_token, l1_tokens.name, l1_tokens.symbol, l1_tokens.decimals
+	ORDER BY total DESC, deposits.l1_token;
+	`
+
+	return d.getTokenStats(ctx, selectDepositTokenStatsStatement)
+}
+
+// GetWithdrawalTokenStats returns the total amount and number of withdrawals
+// of every L1 token, largest total first.
+func (d *Database) GetWithdrawalTokenStats(ctx context.Context) ([]TokenStat, error) {
+	const selectWithdrawalTokenStatsStatement = `
+	SELECT
+		withdrawals.l1_token,
+		COALESCE(l1_tokens.name, ''), COALESCE(l1_tokens.symbol, ''), COALESCE(l1_tokens.decimals, 0),
+		SUM(CAST(withdrawals.amount AS NUMERIC)) AS total, count(*)
+	FROM withdrawals
+		LEFT JOIN l1_tokens ON withdrawals.l1_token=l1_tokens.address
+	GROUP BY withdrawals.l1_token, l1_tokens.name, l1_tokens.symbol, l1_tokens.decimals
+	ORDER BY total DESC, withdrawals.l1_token;
+	`
+
+	return d.getTokenStats(ctx, selectWithdrawalTokenStatsStatement)
+}
+
+func (d *Database) getTokenStats(ctx context.Context, statement string) ([]TokenStat, error) {
+	var stats []TokenStat
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		stats = nil
+
+		rows, err := tx.QueryContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var token Token
+			var total string
+			var count uint64
+			if err := rows.Scan(
+				&token.Address, &token.Name, &token.Symbol, &token.Decimals,
+				&total, &count,
+			); err != nil {
+				return err
+			}
+
+			stat := TokenStat{Token: &token, Count: count}
+			if stat.Total, err = parseAmount(total); err != nil {
+				return fmt.Errorf("unable to parse total of token %s: %w", token.Address, err)
+			}
+			stats = append(stats, stat)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// BridgeTotals is the volume bridged in each direction. Totals add up the
+// amounts of every token in their own base units, so they only make sense as
+// values when a single token is bridged. Tokens breaks them down per token.
+type BridgeTotals struct {
+	DepositTotal    *big.Int `json:"depositTotal"`
+	DepositCount    uint64   `json:"depositCount"`
+	WithdrawalTotal *big.Int `json:"withdrawalTotal"`
+	WithdrawalCount uint64   `json:"withdrawalCount"`
+
+	// Tokens holds the totals of every L1 token bridged in either direction,
+	// ordered by address. It is only set when asked for.
+	Tokens []TokenTotals `json:"tokens,omitempty"`
+}
+
+// TokenTotals is the volume of an L1 token bridged in each direction.
+type TokenTotals struct {
+	// Token is the address of the L1 token.
+	Token string `json:"token"`
+
+	DepositTotal    *big.Int `json:"depositTotal"`
+	DepositCount    uint64   `json:"depositCount"`
+	WithdrawalTotal *big.Int `json:"withdrawalTotal"`
+	WithdrawalCount uint64   `json:"withdrawalCount"`
+}
+
+// GetBridgedTotals returns the total amount and number of deposits and
+// withdrawals, along with their breakdown per token if byToken is set. Either
+// way the totals are aggregated in a single query. Deposits invalidated by a
+// reorg are excluded.
+func (d *Database) GetBridgedTotals(ctx context.Context, byToken bool) (*BridgeTotals, error) {
+	const selectBridgedTotalsStatement = `
+	WITH deposit_totals AS (
+		SELECT COALESCE(SUM(CAST(amount AS NUMERIC)), 0) AS total, count(*) AS count
+		FROM deposits
+		WHERE reorged_at IS NULL
+	), withdrawal_totals AS (
+		SELECT COALESCE(SUM(CAST(amount AS NUMERIC)), 0) AS total, count(*) AS count
+		FROM withdrawals
+	)
+	SELECT deposit_totals.total, deposit_totals.count, withdrawal_totals.total, withdrawal_totals.count
+	FROM deposit_totals, withdrawal_totals;
+	`
+
+	const selectTokenTotalsStatement = `
+	WITH deposit_totals AS (
+		SELECT l1_token AS token, SUM(CAST(amount AS NUMERIC)) AS total, count(*) AS count
+		FROM deposits
+		WHERE reorged_at IS NULL
+		GROUP BY l1_token
+	), withdrawal_totals AS (
+		SELECT l1_token AS token, SUM(CAST(amount AS NUMERIC)) AS total, count(*) AS count
+		FROM withdrawals
+		GROUP BY l1_token
+	), tokens AS (
+		SELECT token FROM deposit_totals
+		UNION
+		SELECT token FROM withdrawal_totals
+	)
+	SELECT
+		tokens.token,
+		COALESCE(deposit_totals.total, 0), COALESCE(deposit_totals.count, 0),
+		COALESCE(withdrawal_totals.total, 0), COALESCE(withdrawal_totals.count, 0)
+	FROM tokens
+		LEFT JOIN deposit_totals ON deposit_totals.token = tokens.token
+		LEFT JOIN withdrawal_totals ON withdrawal_totals.token = tokens.token
+	ORDER BY 1;
+	`
+
+	var totals *BridgeTotals
+	err := d.readTxn(ctx, func(tx *sql.Tx) error {
+		totals = &BridgeTotals{
+			DepositTotal:    new(big.Int),
+			WithdrawalTotal: new(big.Int),
+		}
+
+		if !byToken {
+			var depositTotal, withdrawalTotal string
+			err := tx.QueryRowContext(ctx, selectBridgedTotalsStatement).Scan(
+				&depositTotal, &totals.DepositCount,
+				&withdrawalTotal, &totals.WithdrawalCount,
+			)
+			if err != nil {
+				return err
+			}
+			if totals.DepositTotal, err = parseAmount(depositTotal); err != nil {
+				return err
+			}
+			totals.WithdrawalTotal, err = parseAmount(withdrawalTotal)
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, selectTokenTotalsStatement)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var token TokenTotals
+			var depositTotal, withdrawalTotal string
+			if err := rows.Scan(
+				&token.Token,
+				&depositTotal, &token.DepositCount,
+				&withdrawalTotal, &token.WithdrawalCount,
+			); err != nil {
+				return err
+			}
+			if token.DepositTotal, err = parseAmount(depositTotal); err != nil {
+				return fmt.Errorf("unable to parse deposit total of token %s: %w", token.Token, err)
+			}
+			if token.WithdrawalTotal, err = parseAmount(withdrawalTotal); err != nil {
+				return fmt.Errorf("unable to parse withdrawal total of token %s: %w", token.Token, err)
+			}
+
+			totals.DepositTotal.Add(totals.DepositTotal, token.DepositTotal)
+			totals.DepositCount += token.DepositCount
+			totals.WithdrawalTotal.Add(totals.WithdrawalTotal, token.WithdrawalTotal)
+			totals.WithdrawalCount += token.WithdrawalCount
+			totals.Tokens = append(totals.Tokens, token)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
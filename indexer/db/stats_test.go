@@ -0,0 +1,141 @@
+package db_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetTokenStats asserts that the amounts bridged are summed and counted
+// per token, largest total first, beyond the range of a 64-bit integer.
+func TestGetTokenStats(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	usdc := common.HexToAddress("0xcc01")
+	require.Nil(t, d.AddL1Token(ctx, usdc.String(), &db.Token{Name: "USD Coin", Symbol: "USDC", Decimals: 6}))
+
+	large, ok := new(big.Int).SetString("10000000000000000000000", 10)
+	require.True(t, ok)
+
+	var deposits []db.Deposit
+	for i, amount := range []*big.Int{big.NewInt(5), large, large} {
+		deposit := newTestDeposit(common.BigToHash(big.NewInt(int64(0xff00+i))), uint(i))
+		deposit.Amount = amount
+		if i > 0 {
+			deposit.L1Token = usdc
+		}
+		deposits = append(deposits, deposit)
+	}
+	err := d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   deposits,
+	})
+	require.Nil(t, err)
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.Amount = big.NewInt(3)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	stats, err := d.GetTokenStats(ctx)
+	require.Nil(t, err)
+	require.Len(t, stats, 2)
+	require.Equal(t, "USDC", stats[0].Token.Symbol)
+	require.Equal(t, new(big.Int).Mul(large, big.NewInt(2)).String(), stats[0].Total.String())
+	require.Equal(t, uint64(2), stats[0].Count)
+	require.Equal(t, "ETH", stats[1].Token.Symbol)
+	require.Equal(t, "5", stats[1].Total.String())
+	require.Equal(t, uint64(1), stats[1].Count)
+
+	stats, err = d.GetWithdrawalTokenStats(ctx)
+	require.Nil(t, err)
+	require.Len(t, stats, 1)
+	require.Equal(t, db.ETHL1Token.Address, stats[0].Token.Address)
+	require.Equal(t, "3", stats[0].Total.String())
+	require.Equal(t, uint64(1), stats[0].Count)
+}
+
+// TestGetBridgedTotals asserts that the grand totals match the sum of the
+// per-token totals, and that tokens bridged in a single direction are
+// broken down too.
+func TestGetBridgedTotals(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	usdc := common.HexToAddress("0xcc01")
+
+	totals, err := d.GetBridgedTotals(ctx, false)
+	require.Nil(t, err)
+	require.Equal(t, 0, totals.DepositTotal.Sign())
+	require.Equal(t, uint64(0), totals.DepositCount)
+
+	large, ok := new(big.Int).SetString("10000000000000000000000", 10)
+	require.True(t, ok)
+
+	eth := newTestDeposit(common.HexToHash("0xff01"), 0)
+	eth.Amount = big.NewInt(5)
+	token := newTestDeposit(common.HexToHash("0xff02"), 1)
+	token.L1Token = usdc
+	token.Amount = large
+	err = d.AddIndexedL1Block(ctx, &db.IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  1,
+		Deposits:   []db.Deposit{eth, token},
+	})
+	require.Nil(t, err)
+
+	withdrawal := newTestWithdrawal(common.HexToHash("0xee01"), 0)
+	withdrawal.Amount = big.NewInt(3)
+	err = d.AddIndexedL2Block(ctx, &db.IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []db.Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+
+	expDepositTotal := new(big.Int).Add(large, big.NewInt(5))
+	for _, byToken := range []bool{false, true} {
+		totals, err := d.GetBridgedTotals(ctx, byToken)
+		require.Nil(t, err)
+		require.Equal(t, expDepositTotal.String(), totals.DepositTotal.String())
+		require.Equal(t, uint64(2), totals.DepositCount)
+		require.Equal(t, "3", totals.WithdrawalTotal.String())
+		require.Equal(t, uint64(1), totals.WithdrawalCount)
+
+		if !byToken {
+			require.Empty(t, totals.Tokens)
+			continue
+		}
+		require.Len(t, totals.Tokens, 2)
+		require.Equal(t, db.ETHL1Token.Address, totals.Tokens[0].Token)
+		require.Equal(t, "5", totals.Tokens[0].DepositTotal.String())
+		require.Equal(t, "3", totals.Tokens[0].WithdrawalTotal.String())
+		require.Equal(t, usdc.String(), totals.Tokens[1].Token)
+		require.Equal(t, large.String(), totals.Tokens[1].DepositTotal.String())
+		require.Equal(t, uint64(0), totals.Tokens[1].WithdrawalCount)
+	}
+}
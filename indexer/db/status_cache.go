@@ -0,0 +1,100 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultWithdrawalStatusCacheSize is the number of finalized withdrawal
+// statuses kept in memory when the Database is not configured with
+// WithdrawalStatusCacheSize.
+const DefaultWithdrawalStatusCacheSize = 10000
+
+// withdrawalStatusCache holds the statuses of finalized withdrawals keyed by
+// transaction hash. A finalized withdrawal only changes if the L1 block
+// finalizing it is reorged out, so entries never expire and the cache is
+// purged instead whenever indexed blocks are rolled back. Once full, an
+// arbitrary entry is evicted for every new one. Like the token cache, it drops
+// statuses read before the last purge, see tokenCache.
+type withdrawalStatusCache struct {
+	mu         sync.RWMutex
+	size       int
+	generation uint64
+	statuses   map[common.Hash]*WithdrawalJSON
+}
+
+func newWithdrawalStatusCache(size int) *withdrawalStatusCache {
+	return &withdrawalStatusCache{
+		size:     size,
+		statuses: make(map[common.Hash]*WithdrawalJSON),
+	}
+}
+
+// get returns a copy of the cached status of the withdrawal, so that callers
+// may modify it freely.
+func (c *withdrawalStatusCache) get(hash common.Hash) (*WithdrawalJSON, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	withdrawal, ok := c.statuses[hash]
+	if !ok {
+		return nil, false
+	}
+	return copyWithdrawalJSON(withdrawal), true
+}
+
+// currentGeneration returns the generation to pass to add for a status read
+// from the database after this call.
+func (c *withdrawalStatusCache) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.generation
+}
+
+// add caches a copy of the status of a finalized withdrawal, unless the cache
+// was purged since the given generation, in which case the status may be
+// stale.
+func (c *withdrawalStatusCache) add(hash common.Hash, withdrawal *WithdrawalJSON, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if _, ok := c.statuses[hash]; !ok && len(c.statuses) >= c.size {
+		for evicted := range c.statuses {
+			delete(c.statuses, evicted)
+			break
+		}
+	}
+	c.statuses[hash] = copyWithdrawalJSON(withdrawal)
+}
+
+// purge drops every cached status.
+func (c *withdrawalStatusCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.statuses = make(map[common.Hash]*WithdrawalJSON)
+}
+
+func copyWithdrawalJSON(withdrawal *WithdrawalJSON) *WithdrawalJSON {
+	copied := *withdrawal
+	if withdrawal.L2Token != nil {
+		l2Token := *withdrawal.L2Token
+		copied.L2Token = &l2Token
+	}
+	if withdrawal.L1BlockNumber != nil {
+		l1BlockNumber := *withdrawal.L1BlockNumber
+		copied.L1BlockNumber = &l1BlockNumber
+	}
+	if withdrawal.L1BlockTimestamp != nil {
+		l1BlockTimestamp := *withdrawal.L1BlockTimestamp
+		copied.L1BlockTimestamp = &l1BlockTimestamp
+	}
+	copied.Data = append([]byte(nil), withdrawal.Data...)
+	return &copied
+}
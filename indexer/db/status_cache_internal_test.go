@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetWithdrawalStatusCache asserts that finalized withdrawal statuses are
+// served from the cache on subsequent calls, that pending ones always hit the
+// database, and that a rollback purges the cache.
+func TestGetWithdrawalStatusCache(t *testing.T) {
+	t.Parallel()
+
+	d, r := newRecordingDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	queries := func() int {
+		var n int
+		for _, statement := range r.reset() {
+			if strings.Contains(statement, "FROM withdrawals") {
+				n++
+			}
+		}
+		return n
+	}
+
+	withdrawal := Withdrawal{
+		TxHash:      common.HexToHash("0xee01"),
+		L1Token:     common.HexToAddress(ETHL1Token.Address),
+		L2Token:     ETHL2Address,
+		FromAddress: common.HexToAddress("0xaa01"),
+		ToAddress:   common.HexToAddress("0xaa02"),
+		Amount:      big.NewInt(1),
+		Data:        []byte{},
+	}
+	err := d.AddIndexedL2Block(ctx, &IndexedL2Block{
+		Hash:        common.HexToHash("0x11"),
+		ParentHash:  common.HexToHash("0x10"),
+		Number:      1,
+		Timestamp:   1,
+		Withdrawals: []Withdrawal{withdrawal},
+	})
+	require.Nil(t, err)
+	err = d.AddIndexedL1Block(ctx, &IndexedL1Block{
+		Hash:       common.HexToHash("0x01"),
+		ParentHash: common.HexToHash("0x00"),
+		Number:     1,
+		Timestamp:  2,
+	})
+	require.Nil(t, err)
+	r.reset()
+
+	for i := 0; i < 2; i++ {
+		status, err := d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+		require.Nil(t, err)
+		require.Nil(t, status.L1BlockNumber)
+	}
+	require.Equal(t, 2, queries())
+
+	err = d.MarkWithdrawalFinalized(ctx, withdrawal.TxHash, common.HexToHash("0x01"))
+	require.Nil(t, err)
+	r.reset()
+
+	status, err := d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	status.L2Token.Symbol = "modified"
+	status, err = d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), *status.L1BlockNumber)
+	require.Equal(t, "ETH", status.L2Token.Symbol)
+	require.Equal(t, 1, queries())
+
+	require.Nil(t, d.DeleteL1BlocksFrom(ctx, 1))
+	r.reset()
+
+	status, err = d.GetWithdrawalStatus(ctx, withdrawal.TxHash)
+	require.Nil(t, err)
+	require.Nil(t, status.L1BlockNumber)
+	require.Equal(t, 1, queries())
+
+	_, err = d.GetWithdrawalStatus(ctx, common.HexToHash("0xee02"))
+	require.True(t, errors.Is(err, ErrWithdrawalNotFound))
+}
+
+// TestWithdrawalStatusCacheStaleAdd asserts that a status read before the
+// cache was purged is not cached, while one read afterwards is.
+func TestWithdrawalStatusCacheStaleAdd(t *testing.T) {
+	t.Parallel()
+
+	c := newWithdrawalStatusCache(2)
+	hash := common.HexToHash("0xee01")
+
+	generation := c.currentGeneration()
+	c.purge()
+	c.add(hash, &WithdrawalJSON{GUID: "old"}, generation)
+	_, ok := c.get(hash)
+	require.False(t, ok)
+
+	c.add(hash, &WithdrawalJSON{GUID: "new"}, c.currentGeneration())
+	withdrawal, ok := c.get(hash)
+	require.True(t, ok)
+	require.Equal(t, "new", withdrawal.GUID)
+}
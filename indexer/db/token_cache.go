@@ -0,0 +1,189 @@
+package db
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTokenCacheTTL is how long token metadata is cached when the Database
+// is configured with a TokenCacheSize but no TokenCacheTTL.
+const DefaultTokenCacheTTL = 10 * time.Minute
+
+// TokenCacheStats counts the lookups of GetL1TokenByAddress and
+// GetL2TokenByAddress served from the token cache and those that hit the
+// database.
+type TokenCacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// tokenCache holds the metadata of the most recently looked up tokens of one
+// layer keyed by address. Entries expire after the configured TTL and are
+// invalidated whenever tokens are written through this Database, so the TTL
+// only bounds how stale entries may get when tokens are written by another
+// process. Once full, the least recently used entry is evicted for every new
+// one. A cache of size zero is disabled.
+//
+// A lookup may read a token before a concurrent write commits and cache it
+// after the write invalidated it. To keep such stale tokens out, every
+// invalidation bumps the generation of the cache, and add drops tokens read
+// in an earlier generation.
+type tokenCache struct {
+	// hits and misses are accessed atomically, so they are kept first for
+	// 64-bit alignment on 32-bit platforms.
+	hits   uint64
+	misses uint64
+
+	mu         sync.Mutex
+	size       int
+	ttl        time.Duration
+	now        func() time.Time
+	generation uint64
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type tokenCacheEntry struct {
+	address string
+	token   Token
+	expires time.Time
+}
+
+func newTokenCache(size int, ttl time.Duration) *tokenCache {
+	return &tokenCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the cached token at address, so that callers may
+// modify it freely.
+func (c *tokenCache) get(address string) (*Token, bool) {
+	if c.size <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[address]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+	entry := element.Value.(*tokenCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, address)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	atomic.AddUint64(&c.hits, 1)
+	token := entry.token
+	return &token, true
+}
+
+// currentGeneration returns the generation to pass to add for a token read
+// from the database after this call.
+func (c *tokenCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// add caches a copy of the token at address, unless the cache was
+// invalidated since the given generation, in which case the token may be
+// stale.
+func (c *tokenCache) add(address string, token *Token, generation uint64) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	entry := &tokenCacheEntry{
+		address: address,
+		token:   *token,
+		expires: c.now().Add(c.ttl),
+	}
+	if element, ok := c.entries[address]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenCacheEntry).address)
+	}
+	c.entries[address] = c.order.PushFront(entry)
+}
+
+// remove drops the cached token at address, if any.
+func (c *tokenCache) remove(address string) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if element, ok := c.entries[address]; ok {
+		c.order.Remove(element)
+		delete(c.entries, address)
+	}
+}
+
+// purge drops every cached token.
+func (c *tokenCache) purge() {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// stats returns the number of hits and misses so far.
+func (c *tokenCache) stats() TokenCacheStats {
+	return TokenCacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+// tokenCache returns the cache of the given token table.
+func (d *Database) tokenCache(table string) *tokenCache {
+	if table == "l2_tokens" {
+		return d.l2Tokens
+	}
+	return d.l1Tokens
+}
+
+// TokenCacheStats returns the hits and misses of the token cache across both
+// layers. Both are zero when the cache is disabled.
+func (d *Database) TokenCacheStats() TokenCacheStats {
+	l1, l2 := d.l1Tokens.stats(), d.l2Tokens.stats()
+	return TokenCacheStats{
+		Hits:   l1.Hits + l2.Hits,
+		Misses: l1.Misses + l2.Misses,
+	}
+}
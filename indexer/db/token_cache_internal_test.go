@@ -0,0 +1,84 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenCache asserts that the least recently used token is evicted once
+// the cache is full, that entries expire after the TTL, and that hits and
+// misses are counted.
+func TestTokenCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	c := newTokenCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.add("0xcc01", &Token{Symbol: "FST"}, c.currentGeneration())
+	c.add("0xcc02", &Token{Symbol: "SND"}, c.currentGeneration())
+
+	token, ok := c.get("0xcc01")
+	require.True(t, ok)
+	require.Equal(t, "FST", token.Symbol)
+
+	token.Symbol = "MOD"
+	token, ok = c.get("0xcc01")
+	require.True(t, ok)
+	require.Equal(t, "FST", token.Symbol)
+
+	c.add("0xcc03", &Token{Symbol: "TRD"}, c.currentGeneration())
+	_, ok = c.get("0xcc02")
+	require.False(t, ok)
+	_, ok = c.get("0xcc03")
+	require.True(t, ok)
+
+	c.remove("0xcc03")
+	_, ok = c.get("0xcc03")
+	require.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = c.get("0xcc01")
+	require.False(t, ok)
+
+	require.Equal(t, TokenCacheStats{Hits: 3, Misses: 3}, c.stats())
+}
+
+// TestTokenCacheDisabled asserts that a cache of size zero caches nothing and
+// counts nothing.
+func TestTokenCacheDisabled(t *testing.T) {
+	t.Parallel()
+
+	c := newTokenCache(0, time.Minute)
+	c.add("0xcc01", &Token{Symbol: "FST"}, c.currentGeneration())
+	_, ok := c.get("0xcc01")
+	require.False(t, ok)
+	require.Equal(t, TokenCacheStats{}, c.stats())
+}
+
+// TestTokenCacheStaleAdd asserts that a token read before the cache was
+// invalidated is not cached, while one read afterwards is.
+func TestTokenCacheStaleAdd(t *testing.T) {
+	t.Parallel()
+
+	c := newTokenCache(2, time.Minute)
+
+	generation := c.currentGeneration()
+	c.remove("0xcc01")
+	c.add("0xcc01", &Token{Symbol: "OLD"}, generation)
+	_, ok := c.get("0xcc01")
+	require.False(t, ok)
+
+	generation = c.currentGeneration()
+	c.purge()
+	c.add("0xcc01", &Token{Symbol: "OLD"}, generation)
+	_, ok = c.get("0xcc01")
+	require.False(t, ok)
+
+	c.add("0xcc01", &Token{Symbol: "NEW"}, c.currentGeneration())
+	token, ok := c.get("0xcc01")
+	require.True(t, ok)
+	require.Equal(t, "NEW", token.Symbol)
+}
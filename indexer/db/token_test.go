@@ -0,0 +1,196 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRefreshL1TokenMetadata asserts that only tokens that were added or whose
+// metadata changed are reported.
+func TestRefreshL1TokenMetadata(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	unchanged := &db.Token{Address: "0xcc01", Name: "Unchanged", Symbol: "UNC", Decimals: 18}
+	renamed := &db.Token{Address: "0xcc02", Name: "Renamed", Symbol: "OLD", Decimals: 18}
+	for _, token := range []*db.Token{unchanged, renamed} {
+		require.Nil(t, d.AddL1Token(ctx, token.Address, token))
+	}
+
+	changed, err := d.RefreshL1TokenMetadata(ctx, map[string]*db.Token{
+		"0xcc01": {Address: "0xcc01", Name: "Unchanged", Symbol: "UNC", Decimals: 18},
+		"0xcc02": {Address: "0xcc02", Name: "Renamed", Symbol: "NEW", Decimals: 18},
+		"0xcc03": {Address: "0xcc03", Name: "Added", Symbol: "ADD", Decimals: 6},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"0xcc02", "0xcc03"}, changed)
+
+	token, err := d.GetL1TokenByAddress(ctx, "0xcc02")
+	require.Nil(t, err)
+	require.Equal(t, "NEW", token.Symbol)
+
+	changed, err = d.RefreshL1TokenMetadata(ctx, map[string]*db.Token{
+		"0xcc02": {Address: "0xcc02", Name: "Renamed", Symbol: "NEW", Decimals: 18},
+	})
+	require.Nil(t, err)
+	require.Empty(t, changed)
+}
+
+// TestAddL1Tokens asserts that a batch of tokens is inserted at once, and that
+// re-adding known tokens is not an error and is not counted.
+func TestAddL1Tokens(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	inserted, err := d.AddL1Tokens(ctx, map[string]*db.Token{
+		"0xcc01": {Address: "0xcc01", Name: "First", Symbol: "FST", Decimals: 18},
+		"0xcc02": {Address: "0xcc02", Name: "Second", Symbol: "SND", Decimals: 6},
+	})
+	require.Nil(t, err)
+	require.Equal(t, int64(2), inserted)
+
+	inserted, err = d.AddL1Tokens(ctx, map[string]*db.Token{
+		"0xcc02": {Address: "0xcc02", Name: "Renamed", Symbol: "NEW", Decimals: 6},
+		"0xcc03": {Address: "0xcc03", Name: "Third", Symbol: "TRD", Decimals: 8},
+	})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), inserted)
+
+	token, err := d.GetL1TokenByAddress(ctx, "0xcc02")
+	require.Nil(t, err)
+	require.Equal(t, "SND", token.Symbol)
+
+	token, err = d.GetL1TokenByAddress(ctx, "0xcc03")
+	require.Nil(t, err)
+	require.Equal(t, "TRD", token.Symbol)
+
+	inserted, err = d.AddL2Tokens(ctx, map[string]*db.Token{
+		"0xcc01": {Address: "0xcc01", Name: "First", Symbol: "FST", Decimals: 18},
+	})
+	require.Nil(t, err)
+	require.Equal(t, int64(1), inserted)
+}
+
+// TestUpsertL1Token asserts that upserting a known token overwrites its
+// metadata, while AddL1Token still rejects the duplicate.
+func TestUpsertL1Token(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	token := &db.Token{Address: "0xcc01", Name: "Proxy", Symbol: "OLD", Decimals: 18}
+	require.Nil(t, d.UpsertL1Token(ctx, token.Address, token))
+
+	upgraded := &db.Token{Address: "0xcc01", Name: "Upgraded", Symbol: "NEW", Decimals: 6}
+	require.Nil(t, d.UpsertL1Token(ctx, upgraded.Address, upgraded))
+	require.NotNil(t, d.AddL1Token(ctx, upgraded.Address, upgraded))
+
+	stored, err := d.GetL1TokenByAddress(ctx, "0xcc01")
+	require.Nil(t, err)
+	require.Equal(t, "Upgraded", stored.Name)
+	require.Equal(t, "NEW", stored.Symbol)
+	require.Equal(t, uint8(6), stored.Decimals)
+
+	require.Nil(t, d.UpsertL2Token(ctx, upgraded.Address, upgraded))
+}
+
+// TestGetL1TokensByAddresses asserts that tokens are returned keyed by address
+// and that addresses that are not indexed are left out.
+func TestGetL1TokensByAddresses(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	first := &db.Token{Address: "0xcc01", Name: "First", Symbol: "FST", Decimals: 18}
+	second := &db.Token{Address: "0xcc02", Name: "Second", Symbol: "SND", Decimals: 6}
+	for _, token := range []*db.Token{first, second} {
+		require.Nil(t, d.AddL1Token(ctx, token.Address, token))
+	}
+
+	tokens, err := d.GetL1TokensByAddresses(ctx, []string{"0xcc01", "0xcc02", "0xcc03"})
+	require.Nil(t, err)
+	require.Equal(t, map[string]*db.Token{"0xcc01": first, "0xcc02": second}, tokens)
+
+	tokens, err = d.GetL2TokensByAddresses(ctx, []string{"0xcc01"})
+	require.Nil(t, err)
+	require.Empty(t, tokens)
+}
+
+// TestGetAllL1Tokens asserts that tokens are paginated in symbol order and
+// filtered by symbol prefix regardless of case.
+func TestGetAllL1Tokens(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	usdc := &db.Token{Address: "0xcc01", Name: "USD Coin", Symbol: "USDC", Decimals: 6}
+	dai := &db.Token{Address: "0xcc02", Name: "Dai", Symbol: "DAI", Decimals: 18}
+	usdt := &db.Token{Address: "0xcc03", Name: "Tether", Symbol: "USDT", Decimals: 6}
+	for _, token := range []*db.Token{usdc, dai, usdt} {
+		require.Nil(t, d.AddL1Token(ctx, token.Address, token))
+	}
+
+	tokens, err := d.GetAllL1Tokens(ctx, "us", db.PaginationParam{Limit: 1})
+	require.Nil(t, err)
+	require.Equal(t, []db.Token{*usdc}, tokens.Tokens)
+	require.Equal(t, uint64(2), tokens.Page.Total)
+
+	tokens, err = d.GetAllL1Tokens(ctx, "us", db.PaginationParam{Limit: 1, Offset: 1, SkipTotal: true})
+	require.Nil(t, err)
+	require.Equal(t, []db.Token{*usdt}, tokens.Tokens)
+	require.False(t, tokens.Page.HasMore)
+
+	tokens, err = d.GetAllL1Tokens(ctx, "%", db.PaginationParam{})
+	require.Nil(t, err)
+	require.Empty(t, tokens.Tokens)
+	require.Zero(t, tokens.Page.Total)
+
+	tokens, err = d.GetAllL2Tokens(ctx, "DAI", db.PaginationParam{})
+	require.Nil(t, err)
+	require.Empty(t, tokens.Tokens)
+}
+
+// TestTokenCacheInvalidation asserts that cached tokens are served without
+// hitting the database and invalidated when they are written.
+func TestTokenCacheInvalidation(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDatabaseWithConfig(db.DatabaseConfig{
+		DSN:            newTestDSN(t),
+		TokenCacheSize: 10,
+	})
+	require.Nil(t, err)
+	defer d.Close()
+
+	ctx := context.Background()
+	require.Nil(t, d.AddL1Token(ctx, "0xcc01", &db.Token{Name: "Token", Symbol: "OLD", Decimals: 18}))
+
+	for i := 0; i < 2; i++ {
+		token, err := d.GetL1TokenByAddress(ctx, "0xcc01")
+		require.Nil(t, err)
+		require.Equal(t, "OLD", token.Symbol)
+	}
+	require.Equal(t, db.TokenCacheStats{Hits: 1, Misses: 1}, d.TokenCacheStats())
+
+	require.Nil(t, d.UpsertL1Token(ctx, "0xcc01", &db.Token{Name: "Token", Symbol: "NEW", Decimals: 18}))
+
+	token, err := d.GetL1TokenByAddress(ctx, "0xcc01")
+	require.Nil(t, err)
+	require.Equal(t, "NEW", token.Symbol)
+	require.Equal(t, db.TokenCacheStats{Hits: 1, Misses: 2}, d.TokenCacheStats())
+}
@@ -1,11 +1,131 @@
 package db
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
-func txn(db *sql.DB, apply func(*sql.Tx) error) error {
-	tx, err := db.Begin()
+	"github.com/lib/pq"
+)
+
+const (
+	// serializationFailure and deadlockDetected are the Postgres error codes
+	// of transactions aborted by concurrent transactions. Such transactions
+	// may succeed if simply run again.
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// txn runs apply in a transaction on the primary database, retrying the
+// whole transaction with exponential backoff while it fails with a retryable
+// error, up to the configured number of attempts. apply must therefore reset
+// any state it accumulates.
+func (d *Database) txn(ctx context.Context, apply func(*sql.Tx) error) error {
+	return d.txnOn(ctx, d.db, apply)
+}
+
+// readTxn is txn for transactions that only read, which run on the next read
+// replica if any is configured. Replicas lag behind the primary, so reads
+// that must observe the writes of this process, such as those the indexer
+// resumes from, use txn instead.
+func (d *Database) readTxn(ctx context.Context, apply func(*sql.Tx) error) error {
+	return d.txnOn(ctx, d.reader(), apply)
+}
+
+// txnOn is txn on the given pool.
+func (d *Database) txnOn(ctx context.Context, db *sql.DB, apply func(*sql.Tx) error) error {
+	defer d.logSlowQuery(time.Now(), nil)
+
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	return d.retry(ctx, func() error {
+		return txn(ctx, db, func(tx *sql.Tx) error {
+			if err := d.setStatementTimeout(ctx, tx); err != nil {
+				return err
+			}
+			return apply(tx)
+		})
+	})
+}
+
+// retry calls attempt until it succeeds, fails with an error that is not
+// retryable or has been called TxnMaxAttempts times, and returns its last
+// error. It gives up early with ctx.Err() if ctx is done while backing off.
+func (d *Database) retry(ctx context.Context, attempt func() error) error {
+	backoff := d.txnRetryBackoff
+	for i := 1; ; i++ {
+		err := attempt()
+		if err == nil || i >= d.txnMaxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		d.logger.Warn("retrying transaction", "attempt", i, "backoff", backoff, "err", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// withQueryTimeout returns ctx bounded by the configured QueryTimeout, if any.
+// The earlier of the deadline of ctx and the timeout applies.
+func (d *Database) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.queryTimeout)
+}
+
+// setStatementTimeout bounds the statements of tx by the time left until the
+// deadline set by withQueryTimeout. Statements run with the context of the
+// caller rather than ctx, so without it a statement outliving the deadline
+// would hold its connection until it completes.
+func (d *Database) setStatementTimeout(ctx context.Context, tx *sql.Tx) error {
+	const setStatementTimeoutStatement = `
+	SET LOCAL statement_timeout = %d
+	`
+
+	if d.queryTimeout <= 0 || d.dialect != dialectPostgres {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	milliseconds := time.Until(deadline).Milliseconds()
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(setStatementTimeoutStatement, milliseconds))
+	return err
+}
+
+// isRetryable reports whether err aborted a transaction because of a
+// concurrent transaction, in which case the transaction may be retried.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+}
+
+// txn runs apply in a transaction bound to ctx. If ctx is done before the
+// transaction commits, the transaction is rolled back and ctx.Err() is
+// returned, rather than the driver error of the aborted statement.
+func txn(ctx context.Context, db *sql.DB, apply func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return contextErr(ctx, err)
 	}
 	defer func() {
 		if p := recover(); p != nil {
@@ -19,8 +139,17 @@ func txn(db *sql.DB, apply func(*sql.Tx) error) error {
 	if err != nil {
 		// Don't swallow application error
 		_ = tx.Rollback()
-		return err
+		return contextErr(ctx, err)
 	}
 
-	return tx.Commit()
+	return contextErr(ctx, tx.Commit())
+}
+
+// contextErr returns ctx.Err() in place of err if ctx is done, so that callers
+// can match cancellation with errors.Is(err, context.Canceled).
+func contextErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
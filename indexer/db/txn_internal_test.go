@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+const testDSN = "host=0.0.0.0 port=5432 user=postgres password=password sslmode=disable"
+
+// TestTxnCanceled asserts that cancelling the context aborts an in-flight
+// query and surfaces context.Canceled.
+func TestTxnCanceled(t *testing.T) {
+	t.Parallel()
+
+	conn, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err = txn(ctx, conn, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "SELECT pg_sleep(10)")
+		return err
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	err = txn(ctx, conn, func(tx *sql.Tx) error {
+		return nil
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+// TestTxnRetry asserts that transactions failing with a serialization failure
+// or a deadlock are retried up to the configured number of attempts, and that
+// other errors are returned immediately.
+func TestTxnRetry(t *testing.T) {
+	t.Parallel()
+
+	d := &Database{
+		logger:          log.New(),
+		txnMaxAttempts:  3,
+		txnRetryBackoff: time.Millisecond,
+	}
+	ctx := context.Background()
+
+	attempts := 0
+	err := d.retry(ctx, func() error {
+		attempts++
+		if attempts < 3 {
+			return &pq.Error{Code: serializationFailure}
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = d.retry(ctx, func() error {
+		attempts++
+		return fmt.Errorf("unable to insert: %w", &pq.Error{Code: deadlockDetected})
+	})
+	require.True(t, isRetryable(err))
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = d.retry(ctx, func() error {
+		attempts++
+		return &pq.Error{Code: uniqueViolation}
+	})
+	require.False(t, isRetryable(err))
+	require.Equal(t, 1, attempts)
+
+	d.txnRetryBackoff = time.Hour
+	canceled, cancel := context.WithCancel(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err = d.retry(canceled, func() error {
+		return &pq.Error{Code: serializationFailure}
+	})
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+// TestWithQueryTimeout asserts that a context without a deadline is bounded
+// by the query timeout, that an earlier deadline is kept, and that contexts
+// are left alone without a timeout.
+func TestWithQueryTimeout(t *testing.T) {
+	t.Parallel()
+
+	d := &Database{queryTimeout: time.Minute}
+
+	ctx, cancel := d.withQueryTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.True(t, deadline.After(time.Now().Add(55*time.Second)))
+	require.False(t, deadline.After(time.Now().Add(time.Minute)))
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	parentDeadline, _ := parent.Deadline()
+	ctx, cancel = d.withQueryTimeout(parent)
+	defer cancel()
+	deadline, ok = ctx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, parentDeadline, deadline)
+
+	d = &Database{}
+	ctx, cancel = d.withQueryTimeout(context.Background())
+	defer cancel()
+	_, ok = ctx.Deadline()
+	require.False(t, ok)
+}
+
+// TestTxnQueryTimeout asserts that a transaction given a context without a
+// deadline is aborted once the query timeout elapses, even if its statements
+// run without the context.
+func TestTxnQueryTimeout(t *testing.T) {
+	t.Parallel()
+
+	conn, err := sql.Open("postgres", testDSN)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	d := &Database{
+		db:             conn,
+		logger:         log.New(),
+		txnMaxAttempts: 1,
+		queryTimeout:   100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err = d.txn(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.Exec("SELECT pg_sleep(10)")
+		return err
+	})
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -19,6 +21,40 @@ type Withdrawal struct {
 	LogIndex    uint
 }
 
+// ErrInvalidWithdrawalStatus signals that a WithdrawalStatus is not one of the
+// known values.
+var ErrInvalidWithdrawalStatus = errors.New("invalid withdrawal status")
+
+// WithdrawalStatus selects withdrawals by whether they were finalized on L1.
+type WithdrawalStatus string
+
+const (
+	// WithdrawalStatusAll matches every withdrawal. The zero value is
+	// equivalent.
+	WithdrawalStatusAll WithdrawalStatus = "all"
+
+	// WithdrawalStatusPending matches withdrawals not yet finalized on L1.
+	WithdrawalStatusPending WithdrawalStatus = "pending"
+
+	// WithdrawalStatusFinalized matches withdrawals finalized on L1.
+	WithdrawalStatusFinalized WithdrawalStatus = "finalized"
+)
+
+// matches returns whether pending and finalized withdrawals match the status,
+// or ErrInvalidWithdrawalStatus if the status is unknown.
+func (s WithdrawalStatus) matches() (pending, finalized bool, err error) {
+	switch s {
+	case "", WithdrawalStatusAll:
+		return true, true, nil
+	case WithdrawalStatusPending:
+		return true, false, nil
+	case WithdrawalStatusFinalized:
+		return false, true, nil
+	default:
+		return false, false, fmt.Errorf("%w: %q", ErrInvalidWithdrawalStatus, string(s))
+	}
+}
+
 // String returns the tx hash for the withdrawal.
 func (w Withdrawal) String() string {
 	return w.TxHash.String()
@@ -33,10 +69,28 @@ type WithdrawalJSON struct {
 	L2Token          *Token `json:"l2Token"`
 	Amount           string `json:"amount"`
 	Data             []byte `json:"data"`
+	DataLength       uint64 `json:"dataLength"`
 	LogIndex         uint64 `json:"logIndex"`
-	L1BlockNumber    uint64 `json:"l1BlockNumber"`
-	L1BlockTimestamp string `json:"l1BlockTimestamp"`
 	L2BlockNumber    uint64 `json:"l2BlockNumber"`
 	L2BlockTimestamp string `json:"l2BlockTimestamp"`
 	TxHash           string `json:"transactionHash"`
+
+	// L1BlockNumber and L1BlockTimestamp locate the L1 block the withdrawal
+	// was finalized in. They are nil, and null in JSON, while the withdrawal
+	// is pending, and are only loaded by GetWithdrawalStatus.
+	L1BlockNumber    *uint64 `json:"l1BlockNumber"`
+	L1BlockTimestamp *string `json:"l1BlockTimestamp"`
+
+	// RelatedDepositGUID is the GUID of the deposit this withdrawal likely
+	// round-trips, if requested and one was found.
+	RelatedDepositGUID string `json:"relatedDepositGuid,omitempty"`
+}
+
+// WithdrawalProofData holds the fields of a withdrawal the prover needs to
+// prove it on L1, see GetWithdrawalProofData.
+type WithdrawalProofData struct {
+	GUID          string      `json:"guid"`
+	TxHash        common.Hash `json:"transactionHash"`
+	L2BlockNumber uint64      `json:"l2BlockNumber"`
+	Data          []byte      `json:"data"`
 }
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNilBlock signals that a nil block was written to a BlockWriter.
+var ErrNilBlock = errors.New("nil block")
+
+const (
+	// DefaultBlockWriterBatchSize is the number of blocks committed per
+	// transaction when the BlockWriter is not configured with BatchSize.
+	DefaultBlockWriterBatchSize = 100
+
+	// DefaultBlockWriterBufferSize is the number of blocks queued ahead of
+	// the committing goroutine when the BlockWriter is not configured with
+	// BufferSize.
+	DefaultBlockWriterBufferSize = 1000
+)
+
+// BlockWriterConfig holds the options used to start a BlockWriter.
+type BlockWriterConfig struct {
+	// BatchSize is the number of blocks committed per transaction.
+	// DefaultBlockWriterBatchSize is used when unset.
+	BatchSize int
+
+	// BufferSize is the number of blocks that may be queued before Write
+	// blocks. DefaultBlockWriterBufferSize is used when unset.
+	BufferSize int
+
+	// BulkOptions are passed to AddIndexedL1Blocks for every batch.
+	BulkOptions BulkOptions
+}
+
+// blockWriterRequest is either a block to write or, if flushed is set, a
+// request to commit the pending batch and report the outcome on flushed.
+type blockWriterRequest struct {
+	block   *IndexedL1Block
+	flushed chan error
+}
+
+// BlockWriter commits indexed L1 blocks in batched transactions from a single
+// goroutine, so that fetching blocks is decoupled from writing them. Blocks
+// are committed in the order they are written. Once a batch fails to commit,
+// the writer discards all further blocks and reports the error from every
+// subsequent call.
+type BlockWriter struct {
+	db        *Database
+	ctx       context.Context
+	batchSize int
+	opts      BulkOptions
+
+	requests chan blockWriterRequest
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewBlockWriter starts a BlockWriter committing to the database. Batches are
+// committed with ctx, so canceling it fails the writer. The writer must be
+// closed with Close.
+func (d *Database) NewBlockWriter(ctx context.Context, cfg BlockWriterConfig) *BlockWriter {
+	batchSize := cfg.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultBlockWriterBatchSize
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultBlockWriterBufferSize
+	}
+
+	w := &BlockWriter{
+		db:        d,
+		ctx:       ctx,
+		batchSize: batchSize,
+		opts:      cfg.BulkOptions,
+		requests:  make(chan blockWriterRequest, bufferSize),
+		done:      make(chan struct{}),
+	}
+	go w.run()
+
+	return w
+}
+
+// Write queues block to be committed with the next batch. It blocks while the
+// buffer is full, which applies backpressure to the producer, until ctx is
+// done. It returns the error of a previously failed batch, if any, and
+// ErrNilBlock if block is nil. Write MUST NOT be called after Close.
+func (w *BlockWriter) Write(ctx context.Context, block *IndexedL1Block) error {
+	if block == nil {
+		return ErrNilBlock
+	}
+	if err := w.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case w.requests <- blockWriterRequest{block: block}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Buffered returns the number of requests queued ahead of the committing
+// goroutine. A value close to the buffer size indicates that writes are the
+// bottleneck.
+func (w *BlockWriter) Buffered() int {
+	return len(w.requests)
+}
+
+// Flush commits every block written so far, including a partial batch, and
+// returns once they are committed or ctx is done.
+func (w *BlockWriter) Flush(ctx context.Context) error {
+	flushed := make(chan error, 1)
+	select {
+	case w.requests <- blockWriterRequest{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-flushed:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close commits the final partial batch, stops the writer and returns the
+// error of the first failed batch, if any.
+func (w *BlockWriter) Close() error {
+	close(w.requests)
+	<-w.done
+	return w.Err()
+}
+
+// Err returns the error of the first failed batch, or nil.
+func (w *BlockWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *BlockWriter) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *BlockWriter) run() {
+	defer close(w.done)
+
+	batch := make([]*IndexedL1Block, 0, w.batchSize)
+	commit := func() {
+		if len(batch) > 0 && w.Err() == nil {
+			w.setErr(w.db.AddIndexedL1Blocks(w.ctx, batch, w.opts))
+		}
+		batch = batch[:0]
+	}
+
+	for request := range w.requests {
+		if request.flushed != nil {
+			commit()
+			request.flushed <- w.Err()
+			continue
+		}
+
+		batch = append(batch, request.block)
+		if len(batch) >= w.batchSize {
+			commit()
+		}
+	}
+	commit()
+}
@@ -0,0 +1,84 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/indexer/db"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlockWriter asserts that blocks fed through a BlockWriter are all
+// persisted once it is closed, including the final partial batch, and that
+// Flush commits a partial batch early.
+func TestBlockWriter(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	w := d.NewBlockWriter(ctx, db.BlockWriterConfig{
+		BatchSize:  10,
+		BufferSize: 4,
+	})
+
+	const numBlocks = 25
+	blocks := make([]*db.IndexedL1Block, numBlocks)
+	for i := range blocks {
+		number := uint64(i + 1)
+		blocks[i] = &db.IndexedL1Block{
+			Hash:       common.BigToHash(new(big.Int).SetUint64(number)),
+			ParentHash: common.BigToHash(new(big.Int).SetUint64(number - 1)),
+			Number:     number,
+			Timestamp:  number,
+			Deposits: []db.Deposit{
+				newTestDeposit(common.BigToHash(new(big.Int).SetUint64(1000+number)), 0),
+			},
+		}
+	}
+
+	for _, block := range blocks[:3] {
+		require.Nil(t, w.Write(ctx, block))
+	}
+	require.Nil(t, w.Flush(ctx))
+
+	highest, err := d.GetHighestL1Block(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(3), highest.Number)
+
+	for _, block := range blocks[3:] {
+		require.Nil(t, w.Write(ctx, block))
+	}
+	require.Nil(t, w.Close())
+
+	highest, err = d.GetHighestL1Block(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(numBlocks), highest.Number)
+
+	for _, block := range blocks {
+		stored, err := d.GetIndexedL1BlockByHash(ctx, block.Hash, true)
+		require.Nil(t, err)
+		require.NotNil(t, stored)
+		require.Len(t, stored.Deposits, 1)
+	}
+}
+
+// TestBlockWriterNilBlock asserts that writing a nil block is rejected rather
+// than taken for a flush, and leaves the writer usable.
+func TestBlockWriterNilBlock(t *testing.T) {
+	t.Parallel()
+
+	d := newDatabase(t)
+	defer d.Close()
+
+	ctx := context.Background()
+	w := d.NewBlockWriter(ctx, db.BlockWriterConfig{})
+	err := w.Write(ctx, nil)
+	require.True(t, errors.Is(err, db.ErrNilBlock))
+	require.Nil(t, w.Flush(ctx))
+	require.Nil(t, w.Close())
+}
@@ -5,11 +5,13 @@ go 1.17
 replace github.com/ethereum-optimism/optimism/op-bindings v0.0.0 => ../op-bindings
 
 require (
+	github.com/ethereum-optimism/optimism/op-bindings v0.0.0
 	github.com/ethereum/go-ethereum v1.10.21
 	github.com/getsentry/sentry-go v0.12.0
-	github.com/google/uuid v1.3.0
+	github.com/google/uuid v1.6.0
 	github.com/gorilla/mux v1.8.0
 	github.com/lib/pq v1.10.4
+	github.com/mattn/go-sqlite3 v1.14.18
 	github.com/prometheus/client_golang v1.11.0
 	github.com/rs/cors v1.8.2
 	github.com/stretchr/testify v1.7.2
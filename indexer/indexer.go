@@ -80,6 +80,7 @@ type Indexer struct {
 	cfg      Config
 	l1Client *ethclient.Client
 	l2Client *ethclient.Client
+	db       *database.Database
 
 	l1IndexingService *l1.Service
 	l2IndexingService *l2.Service
@@ -195,6 +196,7 @@ func NewIndexer(cfg Config, gitVersion string) (*Indexer, error) {
 		cfg:               cfg,
 		l1Client:          l1Client,
 		l2Client:          l2Client,
+		db:                db,
 		l1IndexingService: l1IndexingService,
 		l2IndexingService: l2IndexingService,
 		airdropService:    services.NewAirdrop(db, m),
@@ -222,6 +224,18 @@ func (b *Indexer) Serve() error {
 			log.Error("Error handling /healthz", "error", err)
 		}
 	})
+	b.router.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := b.db.HealthCheck(r.Context()); err != nil {
+			log.Warn("Database health check failed", "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(200)
+		_, err := w.Write([]byte("OK"))
+		if err != nil {
+			log.Error("Error handling /readyz", "error", err)
+		}
+	})
 
 	middleware := server.LoggingMiddleware(b.metrics, log.New("service", "server"))
 
@@ -2,8 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"time"
 
 	"github.com/ethereum-optimism/optimism/indexer/metrics"
@@ -25,6 +27,31 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	_, _ = w.Write(response)
 }
 
+// ParseTimeRange returns the unix timestamps of the optional from and to query
+// params of the request. Missing params are returned as zero, which leaves
+// that side of the range unbounded.
+func ParseTimeRange(r *http.Request) (from, to uint64, err error) {
+	parse := func(name string) (uint64, error) {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			return 0, nil
+		}
+		timestamp, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s timestamp: %w", name, err)
+		}
+		return timestamp, nil
+	}
+
+	if from, err = parse("from"); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parse("to"); err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
 // responseWriter is a minimal wrapper for http.ResponseWriter that allows the
 // written HTTP status code to be captured for logging.
 type responseWriter struct {
@@ -28,7 +28,7 @@ func NewAirdrop(db *db.Database, metrics *metrics.Metrics) *Airdrop {
 func (a *Airdrop) GetAirdrop(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	address := vars["address"]
-	airdrop, err := a.db.GetAirdrop(common.HexToAddress(address))
+	airdrop, err := a.db.GetAirdrop(r.Context(), common.HexToAddress(address))
 	if err != nil {
 		airdropLogger.Error("db error getting airdrop", "err", err)
 		server.RespondWithError(w, http.StatusInternalServerError, "database error")
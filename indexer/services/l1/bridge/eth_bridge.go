@@ -35,12 +35,13 @@ func (e *EthBridge) GetDepositsByBlockRange(start, end uint64) (DepositsMap, err
 	for iter.Next() {
 		depositsByBlockhash[iter.Event.Raw.BlockHash] = append(
 			depositsByBlockhash[iter.Event.Raw.BlockHash], db.Deposit{
-				TxHash:      iter.Event.Raw.TxHash,
-				FromAddress: iter.Event.From,
-				ToAddress:   iter.Event.To,
-				Amount:      iter.Event.Amount,
-				Data:        iter.Event.ExtraData,
-				LogIndex:    iter.Event.Raw.Index,
+				TxHash:        iter.Event.Raw.TxHash,
+				FromAddress:   iter.Event.From,
+				ToAddress:     iter.Event.To,
+				Amount:        iter.Event.Amount,
+				Data:          iter.Event.ExtraData,
+				LogIndex:      iter.Event.Raw.Index,
+				SourceAddress: iter.Event.Raw.Address,
 			})
 	}
 	if err := iter.Error(); err != nil {
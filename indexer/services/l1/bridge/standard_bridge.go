@@ -35,14 +35,15 @@ func (s *StandardBridge) GetDepositsByBlockRange(start, end uint64) (DepositsMap
 	for iter.Next() {
 		depositsByBlockhash[iter.Event.Raw.BlockHash] = append(
 			depositsByBlockhash[iter.Event.Raw.BlockHash], db.Deposit{
-				TxHash:      iter.Event.Raw.TxHash,
-				L1Token:     iter.Event.L1Token,
-				L2Token:     iter.Event.L2Token,
-				FromAddress: iter.Event.From,
-				ToAddress:   iter.Event.To,
-				Amount:      iter.Event.Amount,
-				Data:        iter.Event.ExtraData,
-				LogIndex:    iter.Event.Raw.Index,
+				TxHash:        iter.Event.Raw.TxHash,
+				L1Token:       iter.Event.L1Token,
+				L2Token:       iter.Event.L2Token,
+				FromAddress:   iter.Event.From,
+				ToAddress:     iter.Event.To,
+				Amount:        iter.Event.Amount,
+				Data:          iter.Event.ExtraData,
+				LogIndex:      iter.Event.Raw.Index,
+				SourceAddress: iter.Event.Raw.Address,
 			})
 	}
 	if err := iter.Error(); err != nil {
@@ -186,7 +186,7 @@ func (s *Service) Update(newHeader *types.Header) error {
 		Number: s.cfg.StartBlockNumber,
 		Hash:   common.HexToHash(s.cfg.StartBlockHash),
 	}
-	highestConfirmed, err := s.cfg.DB.GetHighestL1Block()
+	highestConfirmed, err := s.cfg.DB.GetHighestL1Block(s.ctx)
 	if err != nil {
 		return err
 	}
@@ -302,7 +302,17 @@ func (s *Service) Update(newHeader *types.Header) error {
 			Withdrawals: withdrawals,
 		}
 
-		err := s.cfg.DB.AddIndexedL1Block(block)
+		err := s.cfg.DB.AddIndexedL1Block(s.ctx, block)
+		if errors.Is(err, db.ErrDuplicateBlock) {
+			logger.Warn("Skipping already imported ",
+				"block", number, "hash", blockHash)
+			continue
+		}
+		if errors.Is(err, db.ErrConflictingBlock) {
+			logger.Warn("Replacing reorged ",
+				"block", number, "hash", blockHash)
+			err = s.cfg.DB.ReplaceIndexedL1Block(s.ctx, block)
+		}
 		if err != nil {
 			logger.Error(
 				"Unable to import ",
@@ -339,7 +349,7 @@ func (s *Service) Update(newHeader *types.Header) error {
 }
 
 func (s *Service) GetIndexerStatus(w http.ResponseWriter, r *http.Request) {
-	highestBlock, err := s.cfg.DB.GetHighestL1Block()
+	highestBlock, err := s.cfg.DB.GetHighestL1Block(r.Context())
 	if err != nil {
 		server.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -382,8 +392,35 @@ func (s *Service) GetDeposits(w http.ResponseWriter, r *http.Request) {
 		Limit:  uint64(limit),
 		Offset: uint64(offset),
 	}
+	page.Cursor = r.URL.Query().Get("cursor")
+	page.InclusiveCursor = r.URL.Query().Get("inclusive") == "true"
+	page.IncludeTokenCounts = r.URL.Query().Get("counts") == "true"
+
+	from, to, err := server.ParseTimeRange(r)
+	if err != nil {
+		server.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	deposits, err := s.cfg.DB.GetDepositsByAddress(common.HexToAddress(vars["address"]), page)
+	address := common.HexToAddress(vars["address"])
+	filter := db.ActivityFilter{
+		Address:           &address,
+		FromTimestamp:     from,
+		ToTimestamp:       to,
+		IncludeUnverified: r.URL.Query().Get("unverified") == "true",
+		SortBy:            db.SortBy(r.URL.Query().Get("sort")),
+		SortDir:           db.SortDir(r.URL.Query().Get("dir")),
+	}
+	if source := r.URL.Query().Get("source"); source != "" {
+		sourceAddress := common.HexToAddress(source)
+		filter.SourceAddress = &sourceAddress
+	}
+	deposits, err := s.cfg.DB.GetDeposits(r.Context(), filter, page)
+	if errors.Is(err, db.ErrPageOffsetTooLarge) || errors.Is(err, db.ErrPageLimitTooLarge) ||
+		errors.Is(err, db.ErrInvalidCursor) || errors.Is(err, db.ErrInvalidSort) {
+		server.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		server.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -416,7 +453,7 @@ func (s *Service) catchUp(ctx context.Context) error {
 	}
 	realHeadNum := realHead.Number.Uint64()
 
-	currHead, err := s.cfg.DB.GetHighestL1Block()
+	currHead, err := s.cfg.DB.GetHighestL1Block(ctx)
 	if err != nil {
 		return err
 	}
@@ -440,7 +477,7 @@ func (s *Service) catchUp(ctx context.Context) error {
 			if err := s.Update(realHead); err != nil && err != errNoNewBlocks {
 				return err
 			}
-			currHead, err := s.cfg.DB.GetHighestL1Block()
+			currHead, err := s.cfg.DB.GetHighestL1Block(ctx)
 			if err != nil {
 				return err
 			}
@@ -458,15 +495,15 @@ func (s *Service) cacheToken(address common.Address) error {
 		return nil
 	}
 
-	token, err := s.cfg.DB.GetL1TokenByAddress(address.String())
-	if err != nil {
-		return err
-	}
-	if token != nil {
+	token, err := s.cfg.DB.GetL1TokenByAddress(s.ctx, address.String())
+	if err == nil {
 		s.metrics.IncL1CachedTokensCount()
 		s.tokenCache[address] = token
 		return nil
 	}
+	if !errors.Is(err, db.ErrTokenNotFound) {
+		return err
+	}
 
 	token, err = QueryERC20(address, s.cfg.L1Client)
 	if err != nil {
@@ -476,7 +513,7 @@ func (s *Service) cacheToken(address common.Address) error {
 			Address: address.String(),
 		}
 	}
-	if err := s.cfg.DB.AddL1Token(address.String(), token); err != nil {
+	if err := s.cfg.DB.AddL1Token(s.ctx, address.String(), token); err != nil {
 		return err
 	}
 	s.tokenCache[address] = token
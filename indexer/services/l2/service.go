@@ -186,7 +186,7 @@ func (s *Service) Update(newHeader *types.Header) error {
 		Number: s.cfg.StartBlockNumber,
 		Hash:   common.HexToHash(s.cfg.StartBlockHash),
 	}
-	highestConfirmed, err := s.cfg.DB.GetHighestL2Block()
+	highestConfirmed, err := s.cfg.DB.GetHighestL2Block(s.ctx)
 	if err != nil {
 		return err
 	}
@@ -302,7 +302,20 @@ func (s *Service) Update(newHeader *types.Header) error {
 			Withdrawals: withdrawals,
 		}
 
-		err := s.cfg.DB.AddIndexedL2Block(block)
+		err := s.cfg.DB.AddIndexedL2Block(s.ctx, block)
+		if errors.Is(err, db.ErrDuplicateBlock) {
+			logger.Warn("Skipping already imported ",
+				"block", number, "hash", blockHash)
+			continue
+		}
+		if errors.Is(err, db.ErrConflictingBlock) {
+			logger.Warn("Replacing reorged ",
+				"block", number, "hash", blockHash)
+			err = s.cfg.DB.DeleteL2BlocksFrom(s.ctx, number)
+			if err == nil {
+				err = s.cfg.DB.AddIndexedL2Block(s.ctx, block)
+			}
+		}
 		if err != nil {
 			logger.Error(
 				"Unable to import ",
@@ -339,7 +352,7 @@ func (s *Service) Update(newHeader *types.Header) error {
 }
 
 func (s *Service) GetIndexerStatus(w http.ResponseWriter, r *http.Request) {
-	highestBlock, err := s.cfg.DB.GetHighestL2Block()
+	highestBlock, err := s.cfg.DB.GetHighestL2Block(r.Context())
 	if err != nil {
 		server.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -361,7 +374,17 @@ func (s *Service) GetIndexerStatus(w http.ResponseWriter, r *http.Request) {
 func (s *Service) GetWithdrawalStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
-	withdrawal, err := s.cfg.DB.GetWithdrawalStatus(common.HexToHash(vars["hash"]))
+	hash, err := db.ParseHash(vars["hash"])
+	if err != nil {
+		server.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	withdrawal, err := s.cfg.DB.GetWithdrawalStatus(r.Context(), hash)
+	if errors.Is(err, db.ErrWithdrawalNotFound) {
+		server.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		server.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -394,8 +417,31 @@ func (s *Service) GetWithdrawals(w http.ResponseWriter, r *http.Request) {
 		Limit:  uint64(limit),
 		Offset: uint64(offset),
 	}
+	page.Cursor = r.URL.Query().Get("cursor")
+	page.InclusiveCursor = r.URL.Query().Get("inclusive") == "true"
 
-	withdrawals, err := s.cfg.DB.GetWithdrawalsByAddress(common.HexToAddress(vars["address"]), page)
+	from, to, err := server.ParseTimeRange(r)
+	if err != nil {
+		server.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	address := common.HexToAddress(vars["address"])
+	filter := db.ActivityFilter{
+		Address:          &address,
+		FromTimestamp:    from,
+		ToTimestamp:      to,
+		WithdrawalStatus: db.WithdrawalStatus(r.URL.Query().Get("status")),
+		SortBy:           db.SortBy(r.URL.Query().Get("sort")),
+		SortDir:          db.SortDir(r.URL.Query().Get("dir")),
+	}
+	withdrawals, err := s.cfg.DB.GetWithdrawals(r.Context(), filter, page)
+	if errors.Is(err, db.ErrPageOffsetTooLarge) || errors.Is(err, db.ErrPageLimitTooLarge) ||
+		errors.Is(err, db.ErrInvalidWithdrawalStatus) ||
+		errors.Is(err, db.ErrInvalidCursor) || errors.Is(err, db.ErrInvalidSort) {
+		server.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		server.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -428,7 +474,7 @@ func (s *Service) catchUp(ctx context.Context) error {
 	}
 	realHeadNum := realHead.Number.Uint64()
 
-	currHead, err := s.cfg.DB.GetHighestL2Block()
+	currHead, err := s.cfg.DB.GetHighestL2Block(ctx)
 	if err != nil {
 		return err
 	}
@@ -452,7 +498,7 @@ func (s *Service) catchUp(ctx context.Context) error {
 			if err := s.Update(realHead); err != nil && err != errNoNewBlocks {
 				return err
 			}
-			currHead, err := s.cfg.DB.GetHighestL2Block()
+			currHead, err := s.cfg.DB.GetHighestL2Block(ctx)
 			if err != nil {
 				return err
 			}
@@ -470,15 +516,15 @@ func (s *Service) cacheToken(address common.Address) error {
 		return nil
 	}
 
-	token, err := s.cfg.DB.GetL2TokenByAddress(address.String())
-	if err != nil {
-		return err
-	}
-	if token != nil {
+	token, err := s.cfg.DB.GetL2TokenByAddress(s.ctx, address.String())
+	if err == nil {
 		s.metrics.IncL2CachedTokensCount()
 		s.tokenCache[address] = token
 		return nil
 	}
+	if !errors.Is(err, db.ErrTokenNotFound) {
+		return err
+	}
 	token, err = QueryERC20(address, s.cfg.L2Client)
 	if err != nil {
 		logger.Error("Error querying ERC20 token details",
@@ -487,7 +533,7 @@ func (s *Service) cacheToken(address common.Address) error {
 			Address: address.String(),
 		}
 	}
-	if err := s.cfg.DB.AddL2Token(address.String(), token); err != nil {
+	if err := s.cfg.DB.AddL2Token(s.ctx, address.String(), token); err != nil {
 		return err
 	}
 	s.tokenCache[address] = token